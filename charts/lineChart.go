@@ -0,0 +1,201 @@
+package charts
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"time"
+)
+
+type Point struct {
+	Time time.Time
+	// NaN if the value is unknown, the line has a gap there
+	Value float64
+}
+
+// one line drawn in its own horizontal band of the image
+type Panel struct {
+	Points []Point
+	Color color.RGBA
+}
+
+const (
+	chartPadding int = 10
+	chartLineWidth int = 2
+	chartGridLinesCount int = 4
+)
+
+var (
+	chartBackgroundColor = color.RGBA{255, 255, 255, 255}
+	chartPanelColor = color.RGBA{248, 248, 248, 255}
+	chartGridColor = color.RGBA{224, 224, 224, 255}
+)
+
+type chartArea struct {
+	left int
+	top int
+	width int
+	height int
+}
+
+// draws the panels one under another with the same time scale and returns PNG image
+func RenderPng(panels []Panel, width int, height int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fillRect(img, image.Rect(0, 0, width, height), chartBackgroundColor)
+
+	if len(panels) > 0 {
+		startTime, endTime := getTimeRange(panels)
+
+		panelHeight := (height - chartPadding) / len(panels) - chartPadding
+
+		for i, panel := range panels {
+			area := chartArea{
+				left: chartPadding,
+				top: chartPadding + i * (panelHeight + chartPadding),
+				width: width - 2 * chartPadding,
+				height: panelHeight,
+			}
+			drawPanel(img, area, panel, startTime, endTime)
+		}
+	}
+
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, img)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func getTimeRange(panels []Panel) (startTime time.Time, endTime time.Time) {
+	isFirst := true
+	for _, panel := range panels {
+		for _, point := range panel.Points {
+			if isFirst || point.Time.Before(startTime) {
+				startTime = point.Time
+			}
+			if isFirst || point.Time.After(endTime) {
+				endTime = point.Time
+			}
+			isFirst = false
+		}
+	}
+	return
+}
+
+// the range of the known values
+func GetValueRange(points []Point) (minValue float64, maxValue float64, ok bool) {
+	for _, point := range points {
+		if math.IsNaN(point.Value) {
+			continue
+		}
+		if !ok || point.Value < minValue {
+			minValue = point.Value
+		}
+		if !ok || point.Value > maxValue {
+			maxValue = point.Value
+		}
+		ok = true
+	}
+	return
+}
+
+func drawPanel(img *image.RGBA, area chartArea, panel Panel, startTime time.Time, endTime time.Time) {
+	fillRect(img, image.Rect(area.left, area.top, area.left + area.width, area.top + area.height), chartPanelColor)
+
+	for i := 0; i <= chartGridLinesCount; i++ {
+		y := area.top + i * (area.height - 1) / chartGridLinesCount
+		fillRect(img, image.Rect(area.left, y, area.left + area.width, y + 1), chartGridColor)
+	}
+
+	minValue, maxValue, ok := GetValueRange(panel.Points)
+	if !ok {
+		return
+	}
+
+	// flat lines are drawn in the middle
+	if maxValue == minValue {
+		minValue = minValue - 1
+		maxValue = maxValue + 1
+	}
+
+	timeRange := endTime.Sub(startTime).Seconds()
+
+	getX := func(point Point) int {
+		if timeRange <= 0 {
+			return area.left + area.width / 2
+		}
+		return area.left + int(point.Time.Sub(startTime).Seconds() / timeRange * float64(area.width - 1))
+	}
+
+	getY := func(point Point) int {
+		return area.top + area.height - 1 - int((point.Value - minValue) / (maxValue - minValue) * float64(area.height - 1))
+	}
+
+	var previousPoint *Point
+	for i := range panel.Points {
+		point := &panel.Points[i]
+
+		if math.IsNaN(point.Value) {
+			previousPoint = nil
+			continue
+		}
+
+		if previousPoint == nil {
+			drawLine(img, getX(*point), getY(*point), getX(*point), getY(*point), panel.Color)
+		} else {
+			drawLine(img, getX(*previousPoint), getY(*previousPoint), getX(*point), getY(*point), panel.Color)
+		}
+		previousPoint = point
+	}
+}
+
+func fillRect(img *image.RGBA, rect image.Rectangle, fillColor color.RGBA) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.SetRGBA(x, y, fillColor)
+		}
+	}
+}
+
+// Bresenham's line algorithm with a square brush
+func drawLine(img *image.RGBA, x0 int, y0 int, x1 int, y1 int, lineColor color.RGBA) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx := 1
+	if x0 > x1 {
+		sx = -1
+	}
+	sy := 1
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+
+	for {
+		fillRect(img, image.Rect(x0, y0, x0 + chartLineWidth, y0 + chartLineWidth).Intersect(img.Bounds()), lineColor)
+
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
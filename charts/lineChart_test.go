@@ -0,0 +1,85 @@
+package charts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRenderPng(t *testing.T) {
+	assert := require.New(t)
+
+	startTime := time.Unix(1536900000, 0)
+	lineColor := color.RGBA{255, 0, 0, 255}
+
+	panels := []Panel{
+		Panel{
+			Points: []Point{
+				Point{Time: startTime, Value: 1},
+				Point{Time: startTime.Add(time.Hour), Value: 3},
+				Point{Time: startTime.Add(2 * time.Hour), Value: math.NaN()},
+				Point{Time: startTime.Add(3 * time.Hour), Value: 2},
+			},
+			Color: lineColor,
+		},
+		Panel{
+			// a flat line
+			Points: []Point{
+				Point{Time: startTime, Value: 5},
+				Point{Time: startTime.Add(3 * time.Hour), Value: 5},
+			},
+			Color: lineColor,
+		},
+	}
+
+	pngData, err := RenderPng(panels, 400, 300)
+	assert.Nil(err)
+
+	img, err := png.Decode(bytes.NewReader(pngData))
+	assert.Nil(err)
+	assert.Equal(400, img.Bounds().Dx())
+	assert.Equal(300, img.Bounds().Dy())
+
+	// the first point is in the bottom left corner of the first panel
+	r, g, b, _ := img.At(chartPadding, 144).RGBA()
+	assert.Equal([]uint32{0xffff, 0, 0}, []uint32{r, g, b})
+
+	// the gap isn't filled
+	r, g, b, _ = img.At(258, 50).RGBA()
+	assert.NotEqual([]uint32{0xffff, 0, 0}, []uint32{r, g, b})
+}
+
+func TestRenderEmptyPng(t *testing.T) {
+	assert := require.New(t)
+
+	pngData, err := RenderPng([]Panel{Panel{}}, 100, 100)
+	assert.Nil(err)
+
+	_, err = png.Decode(bytes.NewReader(pngData))
+	assert.Nil(err)
+
+	pngData, err = RenderPng(nil, 100, 100)
+	assert.Nil(err)
+	assert.NotEmpty(pngData)
+}
+
+func TestGetValueRange(t *testing.T) {
+	assert := require.New(t)
+
+	minValue, maxValue, ok := GetValueRange([]Point{
+		Point{Value: math.NaN()},
+		Point{Value: 3},
+		Point{Value: -1.5},
+		Point{Value: 2},
+	})
+	assert.True(ok)
+	assert.Equal(-1.5, minValue)
+	assert.Equal(3.0, maxValue)
+
+	_, _, ok = GetValueRange([]Point{Point{Value: math.NaN()}})
+	assert.False(ok)
+}
@@ -0,0 +1,390 @@
+package cryptoFunctions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"math/big"
+	"strings"
+)
+
+const base58Alphabet string = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+const bech32Charset string = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+type bech32Encoding int8
+
+const (
+	bech32Invalid bech32Encoding = 0
+	bech32Plain bech32Encoding = 1 // BIP-173
+	bech32Modified bech32Encoding = 2 // BIP-350
+)
+
+const (
+	bech32Const uint32 = 1
+	bech32mConst uint32 = 0x2bc830a3
+)
+
+// size of CashAddr hash in bytes by the lower bits of the version byte
+var cashAddrHashSizes []int = []int{20, 24, 28, 32, 40, 48, 56, 64}
+
+func doubleSha256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+func base58Decode(text string) (result []byte, ok bool) {
+	value := new(big.Int)
+	radix := big.NewInt(58)
+
+	for _, char := range text {
+		digit := strings.IndexRune(base58Alphabet, char)
+		if digit < 0 {
+			return nil, false
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+
+	// every leading "1" stands for a leading zero byte
+	leadingZeros := 0
+	for leadingZeros < len(text) && text[leadingZeros] == base58Alphabet[0] {
+		leadingZeros++
+	}
+
+	result = append(make([]byte, leadingZeros), value.Bytes()...)
+	return result, true
+}
+
+func base58Encode(data []byte) string {
+	value := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	modulo := new(big.Int)
+
+	var reversed []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, radix, modulo)
+		reversed = append(reversed, base58Alphabet[modulo.Int64()])
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		reversed = append(reversed, base58Alphabet[0])
+	}
+
+	result := make([]byte, len(reversed))
+	for i, char := range reversed {
+		result[len(reversed) - 1 - i] = char
+	}
+	return string(result)
+}
+
+// decodes a Base58Check string and returns the version byte and the payload
+func decodeBase58Check(address string) (version byte, payload []byte, ok bool) {
+	decoded, ok := base58Decode(address)
+	if !ok || len(decoded) < 5 {
+		return 0, nil, false
+	}
+
+	data := decoded[:len(decoded) - 4]
+	checksum := decoded[len(decoded) - 4:]
+
+	if !bytes.Equal(doubleSha256(data)[:4], checksum) {
+		return 0, nil, false
+	}
+
+	return data[0], data[1:], true
+}
+
+func encodeBase58Check(version byte, payload []byte) string {
+	data := append([]byte{version}, payload...)
+	return base58Encode(append(data, doubleSha256(data)[:4]...))
+}
+
+// checks that the address is a Base58Check encoded 160-bit hash with one of the given version bytes
+func isBase58AddressValid(address string, versions ...byte) bool {
+	version, payload, ok := decodeBase58Check(address)
+	if !ok || len(payload) != 20 {
+		return false
+	}
+
+	for _, allowedVersion := range versions {
+		if version == allowedVersion {
+			return true
+		}
+	}
+	return false
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum & 0x1ffffff) << 5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top >> uint(i)) & 1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+	return checksum
+}
+
+func bech32HrpExpand(hrp string) []byte {
+	result := make([]byte, 0, len(hrp) * 2 + 1)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i] >> 5)
+	}
+	result = append(result, 0)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i] & 31)
+	}
+	return result
+}
+
+// maps the characters to 5-bit values, the case should be already normalized
+func decodeCharset(text string) (values []byte, ok bool) {
+	values = make([]byte, len(text))
+	for i, char := range text {
+		value := strings.IndexRune(bech32Charset, char)
+		if value < 0 {
+			return nil, false
+		}
+		values[i] = byte(value)
+	}
+	return values, true
+}
+
+// returns the lowercase version of the text if it is not mixed-case
+func normalizeBech32Case(text string) (string, bool) {
+	lower := strings.ToLower(text)
+	if text != lower && text != strings.ToUpper(text) {
+		return "", false
+	}
+	return lower, true
+}
+
+// decodes a Bech32 or Bech32m string and returns its human-readable part and data without the checksum
+func decodeBech32(text string) (hrp string, data []byte, encoding bech32Encoding) {
+	if len(text) > 90 {
+		return "", nil, bech32Invalid
+	}
+
+	text, ok := normalizeBech32Case(text)
+	if !ok {
+		return "", nil, bech32Invalid
+	}
+
+	separatorPos := strings.LastIndex(text, "1")
+	if separatorPos < 1 || separatorPos + 7 > len(text) {
+		return "", nil, bech32Invalid
+	}
+
+	hrp = text[:separatorPos]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, bech32Invalid
+		}
+	}
+
+	values, ok := decodeCharset(text[separatorPos + 1:])
+	if !ok {
+		return "", nil, bech32Invalid
+	}
+
+	switch bech32Polymod(append(bech32HrpExpand(hrp), values...)) {
+	case bech32Const:
+		encoding = bech32Plain
+	case bech32mConst:
+		encoding = bech32Modified
+	default:
+		return "", nil, bech32Invalid
+	}
+
+	return hrp, values[:len(values) - 6], encoding
+}
+
+func encodeBech32(hrp string, data []byte, encoding bech32Encoding) string {
+	constant := bech32Const
+	if encoding == bech32Modified {
+		constant = bech32mConst
+	}
+
+	values := append(bech32HrpExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ constant
+
+	var b bytes.Buffer
+	b.WriteString(hrp)
+	b.WriteString("1")
+	for _, value := range data {
+		b.WriteByte(bech32Charset[value])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod >> uint(5 * (5 - i))) & 31])
+	}
+	return b.String()
+}
+
+// regroups bits of the data, e.g. from 8-bit bytes to 5-bit bech32 values
+func convertBits(data []byte, fromBits uint, toBits uint, pad bool) (result []byte, ok bool) {
+	accumulator := uint32(0)
+	bits := uint(0)
+	maxValue := uint32(1 << toBits) - 1
+
+	for _, value := range data {
+		if uint32(value) >> fromBits != 0 {
+			return nil, false
+		}
+		accumulator = accumulator << fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte(accumulator >> bits & maxValue))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			result = append(result, byte(accumulator << (toBits - bits) & maxValue))
+		}
+	} else if bits >= fromBits || accumulator << (toBits - bits) & maxValue != 0 {
+		return nil, false
+	}
+
+	return result, true
+}
+
+// decodes a SegWit address (BIP-173 for witness v0, BIP-350 for v1 and newer)
+func decodeSegwitAddress(expectedHrp string, address string) (witnessVersion int, program []byte, ok bool) {
+	hrp, data, encoding := decodeBech32(address)
+	if encoding == bech32Invalid || hrp != expectedHrp || len(data) < 1 {
+		return 0, nil, false
+	}
+
+	witnessVersion = int(data[0])
+	if witnessVersion > 16 {
+		return 0, nil, false
+	}
+
+	program, ok = convertBits(data[1:], 5, 8, false)
+	if !ok || len(program) < 2 || len(program) > 40 {
+		return 0, nil, false
+	}
+
+	if witnessVersion == 0 {
+		if len(program) != 20 && len(program) != 32 {
+			return 0, nil, false
+		}
+		if encoding != bech32Plain {
+			return 0, nil, false
+		}
+	} else if encoding != bech32Modified {
+		return 0, nil, false
+	}
+
+	return witnessVersion, program, true
+}
+
+func encodeSegwitAddress(hrp string, witnessVersion int, program []byte) string {
+	encoding := bech32Plain
+	if witnessVersion > 0 {
+		encoding = bech32Modified
+	}
+
+	data, _ := convertBits(program, 8, 5, true)
+	return encodeBech32(hrp, append([]byte{byte(witnessVersion)}, data...), encoding)
+}
+
+func isSegwitAddressValid(hrp string, address string) bool {
+	_, _, ok := decodeSegwitAddress(hrp, address)
+	return ok
+}
+
+func cashAddrPolymod(values []byte) uint64 {
+	checksum := uint64(1)
+	for _, value := range values {
+		top := byte(checksum >> 35)
+		checksum = (checksum & 0x07ffffffff) << 5 ^ uint64(value)
+		if top & 0x01 != 0 {
+			checksum ^= 0x98f2bc8e61
+		}
+		if top & 0x02 != 0 {
+			checksum ^= 0x79b76d99e2
+		}
+		if top & 0x04 != 0 {
+			checksum ^= 0xf33e5fb3c4
+		}
+		if top & 0x08 != 0 {
+			checksum ^= 0xae2eabe2a8
+		}
+		if top & 0x10 != 0 {
+			checksum ^= 0x1e4f43e470
+		}
+	}
+	return checksum ^ 1
+}
+
+// decodes a CashAddr address, the prefix in the address is optional
+func decodeCashAddress(expectedPrefix string, address string) (addressType byte, hash []byte, ok bool) {
+	address, ok = normalizeBech32Case(address)
+	if !ok {
+		return 0, nil, false
+	}
+
+	prefix := expectedPrefix
+	payload := address
+	if separatorPos := strings.LastIndex(address, ":"); separatorPos >= 0 {
+		prefix = address[:separatorPos]
+		payload = address[separatorPos + 1:]
+	}
+
+	if prefix != expectedPrefix || len(payload) <= 8 {
+		return 0, nil, false
+	}
+
+	values, ok := decodeCharset(payload)
+	if !ok {
+		return 0, nil, false
+	}
+
+	prefixValues := make([]byte, 0, len(prefix) + 1)
+	for i := 0; i < len(prefix); i++ {
+		prefixValues = append(prefixValues, prefix[i] & 31)
+	}
+	prefixValues = append(prefixValues, 0)
+
+	if cashAddrPolymod(append(prefixValues, values...)) != 0 {
+		return 0, nil, false
+	}
+
+	data, ok := convertBits(values[:len(values) - 8], 5, 8, false)
+	if !ok || len(data) < 1 {
+		return 0, nil, false
+	}
+
+	versionByte := data[0]
+	if versionByte & 0x80 != 0 || len(data) - 1 != cashAddrHashSizes[versionByte & 0x07] {
+		return 0, nil, false
+	}
+
+	return (versionByte >> 3) & 0x0f, data[1:], true
+}
+
+func isCashAddressValid(prefix string, address string) bool {
+	addressType, _, ok := decodeCashAddress(prefix, address)
+	// only P2PKH (0) and P2SH (1) are defined
+	return ok && addressType <= 1
+}
+
+// all-lowercase and all-uppercase addresses don't carry a checksum
+func isEthereumAddressChecksumValid(address string) bool {
+	hexAddress := address[2:]
+	if hexAddress == strings.ToLower(hexAddress) || hexAddress == strings.ToUpper(hexAddress) {
+		return true
+	}
+
+	return currencies.ToEthereumChecksumAddress(address) == address
+}
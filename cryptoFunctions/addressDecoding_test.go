@@ -0,0 +1,143 @@
+package cryptoFunctions
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type addressValidityTestCase struct {
+	address string
+	isValid bool
+}
+
+func checkAddressValidity(t *testing.T, processor CurrencyProcessor, testCases []addressValidityTestCase) {
+	assert := require.New(t)
+
+	for _, testCase := range testCases {
+		assert.Equal(testCase.isValid, processor.IsAddressValid(testCase.address), testCase.address)
+	}
+}
+
+func TestBitcoinAddressValidation(t *testing.T) {
+	checkAddressValidity(t, &BitcoinProcessor{}, []addressValidityTestCase{
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", true},
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", true},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", true},
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", true},
+		{"bc1q94c3vs4hy6cygqtz0j5lhtpj7hy9xra3jq7vfkczykr30ys6fzqsmphc8w", true},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", true},
+		// wrong checksum
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", false},
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLz", false},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", false},
+		// mixed case
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7KV8F3T4", false},
+		// witness v1 with Bech32 checksum instead of Bech32m
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", false},
+		{"bc1pqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq5us4ke", false},
+		// witness v0 with Bech32m checksum
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", false},
+		// testnet
+		{"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", false},
+		// Bitcoin Gold and Litecoin
+		{"GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk", false},
+		{"LP3Dfk42fVnxRtytyqYSh81gzN6ykZjQAF", false},
+		{"", false},
+		{"0x52908400098527886E0F7030069857D2E4169EE7", false},
+	})
+}
+
+func TestBitcoinCashAddressValidation(t *testing.T) {
+	checkAddressValidity(t, &BitcoinCashProcessor{}, []addressValidityTestCase{
+		{"1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu", true},
+		{"bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a", true},
+		{"qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a", true},
+		{"BITCOINCASH:QPM2QSZNHKS23Z7629MMS6S4CWEF74VCWVY22GDX6A", true},
+		{"bitcoincash:ppm2qsznhks23z7629mms6s4cwef74vcwvn0h829pq", true},
+		// wrong checksum
+		{"bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6b", false},
+		{"qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6b", false},
+		// wrong prefix
+		{"bchtest:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a", false},
+		// mixed case
+		{"bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6A", false},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", false},
+	})
+}
+
+func TestBitcoinGoldAddressValidation(t *testing.T) {
+	checkAddressValidity(t, &BitcoinGoldProcessor{}, []addressValidityTestCase{
+		{"GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk", true},
+		{"AKb942bpuzD3z4VjNMDUZzD2FkfPxYiCB8", true},
+		{"btg1q98vvn8kztvn3qplst6kwslkqp9vhge58j7dxwz", true},
+		{"GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdK", false},
+		{"btg1q98vvn8kztvn3qplst6kwslkqp9vhge58j7dxwy", false},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", false},
+	})
+}
+
+func TestLitecoinAddressValidation(t *testing.T) {
+	checkAddressValidity(t, &LitecoinProcessor{}, []addressValidityTestCase{
+		{"LP3Dfk42fVnxRtytyqYSh81gzN6ykZjQAF", true},
+		{"MBiRdxec5rii4mG52gD5fNZGFNcs7RjZTg", true},
+		{"35WHL5Ee8jsHGFzAvoDjqjJrvg2R8WVmbU", true},
+		{"ltc1q98vvn8kztvn3qplst6kwslkqp9vhge58qt38r6", true},
+		{"ltc1q94c3vs4hy6cygqtz0j5lhtpj7hy9xra3jq7vfkczykr30ys6fzqsc9egat", true},
+		{"LP3Dfk42fVnxRtytyqYSh81gzN6ykZjQAG", false},
+		{"ltc1q98vvn8kztvn3qplst6kwslkqp9vhge58qt38r7", false},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", false},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", false},
+	})
+}
+
+func TestAddressEncodingRoundTrip(t *testing.T) {
+	assert := require.New(t)
+
+	hash, _ := hex.DecodeString("76a04053bda0a88bda5177b86a15c3b29f559873")
+
+	assert.Equal("1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu", encodeBase58Check(bitcoinP2pkhVersion, hash))
+
+	version, payload, ok := decodeBase58Check("1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu")
+	assert.True(ok)
+	assert.Equal(bitcoinP2pkhVersion, version)
+	assert.Equal(hash, payload)
+
+	segwitAddress := encodeSegwitAddress("bc", 0, hash)
+	witnessVersion, program, ok := decodeSegwitAddress("bc", segwitAddress)
+	assert.True(ok)
+	assert.Equal(0, witnessVersion)
+	assert.Equal(hash, program)
+
+	addressType, cashHash, ok := decodeCashAddress("bitcoincash", "bitcoincash:ppm2qsznhks23z7629mms6s4cwef74vcwvn0h829pq")
+	assert.True(ok)
+	assert.Equal(byte(1), addressType)
+	assert.Equal(hash, cashHash)
+}
+
+func TestEthereumAddressValidation(t *testing.T) {
+	checkAddressValidity(t, &EtherProcessor{}, []addressValidityTestCase{
+		// EIP-55 examples
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", true},
+		{"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", true},
+		{"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB", true},
+		{"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb", true},
+		// no checksum
+		{"0x52908400098527886E0F7030069857D2E4169EE7", true},
+		{"0xde709f2102306220921060314715629080e2fb77", true},
+		// wrong checksum
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", false},
+		{"0xfb6916095ca1df60bB79Ce92cE3Ea74c37c5d359", false},
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAe", false},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", false},
+	})
+}
+
+func TestEthereumAddressChecksum(t *testing.T) {
+	assert := require.New(t)
+
+	assert.True(IsEthereumAddressChecksumWrong("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"))
+	assert.False(IsEthereumAddressChecksumWrong("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"))
+	assert.False(IsEthereumAddressChecksumWrong("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"))
+	assert.False(IsEthereumAddressChecksumWrong("not an address"))
+}
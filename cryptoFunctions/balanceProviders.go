@@ -0,0 +1,273 @@
+package cryptoFunctions
+
+import (
+	"fmt"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"log"
+	"math/big"
+	"sync"
+)
+
+// one API that can return balances of a currency
+type BalanceProvider interface {
+	// used in the configuration and to limit parallel requests
+	GetName() string
+	// the order of balances matches the order of addresses, nil for the balances that can't be received
+	// the error tells why some of the balances are missing
+	GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error)
+}
+
+// a balance provider that also returns how many transactions the addresses have
+// it's needed to find the used addresses of HD wallets that are empty now
+type TxCountBalanceProvider interface {
+	// the same as GetBalanceBunch, the counts are -1 for the addresses that can't be received
+	GetBalanceAndTxCountBunch(addresses []currencies.AddressData) ([]*big.Int, []int64, error)
+}
+
+// the balance of one address and where it came from
+type BalanceResult struct {
+	// nil if the balance is unknown
+	Balance *big.Int
+	// the provider that returned the balance or the last one that failed
+	Provider string
+	// why the balance is unknown, nil if it is known
+	Error error
+	// the address has any transactions, or has a balance if the provider doesn't count transactions
+	IsUsed bool
+}
+
+// providers that are used if nothing is configured, in the order of priority
+var defaultBalanceProviderNames map[currencies.Currency][]string = map[currencies.Currency][]string{
+	currencies.Bitcoin : []string{btcComProvider, esploraProvider, blockchairProvider},
+	currencies.BitcoinCash : []string{blockchairProvider},
+	currencies.BitcoinGold : []string{btgexpProvider, insightProvider},
+	currencies.Ether : []string{etherscanProvider},
+	currencies.RippleXrp : []string{rippleDataProvider, xrplProvider},
+	currencies.Erc20Token : []string{tokenBalanceProvider, etherscanProvider},
+	currencies.Litecoin : []string{blockchairProvider, esploraProvider},
+}
+
+var balanceProviders map[currencies.Currency][]BalanceProvider = map[currencies.Currency][]BalanceProvider{}
+
+var isBalanceCrossCheckEnabled bool = false
+
+var balanceProvidersMutex sync.Mutex
+
+// url and apiKey can be empty to use the default ones
+// returns nil if there is no such provider for the currency
+func MakeBalanceProvider(currency currencies.Currency, name string, url string, apiKey string) BalanceProvider {
+	switch name {
+	case blockchairProvider:
+		return makeBlockchairBalanceProvider(currency, url)
+	case btcComProvider:
+		return makeBtcComBalanceProvider(currency, url)
+	case btgexpProvider:
+		return makeBtgexpBalanceProvider(currency, url)
+	case esploraProvider:
+		return makeEsploraBalanceProvider(currency, url)
+	case etherscanProvider:
+		return makeEtherscanBalanceProvider(currency, url, apiKey)
+	case insightProvider:
+		return makeInsightBalanceProvider(currency, url)
+	case rippleDataProvider:
+		return makeRippleDataBalanceProvider(currency, url)
+	case tokenBalanceProvider:
+		return makeTokenBalanceBalanceProvider(currency, url)
+	case xrplProvider:
+		return makeXrplBalanceProvider(currency, url)
+	default:
+		return nil
+	}
+}
+
+// the providers are tried in the given order, nil or empty list returns the default providers
+func SetBalanceProviders(currency currencies.Currency, providers []BalanceProvider) {
+	balanceProvidersMutex.Lock()
+	defer balanceProvidersMutex.Unlock()
+
+	if len(providers) > 0 {
+		balanceProviders[currency] = providers
+	} else {
+		delete(balanceProviders, currency)
+	}
+}
+
+// if enabled, every balance is checked with one more provider and is unknown if they don't match
+func SetBalanceCrossCheckEnabled(isEnabled bool) {
+	balanceProvidersMutex.Lock()
+	defer balanceProvidersMutex.Unlock()
+
+	isBalanceCrossCheckEnabled = isEnabled
+}
+
+func getBalanceProviders(currency currencies.Currency) (providers []BalanceProvider, isCrossCheckEnabled bool) {
+	balanceProvidersMutex.Lock()
+	defer balanceProvidersMutex.Unlock()
+
+	providers, ok := balanceProviders[currency]
+	if !ok {
+		for _, name := range defaultBalanceProviderNames[currency] {
+			if provider := MakeBalanceProvider(currency, name, "", ""); provider != nil {
+				providers = append(providers, provider)
+			}
+		}
+		balanceProviders[currency] = providers
+	}
+
+	return providers, isBalanceCrossCheckEnabled
+}
+
+func getAddressesByIndexes(addresses []currencies.AddressData, indexes []int) (result []currencies.AddressData) {
+	result = make([]currencies.AddressData, len(indexes))
+	for i, index := range indexes {
+		result[i] = addresses[index]
+	}
+	return
+}
+
+func makeUnknownTxCounts(count int) []int64 {
+	txCounts := make([]int64, count)
+	for i := range txCounts {
+		txCounts[i] = -1
+	}
+	return txCounts
+}
+
+// the counts are -1 if the provider doesn't return them
+func getProviderBalanceBunch(provider BalanceProvider, addresses []currencies.AddressData) ([]*big.Int, []int64, error) {
+	if txCountProvider, ok := provider.(TxCountBalanceProvider); ok {
+		return txCountProvider.GetBalanceAndTxCountBunch(addresses)
+	}
+
+	balances, err := provider.GetBalanceBunch(addresses)
+	return balances, makeUnknownTxCounts(len(balances)), err
+}
+
+func isAddressUsed(balance *big.Int, txCount int64) bool {
+	if txCount >= 0 {
+		return txCount > 0
+	}
+	return balance != nil && balance.Sign() > 0
+}
+
+// asks the providers one by one for the balances that are still unknown
+func getCurrencyBalanceResults(currency currencies.Currency, addresses []currencies.AddressData) []BalanceResult {
+	providers, isCrossCheckEnabled := getBalanceProviders(currency)
+
+	results := make([]BalanceResult, len(addresses))
+	// index of the provider that returned the balance
+	balanceProviderIndexes := make([]int, len(addresses))
+
+	missingIndexes := make([]int, len(addresses))
+	for i := range addresses {
+		missingIndexes[i] = i
+		results[i].Error = fmt.Errorf("no balance providers for %s", currencies.GetCurrencySymbol(currency))
+	}
+
+	for providerIndex, provider := range providers {
+		if len(missingIndexes) == 0 {
+			break
+		}
+
+		if providerIndex > 0 {
+			log.Printf("%d balances of %s are unknown, trying %s", len(missingIndexes), currencies.GetCurrencySymbol(currency), provider.GetName())
+		}
+
+		providerBalances, providerTxCounts, err := getProviderBalanceBunch(provider, getAddressesByIndexes(addresses, missingIndexes))
+		if len(providerBalances) != len(missingIndexes) || len(providerTxCounts) != len(missingIndexes) {
+			err = fmt.Errorf("%s return count doesn't match input count: %d != %d", provider.GetName(), len(missingIndexes), len(providerBalances))
+			providerBalances = make([]*big.Int, len(missingIndexes))
+			providerTxCounts = makeUnknownTxCounts(len(missingIndexes))
+		}
+
+		if err != nil {
+			log.Print(err)
+		} else {
+			err = fmt.Errorf("%s returned no balance", provider.GetName())
+		}
+
+		stillMissingIndexes := []int{}
+		for i, addressIndex := range missingIndexes {
+			results[addressIndex] = BalanceResult{
+				Balance: providerBalances[i],
+				Provider: provider.GetName(),
+			}
+
+			if providerBalances[i] != nil {
+				results[addressIndex].IsUsed = isAddressUsed(providerBalances[i], providerTxCounts[i])
+				balanceProviderIndexes[addressIndex] = providerIndex
+			} else {
+				results[addressIndex].Error = err
+				stillMissingIndexes = append(stillMissingIndexes, addressIndex)
+			}
+		}
+		missingIndexes = stillMissingIndexes
+	}
+
+	if isCrossCheckEnabled && len(providers) > 1 {
+		crossCheckBalances(providers, addresses, results, balanceProviderIndexes)
+	}
+
+	return results
+}
+
+// every balance is checked with the provider next to the one that returned it
+// the balances that don't match become unknown
+func crossCheckBalances(providers []BalanceProvider, addresses []currencies.AddressData, results []BalanceResult, balanceProviderIndexes []int) {
+	checkIndexes := make([][]int, len(providers))
+	for addressIndex, result := range results {
+		if result.Balance != nil {
+			checkProviderIndex := (balanceProviderIndexes[addressIndex] + 1) % len(providers)
+			checkIndexes[checkProviderIndex] = append(checkIndexes[checkProviderIndex], addressIndex)
+		}
+	}
+
+	for providerIndex, addressIndexes := range checkIndexes {
+		if len(addressIndexes) == 0 {
+			continue
+		}
+
+		provider := providers[providerIndex]
+		checkBalances, _ := provider.GetBalanceBunch(getAddressesByIndexes(addresses, addressIndexes))
+		if len(checkBalances) != len(addressIndexes) {
+			log.Printf("%s return count doesn't match input count: %d != %d", provider.GetName(), len(addressIndexes), len(checkBalances))
+			continue
+		}
+
+		for i, addressIndex := range addressIndexes {
+			// we can't check it, so trust the first provider
+			if checkBalances[i] == nil {
+				continue
+			}
+
+			result := &results[addressIndex]
+			if checkBalances[i].Cmp(result.Balance) != 0 {
+				result.Error = fmt.Errorf("balances of %s don't match: %s returned %s, %s returned %s",
+					addresses[addressIndex].Address,
+					result.Provider,
+					result.Balance.String(),
+					provider.GetName(),
+					checkBalances[i].String(),
+				)
+				log.Print(result.Error)
+				result.Balance = nil
+			}
+		}
+	}
+}
+
+func getResultsBalances(results []BalanceResult) []*big.Int {
+	balances := make([]*big.Int, len(results))
+	for i, result := range results {
+		balances[i] = result.Balance
+	}
+	return balances
+}
+
+func getCurrencyBalanceBunch(currency currencies.Currency, addresses []currencies.AddressData) []*big.Int {
+	return getResultsBalances(getCurrencyBalanceResults(currency, addresses))
+}
+
+func getCurrencyBalance(currency currencies.Currency, address currencies.AddressData) *big.Int {
+	return getCurrencyBalanceBunch(currency, []currencies.AddressData{address})[0]
+}
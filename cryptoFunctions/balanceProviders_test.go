@@ -0,0 +1,295 @@
+package cryptoFunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type testBalanceProvider struct {
+	name string
+	mutex sync.Mutex
+	// address -> balance, missing addresses are unknown
+	balances map[string]int64
+	requestedAddresses []string
+	// returned when some balances are unknown
+	err error
+}
+
+func (provider *testBalanceProvider) GetName() string {
+	return provider.name
+}
+
+func (provider *testBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	balances := make([]*big.Int, len(addresses))
+	for i, address := range addresses {
+		provider.requestedAddresses = append(provider.requestedAddresses, address.Address)
+		if balance, ok := provider.balances[address.Address]; ok {
+			balances[i] = big.NewInt(balance)
+		}
+	}
+
+	for _, balance := range balances {
+		if balance == nil {
+			return balances, provider.err
+		}
+	}
+	return balances, nil
+}
+
+// also returns counts of transactions, missing addresses have no count
+type testTxCountBalanceProvider struct {
+	testBalanceProvider
+	txCounts map[string]int64
+}
+
+func (provider *testTxCountBalanceProvider) GetBalanceAndTxCountBunch(addresses []currencies.AddressData) ([]*big.Int, []int64, error) {
+	balances, err := provider.GetBalanceBunch(addresses)
+
+	txCounts := make([]int64, len(addresses))
+	for i, address := range addresses {
+		txCount, ok := provider.txCounts[address.Address]
+		if !ok {
+			txCount = -1
+		}
+		txCounts[i] = txCount
+	}
+	return balances, txCounts, err
+}
+
+func makeTestAddresses(addresses ...string) (result []currencies.AddressData) {
+	for _, address := range addresses {
+		result = append(result, currencies.AddressData{Currency: currencies.Bitcoin, Address: address})
+	}
+	return
+}
+
+func TestBalanceProvidersFailover(t *testing.T) {
+	assert := require.New(t)
+
+	first := &testBalanceProvider{name: "first", balances: map[string]int64{"a": 1, "c": 3}}
+	second := &testBalanceProvider{name: "second", balances: map[string]int64{"a": 10, "b": 20}}
+	third := &testBalanceProvider{name: "third", balances: map[string]int64{}}
+
+	SetBalanceProviders(currencies.Bitcoin, []BalanceProvider{first, second, third})
+	defer SetBalanceProviders(currencies.Bitcoin, nil)
+
+	balances := getCurrencyBalanceBunch(currencies.Bitcoin, makeTestAddresses("a", "b", "c", "d"))
+
+	assert.Equal(4, len(balances))
+	assert.Equal(int64(1), balances[0].Int64())
+	assert.Equal(int64(20), balances[1].Int64())
+	assert.Equal(int64(3), balances[2].Int64())
+	assert.Nil(balances[3])
+
+	// only unknown balances are requested from the next providers
+	assert.Equal([]string{"a", "b", "c", "d"}, first.requestedAddresses)
+	assert.Equal([]string{"b", "d"}, second.requestedAddresses)
+	assert.Equal([]string{"d"}, third.requestedAddresses)
+
+	// all the balances are known, the other providers are not requested
+	first.requestedAddresses = nil
+	second.requestedAddresses = nil
+	assert.Equal(int64(3), getCurrencyBalance(currencies.Bitcoin, makeTestAddresses("c")[0]).Int64())
+	assert.Equal([]string{"c"}, first.requestedAddresses)
+	assert.Nil(second.requestedAddresses)
+}
+
+func TestBalanceProvidersCrossCheck(t *testing.T) {
+	assert := require.New(t)
+
+	first := &testBalanceProvider{name: "first", balances: map[string]int64{"a": 1, "b": 2, "c": 3}}
+	second := &testBalanceProvider{name: "second", balances: map[string]int64{"a": 1, "b": 5, "d": 4}}
+
+	SetBalanceProviders(currencies.Bitcoin, []BalanceProvider{first, second})
+	SetBalanceCrossCheckEnabled(true)
+	defer SetBalanceProviders(currencies.Bitcoin, nil)
+	defer SetBalanceCrossCheckEnabled(false)
+
+	balances := getCurrencyBalanceBunch(currencies.Bitcoin, makeTestAddresses("a", "b", "c", "d"))
+
+	assert.Equal(4, len(balances))
+	// the providers agree
+	assert.Equal(int64(1), balances[0].Int64())
+	// the providers don't agree
+	assert.Nil(balances[1])
+	// the second provider doesn't know the balance, so it can't be checked
+	assert.Equal(int64(3), balances[2].Int64())
+	// returned by the second provider, the first one can't check it
+	assert.Equal(int64(4), balances[3].Int64())
+
+	// the mismatch is reported as the reason
+	result := getCurrencyBalanceResults(currencies.Bitcoin, makeTestAddresses("b"))[0]
+	assert.Nil(result.Balance)
+	assert.NotNil(result.Error)
+}
+
+func TestBalanceResults(t *testing.T) {
+	assert := require.New(t)
+
+	first := &testBalanceProvider{name: "first", balances: map[string]int64{"a": 1, "c": 0}, err: fmt.Errorf("timeout")}
+	second := &testBalanceProvider{name: "second", balances: map[string]int64{"b": 2}}
+
+	SetBalanceProviders(currencies.Bitcoin, []BalanceProvider{first, second})
+	defer SetBalanceProviders(currencies.Bitcoin, nil)
+
+	results := getCurrencyBalanceResults(currencies.Bitcoin, makeTestAddresses("a", "b", "c", "d"))
+
+	assert.Equal(4, len(results))
+	// the providers don't count transactions, so the addresses with balances are used
+	assert.Equal(BalanceResult{Balance: big.NewInt(1), Provider: "first", IsUsed: true}, results[0])
+	assert.Equal(BalanceResult{Balance: big.NewInt(2), Provider: "second", IsUsed: true}, results[1])
+	// zero is a known balance
+	assert.Equal(BalanceResult{Balance: big.NewInt(0), Provider: "first"}, results[2])
+	// unknown, the error is from the last provider that was asked
+	assert.Nil(results[3].Balance)
+	assert.Equal("second", results[3].Provider)
+	assert.NotNil(results[3].Error)
+
+	// the error of the provider is kept
+	SetBalanceProviders(currencies.Bitcoin, []BalanceProvider{first})
+	results = getCurrencyBalanceResults(currencies.Bitcoin, makeTestAddresses("d"))
+	assert.Nil(results[0].Balance)
+	assert.Equal("timeout", results[0].Error.Error())
+}
+
+func TestBalanceResultsUsage(t *testing.T) {
+	assert := require.New(t)
+
+	provider := &testTxCountBalanceProvider{
+		testBalanceProvider: testBalanceProvider{name: "counting", balances: map[string]int64{"a": 0, "b": 5, "c": 0, "d": 3}},
+		txCounts: map[string]int64{"a": 2, "b": 0, "c": 0},
+	}
+
+	SetBalanceProviders(currencies.Bitcoin, []BalanceProvider{provider})
+	defer SetBalanceProviders(currencies.Bitcoin, nil)
+
+	results := getCurrencyBalanceResults(currencies.Bitcoin, makeTestAddresses("a", "b", "c", "d", "e"))
+
+	// spent everything, but had transactions
+	assert.True(results[0].IsUsed)
+	// the count of transactions decides when it is known
+	assert.False(results[1].IsUsed)
+	assert.False(results[2].IsUsed)
+	// no count, the balance decides
+	assert.True(results[3].IsUsed)
+	// unknown
+	assert.False(results[4].IsUsed)
+}
+
+func TestMakeBalanceProvider(t *testing.T) {
+	assert := require.New(t)
+
+	assert.NotNil(MakeBalanceProvider(currencies.Bitcoin, esploraProvider, "", ""))
+	assert.NotNil(MakeBalanceProvider(currencies.Erc20Token, etherscanProvider, "", ""))
+	assert.Nil(MakeBalanceProvider(currencies.RippleXrp, esploraProvider, "", ""))
+	assert.NotNil(MakeBalanceProvider(currencies.RippleXrp, xrplProvider, "", ""))
+	assert.Nil(MakeBalanceProvider(currencies.Bitcoin, xrplProvider, "", ""))
+	assert.Nil(MakeBalanceProvider(currencies.Bitcoin, "unknown", "", ""))
+	// any API with the same format can be used with a custom URL
+	assert.NotNil(MakeBalanceProvider(currencies.BitcoinGold, esploraProvider, "https://example.com/api/", ""))
+
+	// every currency has a provider by default
+	for currency := range GetAllProcessors() {
+		providers, _ := getBalanceProviders(currency)
+		assert.NotEqual(0, len(providers))
+	}
+}
+
+func TestEtherscanApiKey(t *testing.T) {
+	assert := require.New(t)
+
+	defer SetBalanceProviders(currencies.Ether, nil)
+	defer SetBalanceProviders(currencies.Erc20Token, nil)
+
+	SetBalanceProviders(currencies.Ether, []BalanceProvider{MakeBalanceProvider(currencies.Ether, etherscanProvider, "", "")})
+	SetBalanceProviders(currencies.Erc20Token, []BalanceProvider{MakeBalanceProvider(currencies.Erc20Token, tokenBalanceProvider, "", "")})
+	assert.Equal("", getEtherscanApiKey(currencies.Ether))
+	assert.Equal("", getEtherscanApiKeyParam(getEtherscanApiKey(currencies.Ether)))
+
+	// tokens use the key of Ether if they have no etherscan provider
+	SetBalanceProviders(currencies.Ether, []BalanceProvider{MakeBalanceProvider(currencies.Ether, etherscanProvider, "", "ethkey")})
+	assert.Equal("ethkey", getEtherscanApiKey(currencies.Ether))
+	assert.Equal("ethkey", getEtherscanApiKey(currencies.Erc20Token))
+	assert.Equal("&apikey=ethkey", getEtherscanApiKeyParam(getEtherscanApiKey(currencies.Ether)))
+
+	SetBalanceProviders(currencies.Erc20Token, []BalanceProvider{MakeBalanceProvider(currencies.Erc20Token, etherscanProvider, "", "tokenkey")})
+	assert.Equal("tokenkey", getEtherscanApiKey(currencies.Erc20Token))
+}
+
+func TestEsploraBalanceParsing(t *testing.T) {
+	assert := require.New(t)
+
+	balance, txCount := parseEsploraBalance(readTestData(t, "esploraAddress.json"))
+	assert.Equal(int64(135000), balance.Int64())
+	// unconfirmed transactions are counted
+	assert.Equal(int64(6), txCount)
+
+	balance, _ = parseEsploraBalance([]byte("<html>"))
+	assert.Nil(balance)
+}
+
+func TestXrplAccountInfoParsing(t *testing.T) {
+	assert := require.New(t)
+
+	balance, err := parseXrplAccountInfo(readTestData(t, "xrplAccountInfo.json"))
+	assert.Nil(err)
+	assert.Equal(int64(123500000), balance.Int64())
+
+	// the account was never funded
+	balance, err = parseXrplAccountInfo(readTestData(t, "xrplAccountNotFound.json"))
+	assert.Nil(err)
+	assert.Equal(int64(0), balance.Int64())
+
+	balance, err = parseXrplAccountInfo([]byte(`{"result":{"error":"noNetwork","status":"error"}}`))
+	assert.NotNil(err)
+	assert.Nil(balance)
+
+	balance, err = parseXrplAccountInfo([]byte("<html>"))
+	assert.NotNil(err)
+	assert.Nil(balance)
+}
+
+func TestXrplBalanceFailoverOverHttp(t *testing.T) {
+	assert := require.New(t)
+	defer setTestRetryDelays()()
+
+	rippleDataServer, _ := makeStatusesServer(http.StatusInternalServerError)
+	defer rippleDataServer.Close()
+
+	accountInfo := readTestData(t, "xrplAccountInfo.json")
+	xrplServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var request struct {
+			Method string `json:"method"`
+			Params []map[string]string `json:"params"`
+		}
+		if r.Method != "POST" || json.Unmarshal(body, &request) != nil || request.Method != "account_info" ||
+			len(request.Params) != 1 || request.Params[0]["account"] != "rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write(accountInfo)
+	}))
+	defer xrplServer.Close()
+
+	SetBalanceProviders(currencies.RippleXrp, []BalanceProvider{
+		MakeBalanceProvider(currencies.RippleXrp, rippleDataProvider, rippleDataServer.URL + "/", ""),
+		MakeBalanceProvider(currencies.RippleXrp, xrplProvider, xrplServer.URL + "/", ""),
+	})
+	defer SetBalanceProviders(currencies.RippleXrp, nil)
+
+	balance := (*GetProcessor(currencies.RippleXrp)).GetBalance(makeTestAddresses("rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn")[0])
+	assert.NotNil(balance)
+	assert.Equal(int64(123500000), balance.Int64())
+}
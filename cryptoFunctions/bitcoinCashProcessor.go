@@ -2,74 +2,47 @@ package cryptoFunctions
 
 import (
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
-	"encoding/json"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"strconv"
+	"fmt"
 	"math/big"
 )
 
+const bitcoinCashApiUrl string = "https://api.blockchair.com/bitcoin-cash/dashboards/"
+
 type BitcoinCashProcessor struct {
+	processorApiUrl
 }
 
-type BitcoinCashRespData struct {
-	SumValueUnspent string `json:"sum_value_unspent"`
+func (processor *BitcoinCashProcessor) GetBalance(address currencies.AddressData) *big.Int {
+	return getCurrencyBalance(currencies.BitcoinCash, address)
 }
 
-type BitcoinCashResp struct {
-	Data []BitcoinCashRespData `json:"data"`
+func (processor *BitcoinCashProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
+	return getCurrencyBalanceBunch(currencies.BitcoinCash, addresses)
 }
 
-func (processor *BitcoinCashProcessor) GetBalance(address currencies.AddressData) *big.Int {
-	resp, err := http.Get("https://api.blockchair.com/bitcoin-cash/dashboards/address/" + address.Address)
-	if err != nil {
-		log.Print(err)
-		return nil
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Print(err)
-		return nil
-	}
-
-	var parsedResp = new(BitcoinCashResp)
-	err = json.Unmarshal(body, &parsedResp)
-	if(err != nil){
-		log.Print(string(body[:]))
-		log.Print(err)
-		return nil
-	}
+func (processor *BitcoinCashProcessor) GetBalanceResults(addresses []currencies.AddressData) []BalanceResult {
+	return getCurrencyBalanceResults(currencies.BitcoinCash, addresses)
+}
 
-	if len(parsedResp.Data) > 0 {
-		intValue, err := strconv.ParseInt(parsedResp.Data[0].SumValueUnspent, 10, 64)
+func (processor *BitcoinCashProcessor) GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error) {
+	apiUrl := processor.getApiUrl(bitcoinCashApiUrl)
 
-		if err == nil {
-			return big.NewInt(intValue)
-		} else {
-			return nil
-		}
+	var requestText string
+	if limit > 0 {
+		requestText = fmt.Sprintf("%saddress/%s?transaction_details=true&limit=%d&offset=%d", apiUrl, address.Address, limit, offset)
 	} else {
-		return nil
+		requestText = fmt.Sprintf("%saddress/%s?transaction_details=true&offset=%d", apiUrl, address.Address, offset)
 	}
-}
 
-func (processor *BitcoinCashProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
-	balances := make([]*big.Int, len(addresses))
-
-	for i, walletAddress := range addresses {
-		balances[i] = processor.GetBalance(walletAddress)
+	addressData, err := getBlockchairAddressData(requestText, address.Address)
+	if err != nil {
+		return nil, err
 	}
 
-	return balances
-}
-
-func (processor *BitcoinCashProcessor) GetTransactionsHistory(address currencies.AddressData, limit int) (history []currencies.TransactionsHistoryItem) {
-	return
+	return makeBlockchairHistory(address.Address, addressData), nil
 }
 
+// legacy addresses are shared with Bitcoin, the new ones are in CashAddr format (with or without "bitcoincash:")
 func (processor *BitcoinCashProcessor) IsAddressValid(address string) bool {
-	return isBitcoinAddressValid(address)
+	return isBase58AddressValid(address, bitcoinP2pkhVersion, bitcoinP2shVersion) || isCashAddressValid("bitcoincash", address)
 }
@@ -2,64 +2,228 @@ package cryptoFunctions
 
 import (
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
-	"io/ioutil"
+	"encoding/json"
+	"fmt"
 	"log"
-	"net/http"
 	"strconv"
+	"strings"
 	"math/big"
-	"regexp"
+	"time"
 )
 
-var bitcoinGoldAddressRegex *regexp.Regexp
+const (
+	bitcoinGoldP2pkhVersion byte = 38 // G...
+	bitcoinGoldP2shVersion byte = 23 // A...
+)
+
+const bitcoinGoldInsightApiUrl string = "https://explorer.bitcoingold.org/insight-api/"
+
+// the insight API doesn't return more than 50 transactions at once
+const bitcoinGoldMaxHistoryRecords int = 50
+
+const btgexpApiUrl string = "http://btgexp.com/ext/"
 
 type BitcoinGoldProcessor struct {
+	processorApiUrl
 }
 
-func init() {
-	bitcoinGoldAddressRegex = regexp.MustCompile("^[GA][1-9A-HJ-NP-Za-km-z]{10,51}$")
-	if bitcoinGoldAddressRegex == nil {
-		log.Fatal("Wrong regexp")
-	}
+type BitcoinGoldHistoryInput struct {
+	Addr string `json:"addr"`
+	ValueSat int64 `json:"valueSat"`
 }
 
-func (processor *BitcoinGoldProcessor) GetBalance(address currencies.AddressData) *big.Int {
-	resp, err := http.Get("http://btgexp.com/ext/getbalance/" + address.Address)
-	if err != nil {
-		log.Print(err)
+type BitcoinGoldScriptPubKey struct {
+	Addresses []string `json:"addresses"`
+}
+
+type BitcoinGoldHistoryOutput struct {
+	Value string `json:"value"`
+	ScriptPubKey BitcoinGoldScriptPubKey `json:"scriptPubKey"`
+}
+
+type BitcoinGoldHistoryRespItem struct {
+	Txid string `json:"txid"`
+	BlockHeight int64 `json:"blockheight"` // -1 for unconfirmed transactions
+	Confirmations int64 `json:"confirmations"`
+	// coinbase transactions don't have fees
+	Fees json.Number `json:"fees"`
+	Time int64 `json:"time"`
+	Vin []BitcoinGoldHistoryInput `json:"vin"`
+	Vout []BitcoinGoldHistoryOutput `json:"vout"`
+}
+
+type BitcoinGoldHistoryResp struct {
+	Items []BitcoinGoldHistoryRespItem `json:"items"`
+}
+
+type btgexpBalanceProvider struct {
+	apiUrl string
+}
+
+func makeBtgexpBalanceProvider(currency currencies.Currency, url string) BalanceProvider {
+	if currency != currencies.BitcoinGold {
 		return nil
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	if url == "" {
+		url = btgexpApiUrl
+	}
+
+	return &btgexpBalanceProvider{
+		apiUrl: url,
+	}
+}
+
+func (provider *btgexpBalanceProvider) GetName() string {
+	return btgexpProvider
+}
+
+func (provider *btgexpBalanceProvider) getBalance(address currencies.AddressData) (*big.Int, error) {
+	body, err := fetchResponseBody(provider.apiUrl + "getbalance/" + address.Address)
 	if err != nil {
-		log.Print(err)
-		return nil
+		return nil, err
 	}
 
 	floatValue, err := strconv.ParseFloat(string(body[:]), 64)
+	if err != nil {
+		return nil, err
+	}
 
-	if err == nil {
-		return big.NewInt(int64(floatValue * 1.0E8))
-	} else {
-		log.Print(err)
+	return big.NewInt(int64(floatValue * 1.0E8)), nil
+}
+
+func (provider *btgexpBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
+	return getBalancesConcurrently(btgexpProvider, addresses, provider.getBalance)
+}
+
+// the insight API of the official explorer
+type insightBalanceProvider struct {
+	apiUrl string
+}
+
+func makeInsightBalanceProvider(currency currencies.Currency, url string) BalanceProvider {
+	if currency != currencies.BitcoinGold {
 		return nil
 	}
+
+	if url == "" {
+		url = bitcoinGoldInsightApiUrl
+	}
+
+	return &insightBalanceProvider{
+		apiUrl: url,
+	}
+}
+
+func (provider *insightBalanceProvider) GetName() string {
+	return insightProvider
+}
+
+func (provider *insightBalanceProvider) getBalance(address currencies.AddressData) (*big.Int, error) {
+	body, err := fetchResponseBody(provider.apiUrl + "addr/" + address.Address + "/balance")
+	if err != nil {
+		return nil, err
+	}
+
+	// the balance is returned in satoshis as a plain number
+	intValue, ok := new(big.Int).SetString(strings.TrimSpace(string(body[:])), 10)
+	if !ok {
+		return nil, fmt.Errorf("can't parse balance: %s", string(body[:]))
+	}
+
+	return intValue, nil
+}
+
+func (provider *insightBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
+	return getBalancesConcurrently(insightProvider, addresses, provider.getBalance)
+}
+
+func (processor *BitcoinGoldProcessor) GetBalance(address currencies.AddressData) *big.Int {
+	return getCurrencyBalance(currencies.BitcoinGold, address)
 }
 
 func (processor *BitcoinGoldProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
-	balances := make([]*big.Int, len(addresses))
+	return getCurrencyBalanceBunch(currencies.BitcoinGold, addresses)
+}
+
+func (processor *BitcoinGoldProcessor) GetBalanceResults(addresses []currencies.AddressData) []BalanceResult {
+	return getCurrencyBalanceResults(currencies.BitcoinGold, addresses)
+}
+
+func (processor *BitcoinGoldProcessor) GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error) {
+	// the API returns at most bitcoinGoldMaxHistoryRecords at once
+	// so the windows are requested one by one until the limit or the end of the history
+	history = []currencies.TransactionsHistoryItem{}
+	for limit <= 0 || len(history) < limit {
+		windowSize := bitcoinGoldMaxHistoryRecords
+		if limit > 0 && limit - len(history) < windowSize {
+			windowSize = limit - len(history)
+		}
 
-	for i, walletAddress := range addresses {
-		balances[i] = processor.GetBalance(walletAddress)
+		from := offset + len(history)
+		body, err := fetchResponseBody(fmt.Sprintf("%saddrs/%s/txs?from=%d&to=%d", processor.getApiUrl(bitcoinGoldInsightApiUrl), address.Address, from, from + windowSize))
+		if err != nil {
+			return nil, err
+		}
+
+		page := parseBitcoinGoldHistory(body, address.Address)
+		if page == nil {
+			return nil, fmt.Errorf("can't parse the history of %s", address.Address)
+		}
+		history = append(history, page...)
+
+		if len(page) < windowSize {
+			break
+		}
 	}
 
-	return balances
+	return history, nil
 }
 
-func (processor *BitcoinGoldProcessor) GetTransactionsHistory(address currencies.AddressData, limit int) (history []currencies.TransactionsHistoryItem) {
+func parseBitcoinGoldHistory(body []byte, address string) (history []currencies.TransactionsHistoryItem) {
+	var parsedResp = new(BitcoinGoldHistoryResp)
+	err := json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		log.Print(err)
+		return
+	}
+
+	history = make([]currencies.TransactionsHistoryItem, 0, len(parsedResp.Items))
+
+	for _, transaction := range parsedResp.Items {
+		inputs := make([]utxoEntry, len(transaction.Vin))
+		for i, input := range transaction.Vin {
+			// coinbase inputs don't have an address
+			if input.Addr != "" {
+				inputs[i] = utxoEntry{addresses: []string{input.Addr}, value: big.NewInt(input.ValueSat)}
+			}
+		}
+
+		outputs := make([]utxoEntry, len(transaction.Vout))
+		for i, output := range transaction.Vout {
+			value, ok := parseDecimalAmount(output.Value, currencies.GetCurrencyDecimals(currencies.BitcoinGold))
+			if !ok {
+				log.Printf("Wrong amount value: %s", output.Value)
+			}
+			outputs[i] = utxoEntry{addresses: output.ScriptPubKey.Addresses, value: value}
+		}
+
+		historyItem := makeUtxoHistoryItem(address, inputs, outputs, time.Unix(transaction.Time, 0))
+		historyItem.Hash = transaction.Txid
+		if transaction.Fees != "" {
+			historyItem.Fee, _ = parseDecimalAmount(transaction.Fees.String(), currencies.GetCurrencyDecimals(currencies.BitcoinGold))
+		} else {
+			historyItem.Fee = big.NewInt(0)
+		}
+		fillUtxoBlockData(&historyItem, transaction.BlockHeight, transaction.Confirmations)
+
+		history = append(history, historyItem)
+	}
+
 	return
 }
 
 func (processor *BitcoinGoldProcessor) IsAddressValid(address string) bool {
-	return bitcoinGoldAddressRegex.MatchString(address)
+	return isBase58AddressValid(address, bitcoinGoldP2pkhVersion, bitcoinGoldP2shVersion) || isSegwitAddressValid("btg", address)
 }
@@ -3,21 +3,28 @@ package cryptoFunctions
 import (
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"encoding/json"
-	"io/ioutil"
-	"net/http"
+	"fmt"
 	"log"
 	"math/big"
-	"regexp"
+	"time"
 )
 
-var bitcoinAddressRegex *regexp.Regexp
+const (
+	bitcoinP2pkhVersion byte = 0x00
+	bitcoinP2shVersion byte = 0x05
+)
+
+const btcComApiUrl string = "https://chain.api.btc.com/v3/"
 
 type BitcoinProcessor struct {
+	processorApiUrl
 }
 
 type BitcoinRespData struct {
 	Address string `json:"address"`
 	Balance int64 `json:"balance"`
+	TxCount int64 `json:"tx_count"`
+	UnconfirmedTxCount int64 `json:"unconfirmed_tx_count"`
 }
 
 type BitcoinResp struct {
@@ -28,66 +35,111 @@ type BitcoinMultiResp struct {
 	Data []BitcoinRespData `json:"data"`
 }
 
-func init() {
-	bitcoinAddressRegex = regexp.MustCompile("^[13][a-zA-Z0-9]{25,34}$")
-	if bitcoinAddressRegex == nil {
-		log.Fatal("Wrong regexp")
-	}
+type BitcoinHistoryInput struct {
+	PrevAddresses []string `json:"prev_addresses"`
+	PrevValue int64 `json:"prev_value"`
 }
 
-func (processor *BitcoinProcessor) GetBalance(address currencies.AddressData) *big.Int {
-	resp, err := http.Get("https://chain.api.btc.com/v3/address/" + address.Address)
-	if err != nil {
-		log.Print(err)
+type BitcoinHistoryOutput struct {
+	Addresses []string `json:"addresses"`
+	Value int64 `json:"value"`
+}
+
+type BitcoinHistoryRespItem struct {
+	Hash string `json:"hash"`
+	BlockHeight int64 `json:"block_height"` // -1 for unconfirmed transactions
+	Confirmations int64 `json:"confirmations"`
+	Fee int64 `json:"fee"`
+	BlockTime int64 `json:"block_time"`
+	CreatedAt int64 `json:"created_at"`
+	Inputs []BitcoinHistoryInput `json:"inputs"`
+	Outputs []BitcoinHistoryOutput `json:"outputs"`
+}
+
+type BitcoinHistoryRespData struct {
+	List []BitcoinHistoryRespItem `json:"list"`
+}
+
+type BitcoinHistoryResp struct {
+	Data BitcoinHistoryRespData `json:"data"`
+}
+
+// chain.api.btc.com
+type btcComBalanceProvider struct {
+	apiUrl string
+}
+
+func makeBtcComBalanceProvider(currency currencies.Currency, url string) BalanceProvider {
+	if currency != currencies.Bitcoin {
 		return nil
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	if url == "" {
+		url = btcComApiUrl
+	}
+
+	return &btcComBalanceProvider{
+		apiUrl: url,
+	}
+}
+
+func (provider *btcComBalanceProvider) GetName() string {
+	return btcComProvider
+}
+
+// unconfirmed transactions are counted as well
+func (data *BitcoinRespData) getTxCount() int64 {
+	return data.TxCount + data.UnconfirmedTxCount
+}
+
+func (provider *btcComBalanceProvider) getBalanceAndTxCount(address currencies.AddressData) (*big.Int, int64, error) {
+	body, err := fetchResponseBody(provider.apiUrl + "address/" + address.Address)
 	if err != nil {
-		log.Print(err)
-		return nil
+		return nil, -1, err
 	}
 
 	var parsedResp = new(BitcoinResp)
 	err = json.Unmarshal(body, &parsedResp)
 	if(err != nil){
 		log.Print(string(body[:]))
-		log.Print(err)
-		return nil
+		return nil, -1, err
 	}
 
-	return big.NewInt(parsedResp.Data.Balance)
+	return big.NewInt(parsedResp.Data.Balance), parsedResp.Data.getTxCount(), nil
 }
 
-func (processor *BitcoinProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
+func (provider *btcComBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
+	balances, _, err := provider.GetBalanceAndTxCountBunch(addresses)
+	return balances, err
+}
+
+func (provider *btcComBalanceProvider) GetBalanceAndTxCountBunch(addresses []currencies.AddressData) (balances []*big.Int, txCounts []int64, err error) {
+	// all the balances are requested at once
+	runProviderRequest(btcComProvider, func() {
+		balances, txCounts, err = provider.getBalanceBunch(addresses)
+	})
+	return
+}
+
+func (provider *btcComBalanceProvider) getBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, []int64, error) {
 	if len(addresses) == 1 {
-		return []*big.Int {
-			processor.GetBalance(addresses[0]),
-		}
+		balance, txCount, err := provider.getBalanceAndTxCount(addresses[0])
+		return []*big.Int{balance}, []int64{txCount}, err
 	}
 
 	balances := make([]*big.Int, len(addresses))
+	txCounts := makeUnknownTxCounts(len(addresses))
 
-	resp, err := http.Get("https://chain.api.btc.com/v3/address/" + joinAddresses(addresses))
-	if err != nil {
-		log.Print(err)
-		return balances
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := fetchResponseBody(provider.apiUrl + "address/" + joinAddresses(addresses))
 	if err != nil {
-		log.Print(err)
-		return balances
+		return balances, txCounts, err
 	}
 
 	var parsedResp = new(BitcoinMultiResp)
 	err = json.Unmarshal(body, &parsedResp)
 	if(err != nil){
 		log.Print(string(body[:]))
-		log.Print(err)
-		return balances
+		return balances, txCounts, err
 	}
 
 	// I'm not sure if it's more time efficient
@@ -99,13 +151,86 @@ func (processor *BitcoinProcessor) GetBalanceBunch(addresses []currencies.Addres
 	for _, data := range parsedResp.Data {
 		if i, ok := addressesIndexes[data.Address]; ok {
 			balances[i] = big.NewInt(data.Balance)
+			txCounts[i] = data.getTxCount()
 		}
 	}
 
-	return balances
+	return balances, txCounts, nil
+}
+
+func (processor *BitcoinProcessor) GetBalance(address currencies.AddressData) *big.Int {
+	return getCurrencyBalance(currencies.Bitcoin, address)
+}
+
+func (processor *BitcoinProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
+	return getCurrencyBalanceBunch(currencies.Bitcoin, addresses)
+}
+
+func (processor *BitcoinProcessor) GetBalanceResults(addresses []currencies.AddressData) []BalanceResult {
+	return getCurrencyBalanceResults(currencies.Bitcoin, addresses)
+}
+
+func (processor *BitcoinProcessor) GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error) {
+	page, pageSize, skip := getPageParams(offset, limit)
+
+	apiUrl := processor.getApiUrl(btcComApiUrl)
+
+	var requestText string
+	if pageSize > 0 {
+		requestText = fmt.Sprintf("%saddress/%s/tx?page=%d&pagesize=%d", apiUrl, address.Address, page, pageSize)
+	} else {
+		requestText = fmt.Sprintf("%saddress/%s/tx", apiUrl, address.Address)
+	}
+
+	body, err := fetchResponseBody(requestText)
+	if err != nil {
+		return nil, err
+	}
+
+	history = parseBitcoinHistory(body, address.Address)
+	if history == nil {
+		return nil, fmt.Errorf("can't parse the history of %s", address.Address)
+	}
+
+	return skipHistoryItems(history, skip), nil
 }
 
-func (processor *BitcoinProcessor) GetTransactionsHistory(address currencies.AddressData, limit int) (history []currencies.TransactionsHistoryItem) {
+func parseBitcoinHistory(body []byte, address string) (history []currencies.TransactionsHistoryItem) {
+	var parsedResp = new(BitcoinHistoryResp)
+	err := json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		log.Print(err)
+		return
+	}
+
+	history = make([]currencies.TransactionsHistoryItem, 0, len(parsedResp.Data.List))
+
+	for _, transaction := range parsedResp.Data.List {
+		inputs := make([]utxoEntry, len(transaction.Inputs))
+		for i, input := range transaction.Inputs {
+			inputs[i] = utxoEntry{addresses: input.PrevAddresses, value: big.NewInt(input.PrevValue)}
+		}
+
+		outputs := make([]utxoEntry, len(transaction.Outputs))
+		for i, output := range transaction.Outputs {
+			outputs[i] = utxoEntry{addresses: output.Addresses, value: big.NewInt(output.Value)}
+		}
+
+		// unconfirmed transactions don't have block time yet
+		txTime := transaction.BlockTime
+		if txTime == 0 {
+			txTime = transaction.CreatedAt
+		}
+
+		historyItem := makeUtxoHistoryItem(address, inputs, outputs, time.Unix(txTime, 0))
+		historyItem.Hash = transaction.Hash
+		historyItem.Fee = big.NewInt(transaction.Fee)
+		fillUtxoBlockData(&historyItem, transaction.BlockHeight, transaction.Confirmations)
+
+		history = append(history, historyItem)
+	}
+
 	return
 }
 
@@ -113,6 +238,7 @@ func (processor *BitcoinProcessor) IsAddressValid(address string) bool {
 	return isBitcoinAddressValid(address)
 }
 
+// legacy (1..., 3...) and SegWit/Taproot (bc1...) addresses
 func isBitcoinAddressValid(address string) bool {
-	return bitcoinAddressRegex.MatchString(address)
+	return isBase58AddressValid(address, bitcoinP2pkhVersion, bitcoinP2shVersion) || isSegwitAddressValid("bc", address)
 }
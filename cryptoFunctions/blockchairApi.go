@@ -0,0 +1,226 @@
+package cryptoFunctions
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+)
+
+// blockchair dashboards API is the same for all the coins it supports
+
+type BlockchairAddressInfo struct {
+	Balance int64 `json:"balance"`
+	TransactionCount int64 `json:"transaction_count"`
+}
+
+type BlockchairTransactionInfo struct {
+	BlockId int64 `json:"block_id"` // -1 for unconfirmed transactions
+	Hash string `json:"hash"`
+	Time string `json:"time"`
+	// net change of the address balance, it already takes into account all the inputs and outputs
+	BalanceChange int64 `json:"balance_change"`
+}
+
+type BlockchairAddressRespData struct {
+	Address BlockchairAddressInfo `json:"address"`
+	Transactions []BlockchairTransactionInfo `json:"transactions"`
+	// height of the latest block, taken from the context of the response
+	LatestBlock int64 `json:"-"`
+}
+
+type BlockchairContext struct {
+	State int64 `json:"state"`
+}
+
+type BlockchairResp struct {
+	Data map[string]BlockchairAddressRespData `json:"data"`
+	Context BlockchairContext `json:"context"`
+}
+
+type BlockchairMultiRespData struct {
+	Addresses map[string]BlockchairAddressInfo `json:"addresses"`
+}
+
+type BlockchairMultiResp struct {
+	Data BlockchairMultiRespData `json:"data"`
+}
+
+const bitcoinBlockchairApiUrl string = "https://api.blockchair.com/bitcoin/dashboards/"
+
+type blockchairBalanceProvider struct {
+	currency currencies.Currency
+	apiUrl string
+}
+
+func makeBlockchairBalanceProvider(currency currencies.Currency, url string) BalanceProvider {
+	if url == "" {
+		switch currency {
+		case currencies.Bitcoin:
+			url = bitcoinBlockchairApiUrl
+		case currencies.BitcoinCash:
+			url = bitcoinCashApiUrl
+		case currencies.Litecoin:
+			url = litecoinApiUrl
+		default:
+			return nil
+		}
+	}
+
+	return &blockchairBalanceProvider{
+		currency: currency,
+		apiUrl: url,
+	}
+}
+
+func (provider *blockchairBalanceProvider) GetName() string {
+	return blockchairProvider
+}
+
+func (provider *blockchairBalanceProvider) getBalanceAndTxCount(address currencies.AddressData) (*big.Int, int64, error) {
+	body, err := fetchResponseBody(provider.apiUrl + "address/" + address.Address + "?limit=0")
+	if err != nil {
+		return nil, -1, err
+	}
+
+	addressData := parseBlockchairAddressData(body, address.Address)
+	if addressData == nil {
+		return nil, -1, fmt.Errorf("can't parse the data of %s", address.Address)
+	}
+
+	return big.NewInt(addressData.Address.Balance), addressData.Address.TransactionCount, nil
+}
+
+func (provider *blockchairBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
+	balances, _, err := provider.GetBalanceAndTxCountBunch(addresses)
+	return balances, err
+}
+
+func (provider *blockchairBalanceProvider) GetBalanceAndTxCountBunch(addresses []currencies.AddressData) (balances []*big.Int, txCounts []int64, err error) {
+	// CashAddr addresses can be returned in a different form, so they can't be matched in a bunch
+	if provider.currency == currencies.BitcoinCash {
+		return getBalancesAndTxCountsConcurrently(blockchairProvider, addresses, provider.getBalanceAndTxCount)
+	}
+
+	// all the balances are requested at once
+	runProviderRequest(blockchairProvider, func() {
+		balances, txCounts, err = provider.getBalanceBunch(addresses)
+	})
+	return
+}
+
+func (provider *blockchairBalanceProvider) getBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, []int64, error) {
+	if len(addresses) == 1 {
+		balance, txCount, err := provider.getBalanceAndTxCount(addresses[0])
+		return []*big.Int{balance}, []int64{txCount}, err
+	}
+
+	balances := make([]*big.Int, len(addresses))
+	txCounts := makeUnknownTxCounts(len(addresses))
+
+	body, err := fetchResponseBody(provider.apiUrl + "addresses/" + joinAddresses(addresses))
+	if err != nil {
+		return balances, txCounts, err
+	}
+
+	var parsedResp = new(BlockchairMultiResp)
+	err = json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		return balances, txCounts, err
+	}
+
+	for i, address := range addresses {
+		if data, ok := parsedResp.Data.Addresses[address.Address]; ok {
+			balances[i] = big.NewInt(data.Balance)
+			txCounts[i] = data.TransactionCount
+		}
+	}
+
+	return balances, txCounts, nil
+}
+
+func getBlockchairAddressData(requestText string, address string) (*BlockchairAddressRespData, error) {
+	body, err := fetchResponseBody(requestText)
+	if err != nil {
+		return nil, err
+	}
+
+	addressData := parseBlockchairAddressData(body, address)
+	if addressData == nil {
+		return nil, fmt.Errorf("can't parse the data of %s", address)
+	}
+
+	return addressData, nil
+}
+
+func parseBlockchairAddressData(body []byte, address string) *BlockchairAddressRespData {
+	var parsedResp = new(BlockchairResp)
+	err := json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		log.Print(err)
+		return nil
+	}
+
+	addressData, ok := parsedResp.Data[address]
+	if !ok {
+		// the address can be returned in a different form (e.g. CashAddr without the prefix)
+		if len(parsedResp.Data) != 1 {
+			log.Print(string(body[:]))
+			log.Printf("No data for address %s", address)
+			return nil
+		}
+		for _, data := range parsedResp.Data {
+			addressData = data
+		}
+	}
+
+	addressData.LatestBlock = parsedResp.Context.State
+
+	return &addressData
+}
+
+// fees are not returned by the dashboards API
+func makeBlockchairHistory(address string, addressData *BlockchairAddressRespData) (history []currencies.TransactionsHistoryItem) {
+	history = make([]currencies.TransactionsHistoryItem, 0, len(addressData.Transactions))
+
+	for _, transaction := range addressData.Transactions {
+		// blockchair returns time in UTC without the zone
+		txTime, err := time.Parse("2006-01-02 15:04:05", transaction.Time)
+		if err != nil {
+			log.Print(err.Error())
+		}
+
+		// only the net change of the address is known, so the other side stays empty
+		var from, to string
+		var amount *big.Int
+		if transaction.BalanceChange < 0 {
+			from = address
+			amount = big.NewInt(-transaction.BalanceChange)
+		} else {
+			to = address
+			amount = big.NewInt(transaction.BalanceChange)
+		}
+
+		historyItem := currencies.TransactionsHistoryItem {
+			From: from,
+			To: to,
+			Amount: amount,
+			Time: txTime,
+			Hash: transaction.Hash,
+		}
+
+		confirmations := int64(0)
+		if transaction.BlockId > 0 && addressData.LatestBlock >= transaction.BlockId {
+			confirmations = addressData.LatestBlock - transaction.BlockId + 1
+		}
+		fillUtxoBlockData(&historyItem, transaction.BlockId, confirmations)
+
+		history = append(history, historyItem)
+	}
+
+	return
+}
@@ -0,0 +1,111 @@
+package cryptoFunctions
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"math/big"
+	"sync"
+)
+
+// names of the providers that are used in the configuration and to limit parallel requests
+const (
+	blockchairProvider string = "blockchair"
+	btcComProvider string = "btc.com"
+	btgexpProvider string = "btgexp"
+	esploraProvider string = "esplora"
+	etherscanProvider string = "etherscan"
+	insightProvider string = "insight"
+	rippleDataProvider string = "ripple"
+	tokenBalanceProvider string = "tokenbalance"
+	xrplProvider string = "xrpl"
+)
+
+// how many requests can be sent to one provider at the same time if not configured
+const defaultProviderConcurrencyLimit int = 4
+
+var providerConcurrencyLimits map[string]int = map[string]int{}
+
+// provider name -> semaphore that holds the running requests
+var providerSemaphores map[string]chan struct{} = map[string]chan struct{}{}
+
+var providerSemaphoresMutex sync.Mutex
+
+// provider name -> how many requests can be sent to it at the same time
+// affects only the requests that start after the call
+func SetProviderConcurrencyLimits(limits map[string]int) {
+	providerSemaphoresMutex.Lock()
+	defer providerSemaphoresMutex.Unlock()
+
+	providerConcurrencyLimits = make(map[string]int)
+	for provider, limit := range limits {
+		providerConcurrencyLimits[provider] = limit
+	}
+
+	// the running requests keep the old semaphores
+	providerSemaphores = make(map[string]chan struct{})
+}
+
+func getProviderSemaphore(provider string) chan struct{} {
+	providerSemaphoresMutex.Lock()
+	defer providerSemaphoresMutex.Unlock()
+
+	semaphore, ok := providerSemaphores[provider]
+	if !ok {
+		limit, isLimitSet := providerConcurrencyLimits[provider]
+		if !isLimitSet || limit <= 0 {
+			limit = defaultProviderConcurrencyLimit
+		}
+
+		semaphore = make(chan struct{}, limit)
+		providerSemaphores[provider] = semaphore
+	}
+
+	return semaphore
+}
+
+// blocks while the provider has too many requests running
+func runProviderRequest(provider string, request func()) {
+	semaphore := getProviderSemaphore(provider)
+	semaphore <- struct{}{}
+	defer func() { <-semaphore }()
+
+	request()
+}
+
+// requests balances one by one in parallel, the order of balances matches the order of addresses
+// returns the first of the errors if some balances can't be received
+func getBalancesConcurrently(provider string, addresses []currencies.AddressData, getBalance func(currencies.AddressData) (*big.Int, error)) ([]*big.Int, error) {
+	balances, _, err := getBalancesAndTxCountsConcurrently(provider, addresses, func(address currencies.AddressData) (*big.Int, int64, error) {
+		balance, err := getBalance(address)
+		return balance, -1, err
+	})
+	return balances, err
+}
+
+// the same as getBalancesConcurrently, but the counts of transactions are returned as well
+func getBalancesAndTxCountsConcurrently(provider string, addresses []currencies.AddressData, getBalance func(currencies.AddressData) (*big.Int, int64, error)) ([]*big.Int, []int64, error) {
+	balances := make([]*big.Int, len(addresses))
+	txCounts := make([]int64, len(addresses))
+	errors := make([]error, len(addresses))
+
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(len(addresses))
+
+	for i := range addresses {
+		go func(i int) {
+			defer waitGroup.Done()
+			runProviderRequest(provider, func() {
+				// every goroutine writes only its own elements
+				balances[i], txCounts[i], errors[i] = getBalance(addresses[i])
+			})
+		}(i)
+	}
+
+	waitGroup.Wait()
+
+	for _, err := range errors {
+		if err != nil {
+			return balances, txCounts, err
+		}
+	}
+	return balances, txCounts, nil
+}
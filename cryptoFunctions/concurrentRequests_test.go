@@ -0,0 +1,111 @@
+package cryptoFunctions
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"math/big"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// counts how many calls are running at the same time
+type concurrencyCounter struct {
+	mutex sync.Mutex
+	running int
+	maxRunning int
+}
+
+func (counter *concurrencyCounter) enter() {
+	counter.mutex.Lock()
+	counter.running++
+	if counter.running > counter.maxRunning {
+		counter.maxRunning = counter.running
+	}
+	counter.mutex.Unlock()
+}
+
+func (counter *concurrencyCounter) leave() {
+	counter.mutex.Lock()
+	counter.running--
+	counter.mutex.Unlock()
+}
+
+func TestGetBalancesConcurrently(t *testing.T) {
+	assert := require.New(t)
+
+	SetProviderConcurrencyLimits(map[string]int{"test": 3})
+	defer SetProviderConcurrencyLimits(nil)
+
+	addresses := make([]currencies.AddressData, 20)
+	for i := range addresses {
+		addresses[i] = currencies.AddressData{Currency: currencies.Bitcoin, Address: strconv.Itoa(i)}
+	}
+
+	counter := concurrencyCounter{}
+	balances, err := getBalancesConcurrently("test", addresses, func(address currencies.AddressData) (*big.Int, error) {
+		counter.enter()
+		defer counter.leave()
+
+		time.Sleep(5 * time.Millisecond)
+
+		value, _ := strconv.Atoi(address.Address)
+		if value == 7 {
+			// failed request
+			return nil, fmt.Errorf("failed request")
+		}
+		return big.NewInt(int64(value)), nil
+	})
+
+	assert.NotNil(err)
+	assert.Equal(20, len(balances))
+	for i, balance := range balances {
+		if i == 7 {
+			assert.Nil(balance)
+		} else {
+			assert.Equal(int64(i), balance.Int64())
+		}
+	}
+
+	assert.True(counter.maxRunning > 1)
+	assert.True(counter.maxRunning <= 3)
+}
+
+func TestProviderLimitIsShared(t *testing.T) {
+	assert := require.New(t)
+
+	SetProviderConcurrencyLimits(map[string]int{"shared": 2, "other": 5})
+	defer SetProviderConcurrencyLimits(nil)
+
+	sharedCounter := concurrencyCounter{}
+	otherCounter := concurrencyCounter{}
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(2)
+		go func() {
+			defer waitGroup.Done()
+			runProviderRequest("shared", func() {
+				sharedCounter.enter()
+				time.Sleep(2 * time.Millisecond)
+				sharedCounter.leave()
+			})
+		}()
+		go func() {
+			defer waitGroup.Done()
+			runProviderRequest("other", func() {
+				otherCounter.enter()
+				time.Sleep(2 * time.Millisecond)
+				otherCounter.leave()
+			})
+		}()
+	}
+	waitGroup.Wait()
+
+	assert.True(sharedCounter.maxRunning <= 2)
+	assert.True(otherCounter.maxRunning <= 5)
+	// the limit of one provider doesn't block the other
+	assert.True(otherCounter.maxRunning > 2)
+}
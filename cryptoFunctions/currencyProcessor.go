@@ -10,8 +10,28 @@ type CurrencyProcessor interface {
 	GetBalance(address currencies.AddressData) *big.Int
 	// get multiple accounts balance
 	GetBalanceBunch(addresses []currencies.AddressData) []*big.Int
+	// same as GetBalanceBunch but also tells where the balances come from and why some are unknown
+	GetBalanceResults(addresses []currencies.AddressData) []BalanceResult
 	// get history of transactions sorted from new to old
-	GetTransactionsHistory(address currencies.AddressData, limit int) (history []currencies.TransactionsHistoryItem)
+	// skips offset newest transactions, limit <= 0 means no limit
+	GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error)
 	// check adress for validness
 	IsAddressValid(address string) bool
 }
+
+// base URL of the API that a processor uses for the transactions history
+type processorApiUrl struct {
+	// the default URL is used if empty
+	apiUrl string
+}
+
+func (processorApi *processorApiUrl) SetApiUrl(url string) {
+	processorApi.apiUrl = url
+}
+
+func (processorApi *processorApiUrl) getApiUrl(defaultUrl string) string {
+	if processorApi.apiUrl != "" {
+		return processorApi.apiUrl
+	}
+	return defaultUrl
+}
@@ -2,14 +2,22 @@ package cryptoFunctions
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
-	"io/ioutil"
 	"log"
 	"math/big"
-	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const tokenBalanceApiUrl string = "https://api.tokenbalance.com/"
+
 type Erc20Processor struct {
+	// etherscan API for the history
+	processorApiUrl
+	// the default URL is used if empty
+	tokenDataApiUrl string
 }
 
 type Erc20Resp struct {
@@ -19,59 +27,90 @@ type Erc20Resp struct {
 	Decimals int64 `json:"decimals"`
 }
 
-func (processor *Erc20Processor) GetBalance(address currencies.AddressData) *big.Int {
-	resp, err := http.Get("https://api.tokenbalance.com/token/" + address.ContractAddress + "/" + address.Address)
-	if err != nil {
-		log.Print(err)
+type Erc20HistoryRespItem struct {
+	EtherscanTransactionDetails // declared in etherProcessor.go
+	From string `json:"from"`
+	To string `json:"to"`
+	// amount in the smallest units of the token
+	Value string `json:"value"`
+	ContractAddress string `json:"contractAddress"`
+	TimeStamp string `json:"timeStamp"`
+}
+
+type Erc20HistoryResp struct {
+	Result []Erc20HistoryRespItem `json:"result"`
+}
+
+type tokenBalanceBalanceProvider struct {
+	apiUrl string
+}
+
+func makeTokenBalanceBalanceProvider(currency currencies.Currency, url string) BalanceProvider {
+	if currency != currencies.Erc20Token {
 		return nil
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	if url == "" {
+		url = tokenBalanceApiUrl
+	}
+
+	return &tokenBalanceBalanceProvider{
+		apiUrl: url,
+	}
+}
+
+func (provider *tokenBalanceBalanceProvider) GetName() string {
+	return tokenBalanceProvider
+}
+
+func (provider *tokenBalanceBalanceProvider) getBalance(address currencies.AddressData) (*big.Int, error) {
+	body, err := fetchResponseBody(provider.apiUrl + "token/" + address.ContractAddress + "/" + address.Address)
 	if err != nil {
-		log.Print(err)
-		return nil
+		return nil, err
 	}
 
 	var parsedResp = new(Erc20Resp)
 	err = json.Unmarshal(body, &parsedResp)
 	if(err != nil){
 		log.Print(string(body[:]))
-		log.Print(err)
-		return nil
+		return nil, err
 	}
 
 	floatValue, _, err := new(big.Float).Parse(parsedResp.Balance, 10)
+	if err != nil {
+		return nil, err
+	}
+
 	decimals := big.NewInt(parsedResp.Decimals)
 	decimalsMultiplier := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), decimals, big.NewInt(0)))
 
-	if err == nil {
-		intValue, _ := new(big.Float).Mul(floatValue, decimalsMultiplier).Int(nil)
-		return intValue
-	} else {
-		return nil
-	}
+	intValue, _ := new(big.Float).Mul(floatValue, decimalsMultiplier).Int(nil)
+	return intValue, nil
 }
 
-func (processor *Erc20Processor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
-	balances := make([]*big.Int, len(addresses))
+func (provider *tokenBalanceBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
+	return getBalancesConcurrently(tokenBalanceProvider, addresses, provider.getBalance)
+}
 
-	for i, walletAddress := range addresses {
-		balances[i] = processor.GetBalance(walletAddress)
-	}
+func (processor *Erc20Processor) GetBalance(address currencies.AddressData) *big.Int {
+	return getCurrencyBalance(currencies.Erc20Token, address)
+}
 
-	return balances
+func (processor *Erc20Processor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
+	return getCurrencyBalanceBunch(currencies.Erc20Token, addresses)
+}
+
+func (processor *Erc20Processor) GetBalanceResults(addresses []currencies.AddressData) []BalanceResult {
+	return getCurrencyBalanceResults(currencies.Erc20Token, addresses)
 }
 
 func (processor *Erc20Processor) GetTokenData(contractAddress string) *currencies.Erc20TokenData {
-	resp, err := http.Get("https://api.tokenbalance.com/token/" + contractAddress + "/0x0")
-	if err != nil {
-		log.Print(err)
-		return nil
+	apiUrl := processor.tokenDataApiUrl
+	if apiUrl == "" {
+		apiUrl = tokenBalanceApiUrl
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := fetchResponseBody(apiUrl + "token/" + contractAddress + "/0x0")
 	if err != nil {
 		log.Print(err)
 		return nil
@@ -94,14 +133,95 @@ func (processor *Erc20Processor) GetTokenData(contractAddress string) *currencie
 	return &tokenData
 }
 
-func (processor *Erc20Processor) GetTransactionsHistory(address currencies.AddressData, limit int) (history []currencies.TransactionsHistoryItem) {
+func (processor *Erc20Processor) GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error) {
+	if address.ContractAddress == "" {
+		log.Print("No contractAddress for token")
+		return
+	}
+
+	page, pageSize, skip := getPageParams(offset, limit)
+
+	var requestText string
+	if pageSize > 0 {
+		requestText = fmt.Sprintf(
+			"%s?module=account&action=tokentx&contractaddress=%s&address=%s&page=%d&offset=%d&sort=desc%s",
+			processor.getApiUrl(etherscanApiUrl),
+			address.ContractAddress,
+			address.Address,
+			page,
+			pageSize,
+			getEtherscanApiKeyParam(getEtherscanApiKey(currencies.Erc20Token)),
+		)
+	} else {
+		requestText = fmt.Sprintf(
+			"%s?module=account&action=tokentx&contractaddress=%s&address=%s&sort=desc%s",
+			processor.getApiUrl(etherscanApiUrl),
+			address.ContractAddress,
+			address.Address,
+			getEtherscanApiKeyParam(getEtherscanApiKey(currencies.Erc20Token)),
+		)
+	}
+
+	body, err := fetchResponseBody(requestText)
+	if err != nil {
+		return nil, err
+	}
+
+	history = parseErc20History(body, address.ContractAddress)
+	if history == nil {
+		return nil, fmt.Errorf("can't parse the history of %s", address.Address)
+	}
+
+	return skipHistoryItems(history, skip), nil
+}
+
+// amounts are kept in the smallest units, they are formatted with the decimals from GetTokenData
+func parseErc20History(body []byte, contractAddress string) (history []currencies.TransactionsHistoryItem) {
+	var parsedResp = new(Erc20HistoryResp)
+	err := json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		log.Print(err)
+		return
+	}
+
+	history = make([]currencies.TransactionsHistoryItem, 0, len(parsedResp.Result))
+
+	for _, historyItem := range parsedResp.Result {
+		// transfers of other tokens can't be summed up with this one
+		if !strings.EqualFold(historyItem.ContractAddress, contractAddress) {
+			continue
+		}
+
+		amount, ok := new(big.Int).SetString(historyItem.Value, 10)
+		if !ok {
+			amount = big.NewInt(0)
+			log.Printf("Wrong amount value: %s", historyItem.Value)
+		}
+
+		intTime, err := strconv.ParseInt(historyItem.TimeStamp, 10, 64)
+		if err != nil {
+			log.Print(err.Error())
+			intTime = int64(0)
+		}
+
+		history = append(history, currencies.TransactionsHistoryItem {
+				From: historyItem.From,
+				To: historyItem.To,
+				Amount: amount,
+				Time: time.Unix(intTime, int64(0)),
+			})
+
+		historyItem.fillHistoryItem(&history[len(history) - 1])
+	}
+
 	return
 }
 
 func (processor *Erc20Processor) IsAddressValid(address string) bool {
-	return ethereumAddressRegex.MatchString(address)
+	return isEthereumAddressValid(address)
 }
 
 func (processor *Erc20Processor) IsContractAddressValid(address string) bool {
-	return ethereumAddressRegex.MatchString(address)
+	return isEthereumAddressValid(address)
 }
@@ -0,0 +1,87 @@
+package cryptoFunctions
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+)
+
+// Esplora API is used by blockstream.info and its forks for other coins
+
+type EsploraStats struct {
+	FundedTxoSum int64 `json:"funded_txo_sum"`
+	SpentTxoSum int64 `json:"spent_txo_sum"`
+	TxCount int64 `json:"tx_count"`
+}
+
+type EsploraAddressResp struct {
+	ChainStats EsploraStats `json:"chain_stats"`
+	MempoolStats EsploraStats `json:"mempool_stats"`
+}
+
+type esploraBalanceProvider struct {
+	apiUrl string
+}
+
+func makeEsploraBalanceProvider(currency currencies.Currency, url string) BalanceProvider {
+	if url == "" {
+		switch currency {
+		case currencies.Bitcoin:
+			url = "https://blockstream.info/api/"
+		case currencies.Litecoin:
+			url = "https://litecoinspace.org/api/"
+		default:
+			return nil
+		}
+	}
+
+	return &esploraBalanceProvider{
+		apiUrl: url,
+	}
+}
+
+func (provider *esploraBalanceProvider) GetName() string {
+	return esploraProvider
+}
+
+func (provider *esploraBalanceProvider) getBalanceAndTxCount(address currencies.AddressData) (*big.Int, int64, error) {
+	body, err := fetchResponseBody(provider.apiUrl + "address/" + address.Address)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	balance, txCount := parseEsploraBalance(body)
+	if balance == nil {
+		return nil, -1, fmt.Errorf("can't parse the data of %s", address.Address)
+	}
+
+	return balance, txCount, nil
+}
+
+// unconfirmed transactions are counted as well
+func parseEsploraBalance(body []byte) (balance *big.Int, txCount int64) {
+	var parsedResp = new(EsploraAddressResp)
+	err := json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		log.Print(err)
+		return nil, -1
+	}
+
+	balance = big.NewInt(parsedResp.ChainStats.FundedTxoSum - parsedResp.ChainStats.SpentTxoSum +
+		parsedResp.MempoolStats.FundedTxoSum - parsedResp.MempoolStats.SpentTxoSum)
+	txCount = parsedResp.ChainStats.TxCount + parsedResp.MempoolStats.TxCount
+
+	return
+}
+
+func (provider *esploraBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
+	balances, _, err := provider.GetBalanceAndTxCountBunch(addresses)
+	return balances, err
+}
+
+func (provider *esploraBalanceProvider) GetBalanceAndTxCountBunch(addresses []currencies.AddressData) ([]*big.Int, []int64, error) {
+	return getBalancesAndTxCountsConcurrently(esploraProvider, addresses, provider.getBalanceAndTxCount)
+}
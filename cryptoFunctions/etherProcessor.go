@@ -4,20 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
-	"io/ioutil"
-	"net/http"
 	"log"
 	"math/big"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
-const etherscanApiKey string = "your-etherscan-code-here" // should have used config for this
-
-var ethereumAddressRegex *regexp.Regexp
+const etherscanApiUrl string = "http://api.etherscan.io/api"
 
 type EtherProcessor struct {
+	processorApiUrl
 }
 
 type EtherRespData struct {
@@ -33,140 +30,208 @@ type EtherMultiResp struct {
 	Result []EtherRespData `json:"result"`
 }
 
+// fields that etherscan returns for both transactions and token transfers
+type EtherscanTransactionDetails struct {
+	Hash string `json:"hash"`
+	BlockNumber string `json:"blockNumber"`
+	Confirmations string `json:"confirmations"`
+	GasUsed string `json:"gasUsed"`
+	GasPrice string `json:"gasPrice"`
+}
+
 type EtherHistoryRespItem struct {
+	EtherscanTransactionDetails
 	From string `json:"from"`
 	To string `json:"to"`
 	Value string `json:"value"`
 	ContractAddress string `json:"contractAddress"`
 	TimeStamp string `json:"timeStamp"`
+	IsError string `json:"isError"`
 }
 
 type EtherHistoryResp struct {
 	Result []EtherHistoryRespItem `json:"result"`
 }
 
-func init() {
-	ethereumAddressRegex = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
-	if ethereumAddressRegex == nil {
-		log.Fatal("Wrong regexp")
-	}
+// works for both Ether and ERC20 tokens
+type etherscanBalanceProvider struct {
+	currency currencies.Currency
+	apiUrl string
+	apiKey string
 }
 
-func (processor *EtherProcessor) GetBalance(address currencies.AddressData) *big.Int {
-	resp, err := http.Get("http://api.etherscan.io/api?module=account&action=balance&address=" + address.Address + "&tag=latest&apikey=" + etherscanApiKey)
-	if err != nil {
-		log.Print(err)
+func makeEtherscanBalanceProvider(currency currencies.Currency, url string, apiKey string) BalanceProvider {
+	if currency != currencies.Ether && currency != currencies.Erc20Token {
 		return nil
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	if url == "" {
+		url = etherscanApiUrl
+	}
+
+	return &etherscanBalanceProvider{
+		currency: currency,
+		apiUrl: url,
+		apiKey: apiKey,
+	}
+}
+
+// the key of the etherscan provider configured for the currency or for Ether, empty if there is none
+// the history is requested from etherscan too, so it uses the same key
+func getEtherscanApiKey(currency currencies.Currency) string {
+	for _, providersCurrency := range []currencies.Currency{currency, currencies.Ether} {
+		providers, _ := getBalanceProviders(providersCurrency)
+		for _, provider := range providers {
+			if etherscan, ok := provider.(*etherscanBalanceProvider); ok && etherscan.apiKey != "" {
+				return etherscan.apiKey
+			}
+		}
+	}
+	return ""
+}
+
+// etherscan serves requests without a key with a lower rate limit
+func getEtherscanApiKeyParam(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	return "&apikey=" + apiKey
+}
+
+func (provider *etherscanBalanceProvider) GetName() string {
+	return etherscanProvider
+}
+
+func (provider *etherscanBalanceProvider) getBalance(address currencies.AddressData) (*big.Int, error) {
+	var requestText string
+	if provider.currency == currencies.Erc20Token {
+		requestText = provider.apiUrl + "?module=account&action=tokenbalance&contractaddress=" + address.ContractAddress + "&address=" + address.Address + "&tag=latest" + getEtherscanApiKeyParam(provider.apiKey)
+	} else {
+		requestText = provider.apiUrl + "?module=account&action=balance&address=" + address.Address + "&tag=latest" + getEtherscanApiKeyParam(provider.apiKey)
+	}
+
+	body, err := fetchResponseBody(requestText)
 	if err != nil {
-		log.Print(err)
-		return nil
+		return nil, err
 	}
 
 	var parsedResp = new(EtherResp)
 	err = json.Unmarshal(body, &parsedResp)
 	if(err != nil){
 		log.Print(string(body[:]))
-		log.Print(err)
-		return nil
+		return nil, err
 	}
 
-	intValue := new(big.Int)
-	_, ok := intValue.SetString(parsedResp.Result, 10)
+	intValue, ok := new(big.Int).SetString(parsedResp.Result, 10)
+	if !ok {
+		return nil, fmt.Errorf("can't parse balance: %s", string(body[:]))
+	}
 
-	if ok {
-		return intValue
-	} else {
-		log.Print(string(body[:]))
-		log.Print("Int parse problem")
-		return nil
+	return intValue, nil
+}
+
+func (provider *etherscanBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) (balances []*big.Int, err error) {
+	// token balances can be requested only one by one
+	if provider.currency == currencies.Erc20Token {
+		return getBalancesConcurrently(etherscanProvider, addresses, provider.getBalance)
 	}
+
+	// all the balances are requested at once
+	runProviderRequest(etherscanProvider, func() {
+		balances, err = provider.getBalanceBunch(addresses)
+	})
+	return
 }
 
-func (processor *EtherProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
+func (provider *etherscanBalanceProvider) getBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
 	if len(addresses) == 1 {
-		return []*big.Int {
-			processor.GetBalance(addresses[0]),
-		}
+		balance, err := provider.getBalance(addresses[0])
+		return []*big.Int{balance}, err
 	}
 
 	balances := make([]*big.Int, len(addresses))
 
-	resp, err := http.Get("http://api.etherscan.io/api?module=account&action=balancemulti&address=" + joinAddresses(addresses) + "&tag=latest&apikey=" + etherscanApiKey)
-	if err != nil {
-		log.Print(err)
-		return balances
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := fetchResponseBody(provider.apiUrl + "?module=account&action=balancemulti&address=" + joinAddresses(addresses) + "&tag=latest" + getEtherscanApiKeyParam(provider.apiKey))
 	if err != nil {
-		log.Print(err)
-		return balances
+		return balances, err
 	}
 
 	var parsedResp = new(EtherMultiResp)
 	err = json.Unmarshal(body, &parsedResp)
 	if(err != nil){
 		log.Print(string(body[:]))
-		log.Print(err)
-		return balances
+		return balances, err
 	}
 
 	// I'm not sure if it's more time efficient
+	// the API can return addresses in a different case, so compare them case-insensitively
 	addressesIndexes := map[string]int{}
 	for i, address := range addresses {
-		addressesIndexes[address.Address] = i
+		addressesIndexes[strings.ToLower(address.Address)] = i
 	}
 
 	for _, data := range parsedResp.Result {
 		if intValue, ok := new(big.Int).SetString(data.Balance, 10); ok {
-			if i, ok := addressesIndexes[data.Account]; ok {
+			if i, ok := addressesIndexes[strings.ToLower(data.Account)]; ok {
 				balances[i] = intValue
 			}
 		}
 	}
 
-	return balances
+	return balances, nil
+}
+
+func (processor *EtherProcessor) GetBalance(address currencies.AddressData) *big.Int {
+	return getCurrencyBalance(currencies.Ether, address)
 }
 
-func (processor *EtherProcessor) GetTransactionsHistory(address currencies.AddressData, limit int) (history []currencies.TransactionsHistoryItem) {
+func (processor *EtherProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
+	return getCurrencyBalanceBunch(currencies.Ether, addresses)
+}
+
+func (processor *EtherProcessor) GetBalanceResults(addresses []currencies.AddressData) []BalanceResult {
+	return getCurrencyBalanceResults(currencies.Ether, addresses)
+}
+
+func (processor *EtherProcessor) GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error) {
+	page, pageSize, skip := getPageParams(offset, limit)
+
 	var requestText string
-	if limit > 0 {
+	if pageSize > 0 {
 		requestText = fmt.Sprintf(
-			"http://api.etherscan.io/api?module=account&action=txlist&address=%s&startblock=0&endblock=99999999&page=1&offset=%d&sort=desc&apikey=%s",
+			"%s?module=account&action=txlist&address=%s&startblock=0&endblock=99999999&page=%d&offset=%d&sort=desc%s",
+			processor.getApiUrl(etherscanApiUrl),
 			address.Address,
-			limit,
-			etherscanApiKey,
+			page,
+			pageSize,
+			getEtherscanApiKeyParam(getEtherscanApiKey(currencies.Ether)),
 		)
 	} else {
 		requestText = fmt.Sprintf(
-			"http://api.etherscan.io/api?module=account&action=txlist&address=%s&startblock=0&endblock=99999999&sort=desc&apikey=%s",
+			"%s?module=account&action=txlist&address=%s&startblock=0&endblock=99999999&sort=desc%s",
+			processor.getApiUrl(etherscanApiUrl),
 			address.Address,
-			etherscanApiKey,
+			getEtherscanApiKeyParam(getEtherscanApiKey(currencies.Ether)),
 		)
 	}
 
-	resp, err := http.Get(requestText)
+	body, err := fetchResponseBody(requestText)
 	if err != nil {
-		log.Print(err)
-		return
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Print(err)
-		return
+	history = parseEtherHistory(body)
+	if history == nil {
+		return nil, fmt.Errorf("can't parse the history of %s", address.Address)
 	}
 
+	return skipHistoryItems(history, skip), nil
+}
+
+func parseEtherHistory(body []byte) (history []currencies.TransactionsHistoryItem) {
 	var parsedResp = new(EtherHistoryResp)
-	err = json.Unmarshal(body, &parsedResp)
+	err := json.Unmarshal(body, &parsedResp)
 	if(err != nil){
-		log.Print("Request: " + requestText)
 		log.Print("Responce: " + string(body[:]))
 		log.Print(err)
 		return
@@ -201,11 +266,45 @@ func (processor *EtherProcessor) GetTransactionsHistory(address currencies.Addre
 				Amount: amount,
 				Time: time.Unix(intTime, int64(0)),
 			})
+
+		historyItem.fillHistoryItem(&history[len(history) - 1])
+
+		if historyItem.IsError == "1" {
+			history[len(history) - 1].Status = currencies.TransactionFailed
+		}
 	}
 
 	return
 }
 
+func (details *EtherscanTransactionDetails) fillHistoryItem(item *currencies.TransactionsHistoryItem) {
+	item.Hash = details.Hash
+
+	// the fee is paid even for failed transactions
+	gasUsed, okGasUsed := new(big.Int).SetString(details.GasUsed, 10)
+	gasPrice, okGasPrice := new(big.Int).SetString(details.GasPrice, 10)
+	if okGasUsed && okGasPrice {
+		item.Fee = gasUsed.Mul(gasUsed, gasPrice)
+	}
+
+	// errors are not important here, the values just stay unknown
+	item.BlockHeight, _ = strconv.ParseInt(details.BlockNumber, 10, 64)
+	item.Confirmations, _ = strconv.ParseInt(details.Confirmations, 10, 64)
+
+	if item.Confirmations == 0 {
+		item.Status = currencies.TransactionPending
+	}
+}
+
 func (processor *EtherProcessor) IsAddressValid(address string) bool {
-	return ethereumAddressRegex.MatchString(address)
+	return isEthereumAddressValid(address)
+}
+
+func isEthereumAddressValid(address string) bool {
+	return currencies.EthereumAddressRegex.MatchString(address) && isEthereumAddressChecksumValid(address)
+}
+
+// returns true if the address is well-formed but its EIP-55 mixed-case checksum doesn't match
+func IsEthereumAddressChecksumWrong(address string) bool {
+	return currencies.EthereumAddressRegex.MatchString(address) && !isEthereumAddressChecksumValid(address)
 }
@@ -0,0 +1,220 @@
+package cryptoFunctions
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"golang.org/x/crypto/ripemd160"
+	"log"
+	"math/big"
+)
+
+type hdScriptType int8
+
+const (
+	hdScriptP2pkh hdScriptType = 0 // BIP-44
+	hdScriptP2shP2wpkh hdScriptType = 1 // BIP-49
+	hdScriptP2wpkh hdScriptType = 2 // BIP-84
+)
+
+type hdAddressParams struct {
+	p2pkhVersion byte
+	p2shVersion byte
+	bech32Hrp string // empty if the currency doesn't support SegWit
+	// version bytes of extended public keys that are accepted for the currency
+	keyVersions map[uint32]hdScriptType
+}
+
+type extendedPublicKey struct {
+	scriptType hdScriptType
+	chainCode []byte
+	publicKey ecPoint
+}
+
+const (
+	xpubVersion uint32 = 0x0488b21e
+	ypubVersion uint32 = 0x049d7cb2
+	zpubVersion uint32 = 0x04b24746
+	ltubVersion uint32 = 0x019da462
+	mtubVersion uint32 = 0x01b26ef6
+)
+
+var hdAddressParamsList map[currencies.Currency]hdAddressParams = map[currencies.Currency]hdAddressParams{
+	currencies.Bitcoin : {
+		p2pkhVersion: bitcoinP2pkhVersion,
+		p2shVersion: bitcoinP2shVersion,
+		bech32Hrp: "bc",
+		keyVersions: map[uint32]hdScriptType{
+			xpubVersion: hdScriptP2pkh,
+			ypubVersion: hdScriptP2shP2wpkh,
+			zpubVersion: hdScriptP2wpkh,
+		},
+	},
+	currencies.BitcoinCash : {
+		p2pkhVersion: bitcoinP2pkhVersion,
+		p2shVersion: bitcoinP2shVersion,
+		keyVersions: map[uint32]hdScriptType{
+			xpubVersion: hdScriptP2pkh,
+		},
+	},
+	currencies.BitcoinGold : {
+		p2pkhVersion: bitcoinGoldP2pkhVersion,
+		p2shVersion: bitcoinGoldP2shVersion,
+		bech32Hrp: "btg",
+		keyVersions: map[uint32]hdScriptType{
+			xpubVersion: hdScriptP2pkh,
+			ypubVersion: hdScriptP2shP2wpkh,
+			zpubVersion: hdScriptP2wpkh,
+		},
+	},
+	currencies.Litecoin : {
+		p2pkhVersion: litecoinP2pkhVersion,
+		p2shVersion: litecoinP2shVersion,
+		bech32Hrp: "ltc",
+		keyVersions: map[uint32]hdScriptType{
+			xpubVersion: hdScriptP2pkh,
+			ltubVersion: hdScriptP2pkh,
+			ypubVersion: hdScriptP2shP2wpkh,
+			mtubVersion: hdScriptP2shP2wpkh,
+			zpubVersion: hdScriptP2wpkh,
+		},
+	},
+}
+
+func hash160(data []byte) []byte {
+	sha := sha256.Sum256(data)
+	hasher := ripemd160.New()
+	hasher.Write(sha[:])
+	return hasher.Sum(nil)
+}
+
+func parseExtendedPublicKey(params *hdAddressParams, key string) *extendedPublicKey {
+	decoded, ok := base58Decode(key)
+	// version(4) depth(1) fingerprint(4) child number(4) chain code(32) key(33) checksum(4)
+	if !ok || len(decoded) != 82 {
+		return nil
+	}
+
+	if !bytes.Equal(doubleSha256(decoded[:78])[:4], decoded[78:]) {
+		return nil
+	}
+
+	scriptType, ok := params.keyVersions[binary.BigEndian.Uint32(decoded[0:4])]
+	if !ok {
+		return nil
+	}
+
+	if scriptType != hdScriptP2pkh && params.bech32Hrp == "" {
+		return nil
+	}
+
+	publicKey, ok := decompressPoint(decoded[45:78])
+	if !ok {
+		return nil
+	}
+
+	return &extendedPublicKey{
+		scriptType: scriptType,
+		chainCode: decoded[13:45],
+		publicKey: publicKey,
+	}
+}
+
+// public parent key to public child key derivation (CKDpub from BIP-32)
+func (key *extendedPublicKey) deriveChild(index uint32) *extendedPublicKey {
+	if index >= 0x80000000 {
+		// hardened keys can't be derived from public keys
+		return nil
+	}
+
+	data := make([]byte, 37)
+	copy(data, compressPoint(key.publicKey))
+	binary.BigEndian.PutUint32(data[33:], index)
+
+	mac := hmac.New(sha512.New, key.chainCode)
+	mac.Write(data)
+	digest := mac.Sum(nil)
+
+	tweak := new(big.Int).SetBytes(digest[:32])
+	if tweak.Cmp(secp256k1N) >= 0 {
+		return nil
+	}
+
+	childKey := ecAdd(ecScalarBaseMult(tweak), key.publicKey)
+	if childKey.isInfinity() {
+		return nil
+	}
+
+	return &extendedPublicKey{
+		scriptType: key.scriptType,
+		chainCode: digest[32:],
+		publicKey: childKey,
+	}
+}
+
+func (key *extendedPublicKey) getAddress(params *hdAddressParams) string {
+	publicKeyHash := hash160(compressPoint(key.publicKey))
+
+	switch key.scriptType {
+	case hdScriptP2shP2wpkh:
+		redeemScript := append([]byte{0x00, 0x14}, publicKeyHash...)
+		return encodeBase58Check(params.p2shVersion, hash160(redeemScript))
+	case hdScriptP2wpkh:
+		return encodeSegwitAddress(params.bech32Hrp, 0, publicKeyHash)
+	default:
+		return encodeBase58Check(params.p2pkhVersion, publicKeyHash)
+	}
+}
+
+func IsHdWalletSupported(currency currencies.Currency) bool {
+	_, ok := hdAddressParamsList[currency]
+	return ok
+}
+
+// checks that the key is an extended public key (xpub, ypub, zpub, etc.) that can be used with the currency
+func IsExtendedPublicKeyValid(currency currencies.Currency, key string) bool {
+	params, ok := hdAddressParamsList[currency]
+	if !ok {
+		return false
+	}
+
+	return parseExtendedPublicKey(&params, key) != nil
+}
+
+// derives count addresses starting from fromIndex on the chain (0 for receiving addresses, 1 for change)
+// the key is expected to be an account-level key (e.g. m/84'/0'/0')
+func DeriveHdWalletAddresses(currency currencies.Currency, key string, chain uint32, fromIndex uint32, count int) (addresses []string) {
+	params, ok := hdAddressParamsList[currency]
+	if !ok {
+		log.Printf("HD wallets are not supported for currency %d", currency)
+		return nil
+	}
+
+	accountKey := parseExtendedPublicKey(&params, key)
+	if accountKey == nil {
+		log.Print("Wrong extended public key")
+		return nil
+	}
+
+	chainKey := accountKey.deriveChild(chain)
+	if chainKey == nil {
+		log.Printf("Can't derive chain %d", chain)
+		return nil
+	}
+
+	addresses = make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		childKey := chainKey.deriveChild(fromIndex + uint32(i))
+		if childKey == nil {
+			// the probability of this is lower than 1 in 2^127
+			log.Printf("Can't derive address %d/%d", chain, fromIndex + uint32(i))
+			return nil
+		}
+		addresses = append(addresses, childKey.getAddress(&params))
+	}
+
+	return
+}
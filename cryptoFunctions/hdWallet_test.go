@@ -0,0 +1,84 @@
+package cryptoFunctions
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPublicKeyDerivation(t *testing.T) {
+	assert := require.New(t)
+
+	params := hdAddressParamsList[currencies.Bitcoin]
+
+	// BIP-32 test vector 1, derivations that don't need private keys
+	testCases := []struct {
+		parentKey string
+		index uint32
+		childKey string
+	}{
+		{
+			"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+			1,
+			"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		},
+		{
+			"xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+			2,
+			"xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+		},
+		{
+			"xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+			1000000000,
+			"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		},
+	}
+
+	for _, testCase := range testCases {
+		parentKey := parseExtendedPublicKey(&params, testCase.parentKey)
+		expectedKey := parseExtendedPublicKey(&params, testCase.childKey)
+		assert.NotNil(parentKey)
+		assert.NotNil(expectedKey)
+
+		childKey := parentKey.deriveChild(testCase.index)
+		assert.NotNil(childKey)
+		assert.Equal(expectedKey.chainCode, childKey.chainCode)
+		assert.Equal(compressPoint(expectedKey.publicKey), compressPoint(childKey.publicKey))
+	}
+
+	// hardened derivation is impossible
+	assert.Nil(parseExtendedPublicKey(&params, testCases[0].parentKey).deriveChild(0x80000000))
+}
+
+func TestHdWalletAddresses(t *testing.T) {
+	assert := require.New(t)
+
+	// account keys of "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	bip44Key := "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj"
+	bip49Key := "ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP"
+	bip84Key := "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+
+	assert.Equal([]string{"1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"}, DeriveHdWalletAddresses(currencies.Bitcoin, bip44Key, 0, 0, 1))
+	assert.Equal([]string{"37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"}, DeriveHdWalletAddresses(currencies.Bitcoin, bip49Key, 0, 0, 1))
+	assert.Equal([]string{"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"}, DeriveHdWalletAddresses(currencies.Bitcoin, bip84Key, 0, 0, 2))
+	assert.Equal([]string{"bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el"}, DeriveHdWalletAddresses(currencies.Bitcoin, bip84Key, 1, 0, 1))
+	assert.Equal([]string{"bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"}, DeriveHdWalletAddresses(currencies.Bitcoin, bip84Key, 0, 1, 1))
+
+	// derived addresses should pass the validation of the currency
+	for _, address := range DeriveHdWalletAddresses(currencies.Litecoin, bip84Key, 0, 0, 3) {
+		assert.True((&LitecoinProcessor{}).IsAddressValid(address), address)
+	}
+	for _, address := range DeriveHdWalletAddresses(currencies.BitcoinGold, bip49Key, 0, 0, 3) {
+		assert.True((&BitcoinGoldProcessor{}).IsAddressValid(address), address)
+	}
+
+	assert.True(IsExtendedPublicKeyValid(currencies.Bitcoin, bip84Key))
+	assert.True(IsExtendedPublicKeyValid(currencies.BitcoinCash, bip44Key))
+	// no SegWit for Bitcoin Cash
+	assert.False(IsExtendedPublicKeyValid(currencies.BitcoinCash, bip84Key))
+	assert.False(IsExtendedPublicKeyValid(currencies.Ether, bip44Key))
+	// broken checksum
+	assert.False(IsExtendedPublicKeyValid(currencies.Bitcoin, bip84Key[:len(bip84Key) - 1] + "t"))
+	assert.False(IsExtendedPublicKeyValid(currencies.Bitcoin, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"))
+	assert.Nil(DeriveHdWalletAddresses(currencies.Bitcoin, "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA", 0, 0, 1))
+}
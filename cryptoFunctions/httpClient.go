@@ -0,0 +1,221 @@
+package cryptoFunctions
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// requests that take longer are canceled
+const requestTimeout time.Duration = 30 * time.Second
+
+// how many times a request is repeated after a 429 or 5xx response or a network error
+const maxRequestRetries int = 3
+
+// how many requests per second can be sent to one host if not configured
+const defaultHostRequestsPerSecond float64 = 5
+
+// how many requests to one host can be sent at once after a pause
+const hostRequestsBurst float64 = 5
+
+// a host is paused after this number of failed requests in a row
+const circuitBreakerFailuresCount int = 5
+
+// changed in tests
+var retryBaseDelay time.Duration = 500 * time.Millisecond
+var retryMaxDelay time.Duration = 10 * time.Second
+var circuitBreakerPause time.Duration = 2 * time.Minute
+
+var defaultHttpClient *http.Client = &http.Client{
+	Timeout: requestTimeout,
+}
+
+var httpClient *http.Client = defaultHttpClient
+
+// the client is used for all the requests to the providers, nil sets the default one
+// should be called before any requests are sent
+func SetHttpClient(client *http.Client) {
+	if client != nil {
+		httpClient = client
+	} else {
+		httpClient = defaultHttpClient
+	}
+}
+
+// rate limit and circuit breaker of one host
+type hostState struct {
+	mutex sync.Mutex
+	requestsPerSecond float64
+	// token bucket, can be negative when requests are waiting for their turn
+	tokens float64
+	lastRefillTime time.Time
+	failuresCount int
+	pausedUntil time.Time
+}
+
+var hostRequestsPerSecond map[string]float64 = map[string]float64{}
+
+var hostStates map[string]*hostState = map[string]*hostState{}
+
+var hostStatesMutex sync.Mutex
+
+// host -> how many requests per second can be sent to it
+// resets the state of all the hosts
+func SetHostRateLimits(limits map[string]float64) {
+	hostStatesMutex.Lock()
+	defer hostStatesMutex.Unlock()
+
+	hostRequestsPerSecond = make(map[string]float64)
+	for host, limit := range limits {
+		hostRequestsPerSecond[host] = limit
+	}
+
+	hostStates = make(map[string]*hostState)
+}
+
+func getHostState(host string) *hostState {
+	hostStatesMutex.Lock()
+	defer hostStatesMutex.Unlock()
+
+	state, ok := hostStates[host]
+	if !ok {
+		requestsPerSecond, isLimitSet := hostRequestsPerSecond[host]
+		if !isLimitSet || requestsPerSecond <= 0 {
+			requestsPerSecond = defaultHostRequestsPerSecond
+		}
+
+		state = &hostState{
+			requestsPerSecond: requestsPerSecond,
+			tokens: hostRequestsBurst,
+		}
+		hostStates[host] = state
+	}
+
+	return state
+}
+
+// takes a token and returns how long to wait before sending the request
+func (state *hostState) reserveRequest(now time.Time) time.Duration {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if !state.lastRefillTime.IsZero() {
+		state.tokens += now.Sub(state.lastRefillTime).Seconds() * state.requestsPerSecond
+		if state.tokens > hostRequestsBurst {
+			state.tokens = hostRequestsBurst
+		}
+	}
+	state.lastRefillTime = now
+
+	state.tokens--
+	if state.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-state.tokens / state.requestsPerSecond * float64(time.Second))
+}
+
+func (state *hostState) isPaused(now time.Time) bool {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	return now.Before(state.pausedUntil)
+}
+
+func (state *hostState) reportSuccess() {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.failuresCount = 0
+}
+
+// after the pause one more failure pauses the host again
+func (state *hostState) reportFailure(now time.Time) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.failuresCount++
+	if state.failuresCount >= circuitBreakerFailuresCount {
+		state.pausedUntil = now.Add(circuitBreakerPause)
+	}
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// exponential backoff with jitter, a Retry-After header of the response is respected
+func getRetryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay := time.Duration(seconds) * time.Second
+			if delay > retryMaxDelay {
+				delay = retryMaxDelay
+			}
+			return delay
+		}
+	}
+
+	delay := retryBaseDelay << uint(attempt)
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+
+	// from a half to the full delay to not to repeat requests at the same time
+	return delay / 2 + time.Duration(rand.Int63n(int64(delay / 2) + 1))
+}
+
+// sends a request respecting the limits of the host, repeats it if the host is temporarily unavailable
+// only requests without body can be sent
+func doProviderRequest(request *http.Request) (*http.Response, error) {
+	state := getHostState(request.URL.Host)
+
+	if state.isPaused(time.Now()) {
+		return nil, fmt.Errorf("requests to %s are paused after failures", request.URL.Host)
+	}
+
+	for attempt := 0; ; attempt++ {
+		time.Sleep(state.reserveRequest(time.Now()))
+
+		// the body of the previous attempt is already read
+		if attempt > 0 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request.Body = body
+		}
+
+		resp, err := httpClient.Do(request)
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			state.reportSuccess()
+			return resp, nil
+		}
+
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("%s responded with %s", request.URL.Host, resp.Status)
+		}
+
+		if attempt >= maxRequestRetries {
+			state.reportFailure(time.Now())
+			return nil, err
+		}
+
+		log.Printf("%s, retrying", err)
+		time.Sleep(getRetryDelay(attempt, resp))
+	}
+}
+
+func httpGet(requestText string) (*http.Response, error) {
+	request, err := http.NewRequest("GET", requestText, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return doProviderRequest(request)
+}
@@ -0,0 +1,217 @@
+package cryptoFunctions
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// makes retries fast for the tests
+func setTestRetryDelays() func() {
+	oldBaseDelay, oldMaxDelay, oldPause := retryBaseDelay, retryMaxDelay, circuitBreakerPause
+	retryBaseDelay = time.Millisecond
+	retryMaxDelay = 5 * time.Millisecond
+	circuitBreakerPause = time.Hour
+
+	return func() {
+		retryBaseDelay, retryMaxDelay, circuitBreakerPause = oldBaseDelay, oldMaxDelay, oldPause
+		SetHostRateLimits(nil)
+	}
+}
+
+// responds with the given statuses one by one, the last one is repeated
+func makeStatusesServer(statuses ...int) (server *httptest.Server, getCallsCount func() int) {
+	var mutex sync.Mutex
+	callsCount := 0
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		status := statuses[len(statuses) - 1]
+		if callsCount < len(statuses) {
+			status = statuses[callsCount]
+		}
+		callsCount++
+		mutex.Unlock()
+
+		w.WriteHeader(status)
+		w.Write([]byte("body"))
+	}))
+
+	getCallsCount = func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return callsCount
+	}
+	return
+}
+
+func TestRetryOnServerErrors(t *testing.T) {
+	assert := require.New(t)
+	defer setTestRetryDelays()()
+
+	server, getCallsCount := makeStatusesServer(http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	defer server.Close()
+
+	body, err := fetchResponseBody(server.URL)
+	assert.Nil(err)
+	assert.Equal([]byte("body"), body)
+	assert.Equal(3, getCallsCount())
+}
+
+func TestRetryResendsRequestBody(t *testing.T) {
+	assert := require.New(t)
+	defer setTestRetryDelays()()
+
+	var mutex sync.Mutex
+	receivedBodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		receivedBodies = append(receivedBodies, string(body))
+		callsCount := len(receivedBodies)
+		mutex.Unlock()
+
+		if callsCount == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	request, err := http.NewRequest("POST", server.URL, bytes.NewReader([]byte("request")))
+	assert.Nil(err)
+
+	_, err = fetchRequestResponseBody(request)
+	assert.Nil(err)
+	assert.Equal([]string{"request", "request"}, receivedBodies)
+}
+
+func TestNoRetryOnClientErrors(t *testing.T) {
+	assert := require.New(t)
+	defer setTestRetryDelays()()
+
+	server, getCallsCount := makeStatusesServer(http.StatusNotFound)
+	defer server.Close()
+
+	resp, err := httpGet(server.URL)
+	assert.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	assert.Equal(1, getCallsCount())
+
+	// the error response is not taken as data
+	body, err := fetchResponseBody(server.URL)
+	assert.NotNil(err)
+	assert.Nil(body)
+	assert.Equal(2, getCallsCount())
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	assert := require.New(t)
+	defer setTestRetryDelays()()
+
+	server, getCallsCount := makeStatusesServer(http.StatusBadGateway)
+	defer server.Close()
+
+	// to not to wait for the rate limit
+	SetHostRateLimits(map[string]float64{server.Listener.Addr().String(): 1000})
+
+	for i := 0; i < circuitBreakerFailuresCount; i++ {
+		_, err := fetchResponseBody(server.URL)
+		assert.NotNil(err)
+	}
+	assert.Equal(circuitBreakerFailuresCount * (maxRequestRetries + 1), getCallsCount())
+
+	// the host is paused and isn't requested anymore
+	_, err := httpGet(server.URL)
+	assert.NotNil(err)
+	assert.Equal(circuitBreakerFailuresCount * (maxRequestRetries + 1), getCallsCount())
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+	assert := require.New(t)
+	defer setTestRetryDelays()()
+
+	state := hostState{}
+	now := time.Date(2018, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < circuitBreakerFailuresCount - 1; i++ {
+		state.reportFailure(now)
+	}
+	state.reportSuccess()
+	state.reportFailure(now)
+	assert.False(state.isPaused(now))
+
+	for i := 0; i < circuitBreakerFailuresCount; i++ {
+		state.reportFailure(now)
+	}
+	assert.True(state.isPaused(now))
+	assert.False(state.isPaused(now.Add(circuitBreakerPause)))
+}
+
+func TestHostRateLimit(t *testing.T) {
+	assert := require.New(t)
+
+	state := hostState{
+		requestsPerSecond: 2,
+		tokens: hostRequestsBurst,
+	}
+	now := time.Date(2018, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+	// the burst is sent without waiting
+	for i := 0; i < int(hostRequestsBurst); i++ {
+		assert.Equal(time.Duration(0), state.reserveRequest(now))
+	}
+	assert.Equal(500 * time.Millisecond, state.reserveRequest(now))
+	assert.Equal(time.Second, state.reserveRequest(now))
+
+	// two tokens are restored in a second
+	assert.Equal(500 * time.Millisecond, state.reserveRequest(now.Add(time.Second)))
+
+	// the bucket doesn't grow bigger than the burst
+	later := now.Add(time.Hour)
+	for i := 0; i < int(hostRequestsBurst); i++ {
+		assert.Equal(time.Duration(0), state.reserveRequest(later))
+	}
+	assert.Equal(500 * time.Millisecond, state.reserveRequest(later))
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert := require.New(t)
+
+	for attempt := 0; attempt < 10; attempt++ {
+		delay := getRetryDelay(attempt, nil)
+		expectedDelay := retryBaseDelay << uint(attempt)
+		if expectedDelay > retryMaxDelay {
+			expectedDelay = retryMaxDelay
+		}
+		assert.True(delay >= expectedDelay / 2)
+		assert.True(delay <= expectedDelay)
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "3")
+	assert.Equal(3 * time.Second, getRetryDelay(0, resp))
+	resp.Header.Set("Retry-After", "3600")
+	assert.Equal(retryMaxDelay, getRetryDelay(0, resp))
+}
+
+func TestHttpGetBody(t *testing.T) {
+	assert := require.New(t)
+	defer setTestRetryDelays()()
+
+	server, _ := makeStatusesServer(http.StatusOK)
+	defer server.Close()
+
+	resp, err := httpGet(server.URL)
+	assert.Nil(err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(err)
+	assert.Equal("body", string(body))
+}
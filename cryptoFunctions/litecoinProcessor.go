@@ -0,0 +1,53 @@
+package cryptoFunctions
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"fmt"
+	"math/big"
+)
+
+const litecoinApiUrl string = "https://api.blockchair.com/litecoin/dashboards/"
+
+const (
+	litecoinP2pkhVersion byte = 48 // L...
+	litecoinP2shVersion byte = 50 // M...
+	litecoinOldP2shVersion byte = 5 // 3..., deprecated but still in use
+)
+
+type LitecoinProcessor struct {
+	processorApiUrl
+}
+
+func (processor *LitecoinProcessor) GetBalance(address currencies.AddressData) *big.Int {
+	return getCurrencyBalance(currencies.Litecoin, address)
+}
+
+func (processor *LitecoinProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
+	return getCurrencyBalanceBunch(currencies.Litecoin, addresses)
+}
+
+func (processor *LitecoinProcessor) GetBalanceResults(addresses []currencies.AddressData) []BalanceResult {
+	return getCurrencyBalanceResults(currencies.Litecoin, addresses)
+}
+
+func (processor *LitecoinProcessor) GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error) {
+	apiUrl := processor.getApiUrl(litecoinApiUrl)
+
+	var requestText string
+	if limit > 0 {
+		requestText = fmt.Sprintf("%saddress/%s?transaction_details=true&limit=%d&offset=%d", apiUrl, address.Address, limit, offset)
+	} else {
+		requestText = fmt.Sprintf("%saddress/%s?transaction_details=true&offset=%d", apiUrl, address.Address, offset)
+	}
+
+	addressData, err := getBlockchairAddressData(requestText, address.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return makeBlockchairHistory(address.Address, addressData), nil
+}
+
+func (processor *LitecoinProcessor) IsAddressValid(address string) bool {
+	return isBase58AddressValid(address, litecoinP2pkhVersion, litecoinP2shVersion, litecoinOldP2shVersion) || isSegwitAddressValid("ltc", address)
+}
@@ -13,6 +13,21 @@ var processorsList map[currencies.Currency]CurrencyProcessor = map[currencies.Cu
 	currencies.Ether : &EtherProcessor{},
 	currencies.RippleXrp : &RippleXrpProcessor{},
 	currencies.Erc20Token : &erc20Processor,
+	currencies.Litecoin : &LitecoinProcessor{},
+}
+
+type apiUrlSetter interface {
+	SetApiUrl(url string)
+}
+
+// changes the base URL of the API that is used for the transactions history
+// should be called before the processors are used
+func SetProcessorApiUrl(currency currencies.Currency, url string) bool {
+	setter, ok := processorsList[currency].(apiUrlSetter)
+	if ok {
+		setter.SetApiUrl(url)
+	}
+	return ok
 }
 
 func GetProcessor(currency currencies.Currency) *CurrencyProcessor {
@@ -0,0 +1,541 @@
+package cryptoFunctions
+
+import (
+	"fmt"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// a recorded response of a provider
+type fixtureRoute struct {
+	// 200 if not set
+	status int
+	// name of a file in testdata, used instead of the body if set
+	file string
+	body string
+	// headers that the request should have, otherwise 401 is returned
+	requiredHeaders map[string]string
+}
+
+// replays the fixtures, a request gets the route with the longest matching prefix of its URI
+// all the requests of the package go to the server until the returned cleanup function is called
+func makeFixturesServer(t *testing.T, routes map[string]fixtureRoute) (server *httptest.Server, cleanup func()) {
+	bodies := map[string][]byte{}
+	for prefix, route := range routes {
+		if route.file != "" {
+			data, err := ioutil.ReadFile("testdata/" + route.file)
+			require.NoError(t, err)
+			bodies[prefix] = data
+		} else {
+			bodies[prefix] = []byte(route.body)
+		}
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestUri := r.URL.RequestURI()
+
+		matchedPrefix := ""
+		isMatched := false
+		for prefix := range routes {
+			if strings.HasPrefix(requestUri, prefix) && len(prefix) >= len(matchedPrefix) {
+				matchedPrefix = prefix
+				isMatched = true
+			}
+		}
+
+		if !isMatched {
+			http.NotFound(w, r)
+			return
+		}
+
+		route := routes[matchedPrefix]
+		for header, value := range route.requiredHeaders {
+			if r.Header.Get(header) != value {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if route.status != 0 {
+			w.WriteHeader(route.status)
+		}
+		w.Write(bodies[matchedPrefix])
+	}))
+
+	SetHttpClient(server.Client())
+	restoreDelays := setTestRetryDelays()
+	SetHostRateLimits(map[string]float64{server.Listener.Addr().String(): 1000})
+
+	cleanup = func() {
+		server.Close()
+		SetHttpClient(nil)
+		restoreDelays()
+	}
+	return
+}
+
+func useTestBalanceProvider(currency currencies.Currency, name string, url string) func() {
+	SetBalanceProviders(currency, []BalanceProvider{MakeBalanceProvider(currency, name, url, "")})
+	return func() {
+		SetBalanceProviders(currency, nil)
+	}
+}
+
+func makeTokenAddress(address string) currencies.AddressData {
+	return currencies.AddressData{
+		Currency: currencies.Erc20Token,
+		Address: address,
+		ContractAddress: "0xd26114cd6ee289accf82350c8d8487fedb8a0c07",
+	}
+}
+
+func TestBitcoinBalanceProviders(t *testing.T) {
+	assert := require.New(t)
+
+	server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		"/btccom/address/1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu": {file: "btcComAddress.json"},
+		"/btccom/address/3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy,": {file: "btcComAddresses.json"},
+		"/esplora/address/1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu": {file: "esploraAddress.json"},
+	})
+	defer cleanup()
+
+	processor := GetProcessor(currencies.Bitcoin)
+
+	restoreProviders := useTestBalanceProvider(currencies.Bitcoin, btcComProvider, server.URL + "/btccom/")
+	assert.Equal(int64(150000), (*processor).GetBalance(makeTestAddresses("1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu")[0]).Int64())
+
+	balances := (*processor).GetBalanceBunch(makeTestAddresses("3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"))
+	assert.Equal(3, len(balances))
+	assert.Equal(int64(2500), balances[0].Int64())
+	assert.Equal(int64(150000), balances[1].Int64())
+	// null in the response
+	assert.Nil(balances[2])
+
+	results := (*processor).GetBalanceResults(makeTestAddresses("3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu"))
+	assert.True(results[0].IsUsed)
+	assert.True(results[1].IsUsed)
+	restoreProviders()
+
+	defer useTestBalanceProvider(currencies.Bitcoin, esploraProvider, server.URL + "/esplora/")()
+	assert.Equal(int64(135000), (*processor).GetBalance(makeTestAddresses("1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu")[0]).Int64())
+}
+
+func TestBlockchairBalanceProvider(t *testing.T) {
+	assert := require.New(t)
+
+	server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		"/address/qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a?limit=0": {file: "bitcoinCashHistory.json"},
+		"/addresses/LM2WMpR1Rp6j3Sa59cMXMs1SPzj9eXpGc1,": {file: "blockchairAddresses.json"},
+	})
+	defer cleanup()
+
+	defer useTestBalanceProvider(currencies.BitcoinCash, blockchairProvider, server.URL + "/")()
+	assert.Equal(int64(1500000), (*GetProcessor(currencies.BitcoinCash)).GetBalance(currencies.AddressData{Address: "qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a"}).Int64())
+
+	defer useTestBalanceProvider(currencies.Litecoin, blockchairProvider, server.URL + "/")()
+	balances := (*GetProcessor(currencies.Litecoin)).GetBalanceBunch([]currencies.AddressData{
+		currencies.AddressData{Address: "LM2WMpR1Rp6j3Sa59cMXMs1SPzj9eXpGc1"},
+		currencies.AddressData{Address: "ltc1qg82tzp5xq0yq5fhcvsfsnf4ppr2ta2ld6k5jpl"},
+		currencies.AddressData{Address: "LZ3RvzEbLJE1hUuE4dM6XcAvMB7vrMHjSr"},
+	})
+	assert.Equal(3, len(balances))
+	assert.Equal(int64(1250000), balances[0].Int64())
+	assert.Equal(int64(0), balances[1].Int64())
+	// missing in the response
+	assert.Nil(balances[2])
+
+	// the address without transactions is not used
+	results := (*GetProcessor(currencies.Litecoin)).GetBalanceResults([]currencies.AddressData{
+		currencies.AddressData{Address: "LM2WMpR1Rp6j3Sa59cMXMs1SPzj9eXpGc1"},
+		currencies.AddressData{Address: "ltc1qg82tzp5xq0yq5fhcvsfsnf4ppr2ta2ld6k5jpl"},
+	})
+	assert.True(results[0].IsUsed)
+	assert.False(results[1].IsUsed)
+}
+
+func TestBitcoinGoldBalanceProviders(t *testing.T) {
+	assert := require.New(t)
+
+	server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		"/btgexp/getbalance/GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk": {body: "12.5"},
+		"/insight/addr/GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk/balance": {body: "1250000000\n"},
+	})
+	defer cleanup()
+
+	processor := GetProcessor(currencies.BitcoinGold)
+	address := currencies.AddressData{Address: "GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk"}
+
+	restoreProviders := useTestBalanceProvider(currencies.BitcoinGold, btgexpProvider, server.URL + "/btgexp/")
+	assert.Equal(int64(1250000000), (*processor).GetBalance(address).Int64())
+	restoreProviders()
+
+	defer useTestBalanceProvider(currencies.BitcoinGold, insightProvider, server.URL + "/insight/")()
+	assert.Equal(int64(1250000000), (*processor).GetBalance(address).Int64())
+}
+
+func TestEtherscanBalanceProvider(t *testing.T) {
+	assert := require.New(t)
+
+	server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		"/api?module=account&action=balance&": {file: "etherscanBalance.json"},
+		"/api?module=account&action=balancemulti&": {file: "etherscanBalanceMulti.json"},
+		"/api?module=account&action=tokenbalance&": {file: "etherscanTokenBalance.json"},
+	})
+	defer cleanup()
+
+	defer useTestBalanceProvider(currencies.Ether, etherscanProvider, server.URL + "/api")()
+	processor := GetProcessor(currencies.Ether)
+
+	assert.Equal("1500000000000000000", (*processor).GetBalance(currencies.AddressData{Address: "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"}).String())
+
+	// the addresses are returned in lowercase
+	balances := (*processor).GetBalanceBunch([]currencies.AddressData{
+		currencies.AddressData{Address: "0x63a9975ba31b0b9626b34300f7f627147df1f526"},
+		currencies.AddressData{Address: "0xdDBd2B932c763bA5b1b7AE3B362eac3e8d40121A"},
+	})
+	assert.Equal(2, len(balances))
+	assert.Equal("332567136222827062478", balances[0].String())
+	assert.Equal("40807178566070000000000", balances[1].String())
+
+	defer useTestBalanceProvider(currencies.Erc20Token, etherscanProvider, server.URL + "/api")()
+	assert.Equal(int64(135499), (*GetProcessor(currencies.Erc20Token)).GetBalance(makeTokenAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")).Int64())
+}
+
+func TestTokenBalanceProvider(t *testing.T) {
+	assert := require.New(t)
+
+	server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		"/token/0xd26114cd6ee289accf82350c8d8487fedb8a0c07/": {file: "tokenBalance.json"},
+	})
+	defer cleanup()
+
+	defer useTestBalanceProvider(currencies.Erc20Token, tokenBalanceProvider, server.URL + "/")()
+	assert.Equal("1250000000000000000", (*GetProcessor(currencies.Erc20Token)).GetBalance(makeTokenAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")).String())
+
+	processor := &Erc20Processor{tokenDataApiUrl: server.URL + "/"}
+	assert.Equal(&currencies.Erc20TokenData{
+		Name: "OmiseGO",
+		Symbol: "OMG",
+		Decimals: 18,
+	}, processor.GetTokenData("0xd26114cd6ee289accf82350c8d8487fedb8a0c07"))
+	assert.Nil(processor.GetTokenData("0x0000000000000000000000000000000000000000"))
+}
+
+func TestRippleXrpBalanceProvider(t *testing.T) {
+	assert := require.New(t)
+
+	server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		"/accounts/rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn/balances": {file: "rippleXrpBalance.json"},
+	})
+	defer cleanup()
+
+	defer useTestBalanceProvider(currencies.RippleXrp, rippleDataProvider, server.URL + "/")()
+	assert.Equal(int64(123500000), (*GetProcessor(currencies.RippleXrp)).GetBalance(currencies.AddressData{Address: "rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn"}).Int64())
+}
+
+func TestBalanceProvidersFailoverOverHttp(t *testing.T) {
+	assert := require.New(t)
+
+	server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		"/btccom/": {status: http.StatusInternalServerError},
+		"/esplora/address/1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu": {file: "esploraAddress.json"},
+	})
+	defer cleanup()
+
+	SetBalanceProviders(currencies.Bitcoin, []BalanceProvider{
+		MakeBalanceProvider(currencies.Bitcoin, btcComProvider, server.URL + "/btccom/", ""),
+		MakeBalanceProvider(currencies.Bitcoin, esploraProvider, server.URL + "/esplora/", ""),
+	})
+	defer SetBalanceProviders(currencies.Bitcoin, nil)
+
+	assert.Equal(int64(135000), (*GetProcessor(currencies.Bitcoin)).GetBalance(makeTestAddresses("1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu")[0]).Int64())
+}
+
+type processorHistoryTestCase struct {
+	name string
+	makeProcessor func(url string) CurrencyProcessor
+	address currencies.AddressData
+	route string
+	file string
+	// what the processor should return for the file
+	parse func(body []byte, address currencies.AddressData) []currencies.TransactionsHistoryItem
+}
+
+func getProcessorHistoryTestCases() []processorHistoryTestCase {
+	parseBlockchairHistory := func(body []byte, address currencies.AddressData) []currencies.TransactionsHistoryItem {
+		return makeBlockchairHistory(address.Address, parseBlockchairAddressData(body, address.Address))
+	}
+
+	return []processorHistoryTestCase{
+		{
+			name: "BTC",
+			makeProcessor: func(url string) CurrencyProcessor { return &BitcoinProcessor{processorApiUrl{apiUrl: url + "/"}} },
+			address: currencies.AddressData{Address: "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu"},
+			route: "/address/1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu/tx",
+			file: "bitcoinHistory.json",
+			parse: func(body []byte, address currencies.AddressData) []currencies.TransactionsHistoryItem {
+				return parseBitcoinHistory(body, address.Address)
+			},
+		},
+		{
+			name: "BCH",
+			makeProcessor: func(url string) CurrencyProcessor { return &BitcoinCashProcessor{processorApiUrl{apiUrl: url + "/"}} },
+			address: currencies.AddressData{Address: "qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a"},
+			route: "/address/qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a?transaction_details=true",
+			file: "bitcoinCashHistory.json",
+			parse: parseBlockchairHistory,
+		},
+		{
+			name: "LTC",
+			makeProcessor: func(url string) CurrencyProcessor { return &LitecoinProcessor{processorApiUrl{apiUrl: url + "/"}} },
+			address: currencies.AddressData{Address: "LM2WMpR1Rp6j3Sa59cMXMs1SPzj9eXpGc1"},
+			route: "/address/LM2WMpR1Rp6j3Sa59cMXMs1SPzj9eXpGc1?transaction_details=true",
+			file: "bitcoinCashHistory.json",
+			parse: parseBlockchairHistory,
+		},
+		{
+			name: "BTG",
+			makeProcessor: func(url string) CurrencyProcessor { return &BitcoinGoldProcessor{processorApiUrl{apiUrl: url + "/"}} },
+			address: currencies.AddressData{Address: "GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk"},
+			route: "/addrs/GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk/txs",
+			file: "bitcoinGoldHistory.json",
+			parse: func(body []byte, address currencies.AddressData) []currencies.TransactionsHistoryItem {
+				return parseBitcoinGoldHistory(body, address.Address)
+			},
+		},
+		{
+			name: "ETH",
+			makeProcessor: func(url string) CurrencyProcessor { return &EtherProcessor{processorApiUrl{apiUrl: url + "/api"}} },
+			address: currencies.AddressData{Address: "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"},
+			route: "/api?module=account&action=txlist&",
+			file: "etherHistory.json",
+			parse: func(body []byte, address currencies.AddressData) []currencies.TransactionsHistoryItem {
+				return parseEtherHistory(body)
+			},
+		},
+		{
+			name: "ERC20",
+			makeProcessor: func(url string) CurrencyProcessor { return &Erc20Processor{processorApiUrl: processorApiUrl{apiUrl: url + "/api"}} },
+			address: currencies.AddressData{Address: "0x63a9975ba31b0b9626b34300f7f627147df1f526", ContractAddress: "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"},
+			route: "/api?module=account&action=tokentx&",
+			file: "erc20History.json",
+			parse: func(body []byte, address currencies.AddressData) []currencies.TransactionsHistoryItem {
+				return parseErc20History(body, address.ContractAddress)
+			},
+		},
+		{
+			name: "XRP",
+			makeProcessor: func(url string) CurrencyProcessor { return &RippleXrpProcessor{processorApiUrl{apiUrl: url + "/"}} },
+			address: currencies.AddressData{Address: "rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn"},
+			route: "/accounts/rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn/payments",
+			file: "rippleXrpHistory.json",
+			parse: func(body []byte, address currencies.AddressData) []currencies.TransactionsHistoryItem {
+				history, _ := parseRippleXrpHistory(body)
+				return history
+			},
+		},
+	}
+}
+
+func TestProcessorsHistory(t *testing.T) {
+	for _, testCase := range getProcessorHistoryTestCases() {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+				testCase.route: {file: testCase.file},
+			})
+			defer cleanup()
+
+			expectedHistory := testCase.parse(readTestData(t, testCase.file), testCase.address)
+			assert.NotEmpty(expectedHistory)
+
+			history, err := testCase.makeProcessor(server.URL).GetTransactionsHistory(testCase.address, 0, 0)
+			assert.Nil(err)
+			assert.Equal(expectedHistory, history)
+		})
+	}
+}
+
+func makeRippleXrpPaymentsPage(marker string, hashes ...string) string {
+	payments := []string{}
+	for _, hash := range hashes {
+		payments = append(payments, fmt.Sprintf("{\"amount\":\"1\",\"executed_time\":\"2018-09-01T10:00:00Z\",\"tx_hash\":\"%s\",\"transaction_cost\":\"0.00001\"}", hash))
+	}
+	return fmt.Sprintf("{\"marker\":\"%s\",\"payments\":[%s]}", marker, strings.Join(payments, ","))
+}
+
+func TestRippleXrpHistoryPages(t *testing.T) {
+	assert := require.New(t)
+
+	pageRoute := "/accounts/rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn/payments?currency=XRP&descending=true"
+	routes := map[string]fixtureRoute{
+		pageRoute + "&limit=": {body: makeRippleXrpPaymentsPage("m1", "a", "b")},
+		pageRoute + "&marker=m1": {body: makeRippleXrpPaymentsPage("m2", "c", "d")},
+		pageRoute + "&marker=m2": {body: makeRippleXrpPaymentsPage("", "e")},
+	}
+	server, cleanup := makeFixturesServer(t, routes)
+	defer cleanup()
+
+	getHashes := func(history []currencies.TransactionsHistoryItem) (hashes []string) {
+		for _, item := range history {
+			hashes = append(hashes, item.Hash)
+		}
+		return
+	}
+
+	processor := &RippleXrpProcessor{processorApiUrl{apiUrl: server.URL + "/"}}
+	address := currencies.AddressData{Address: "rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn"}
+
+	history, err := processor.GetTransactionsHistory(address, 0, 0)
+	assert.Nil(err)
+	assert.Equal([]string{"a", "b", "c", "d", "e"}, getHashes(history))
+
+	// the offset is served over the page borders
+	history, err = processor.GetTransactionsHistory(address, 3, 2)
+	assert.Nil(err)
+	assert.Equal([]string{"d", "e"}, getHashes(history))
+
+	history, err = processor.GetTransactionsHistory(address, 1, 2)
+	assert.Nil(err)
+	assert.Equal([]string{"b", "c"}, getHashes(history))
+
+	// a missing page is an error, not the end of the history
+	brokenServer, brokenCleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		pageRoute + "&limit=": routes[pageRoute + "&limit="],
+	})
+	defer brokenCleanup()
+
+	history, err = (&RippleXrpProcessor{processorApiUrl{apiUrl: brokenServer.URL + "/"}}).GetTransactionsHistory(address, 2, 2)
+	assert.Error(err)
+	assert.Nil(history)
+}
+
+func makeBitcoinGoldHistoryWindow(from int, count int) string {
+	items := []string{}
+	for i := from; i < from + count; i++ {
+		items = append(items, fmt.Sprintf("{\"txid\":\"%d\",\"vin\":[],\"vout\":[]}", i))
+	}
+	return fmt.Sprintf("{\"items\":[%s]}", strings.Join(items, ","))
+}
+
+func TestBitcoinGoldHistoryWindows(t *testing.T) {
+	assert := require.New(t)
+
+	historyRoute := "/addrs/GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk/txs"
+	server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		historyRoute + "?from=0&to=50": {body: makeBitcoinGoldHistoryWindow(0, 50)},
+		historyRoute + "?from=50&to=100": {body: makeBitcoinGoldHistoryWindow(50, 3)},
+		historyRoute + "?from=50&to=52": {body: makeBitcoinGoldHistoryWindow(50, 2)},
+	})
+	defer cleanup()
+
+	processor := &BitcoinGoldProcessor{processorApiUrl{apiUrl: server.URL + "/"}}
+	address := currencies.AddressData{Address: "GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk"}
+
+	// no limit, the windows are requested until the history ends
+	history, err := processor.GetTransactionsHistory(address, 0, 0)
+	assert.Nil(err)
+	assert.Equal(53, len(history))
+	assert.Equal("52", history[52].Hash)
+
+	// the limit is bigger than one window
+	history, err = processor.GetTransactionsHistory(address, 0, 52)
+	assert.Nil(err)
+	assert.Equal(52, len(history))
+	assert.Equal("51", history[51].Hash)
+
+	// a window that can't be received fails the request
+	history, err = processor.GetTransactionsHistory(address, 50, 10)
+	assert.Error(err)
+	assert.Nil(history)
+}
+
+func TestProcessorsErrorResponses(t *testing.T) {
+	errorRoutes := map[string]fixtureRoute{
+		"malformed": {body: "<html><body>Bad gateway</body></html>"},
+		"server error": {status: http.StatusInternalServerError, body: "{\"error\":\"internal\"}"},
+		"not found": {status: http.StatusNotFound, body: "Not found"},
+		// would be parsed as an empty history if the status was ignored
+		"client error": {status: http.StatusBadRequest, body: "{}"},
+	}
+
+	balanceProviderNames := map[currencies.Currency]string{
+		currencies.Bitcoin: btcComProvider,
+		currencies.BitcoinCash: blockchairProvider,
+		currencies.BitcoinGold: btgexpProvider,
+		currencies.Ether: etherscanProvider,
+		currencies.RippleXrp: rippleDataProvider,
+		currencies.Erc20Token: tokenBalanceProvider,
+		currencies.Litecoin: esploraProvider,
+	}
+
+	for routeName, route := range errorRoutes {
+		t.Run(routeName, func(t *testing.T) {
+			assert := require.New(t)
+
+			// every request gets the same error
+			server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+				"/": route,
+			})
+			defer cleanup()
+
+			for currency, providerName := range balanceProviderNames {
+				defer useTestBalanceProvider(currency, providerName, server.URL + "/")()
+
+				address := currencies.AddressData{Currency: currency, Address: "address", ContractAddress: "contract"}
+				balances := (*GetProcessor(currency)).GetBalanceBunch([]currencies.AddressData{address, address})
+				assert.Equal([]*big.Int{nil, nil}, balances, providerName)
+			}
+
+			for _, testCase := range getProcessorHistoryTestCases() {
+				history, err := testCase.makeProcessor(server.URL).GetTransactionsHistory(testCase.address, 0, 0)
+				assert.Error(err, testCase.name)
+				assert.Nil(history, testCase.name)
+			}
+
+			assert.Nil((&Erc20Processor{tokenDataApiUrl: server.URL + "/"}).GetTokenData("contract"))
+
+			rates, err := (&CoinGeckoRateProvider{ApiUrl: server.URL + "/"}).GetRates([]string{"bitcoin"}, []string{"usd"})
+			assert.Error(err)
+			assert.Nil(rates)
+
+			rates, err = (&CoinMarketCapRateProvider{ApiKey: "key", ApiUrl: server.URL + "/"}).GetRates([]string{"bitcoin"}, []string{"usd"})
+			assert.Error(err)
+			assert.Nil(rates)
+		})
+	}
+}
+
+func TestRateProvidersOverHttp(t *testing.T) {
+	assert := require.New(t)
+
+	server, cleanup := makeFixturesServer(t, map[string]fixtureRoute{
+		"/coingecko/simple/price?": {file: "coinGeckoRates.json"},
+		"/cmc/cryptocurrency/quotes/latest?": {
+			file: "coinMarketCapRates.json",
+			requiredHeaders: map[string]string{"X-CMC_PRO_API_KEY": "key"},
+		},
+	})
+	defer cleanup()
+
+	rates, err := MakeRateProvider(CoinGeckoRateProviderName, "", server.URL + "/coingecko/").GetRates([]string{"bitcoin", "ripple"}, []string{"usd", "eur"})
+	assert.Nil(err)
+	assert.Equal("6512.37", rates["usd"]["bitcoin"].Text('f', 2))
+	assert.Equal("0.29182", rates["eur"]["ripple"].Text('f', 5))
+
+	rates, err = MakeRateProvider(CoinMarketCapRateProviderName, "key", server.URL + "/cmc/").GetRates([]string{"bitcoin", "ethereum"}, []string{"eur"})
+	assert.Nil(err)
+	assert.Equal("5604.81", rates["eur"]["bitcoin"].Text('f', 2))
+
+	// the server refuses requests without the key
+	rates, err = MakeRateProvider(CoinMarketCapRateProviderName, "wrong", server.URL + "/cmc/").GetRates([]string{"bitcoin"}, []string{"eur"})
+	assert.Error(err)
+	assert.Nil(rates)
+}
@@ -0,0 +1,251 @@
+package cryptoFunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// rates of coins in fiat currencies: fiat -> price id -> rate
+type RatesData map[string]map[string]*big.Float
+
+type RateProvider interface {
+	// returns rates for the price ids in one go, ids unknown to the provider are missing in the result
+	// fiats are lowercase ISO 4217 codes, the rates of successful requests are returned even if some of them failed
+	GetRates(priceIds []string, fiats []string) (RatesData, error)
+}
+
+const (
+	CoinGeckoRateProviderName string = "coingecko"
+	CoinMarketCapRateProviderName string = "coinmarketcap"
+)
+
+const (
+	coinGeckoApiUrl string = "https://api.coingecko.com/api/v3/"
+	coinMarketCapApiUrl string = "https://pro-api.coinmarketcap.com/v1/"
+)
+
+// how many ids we put to one request (the providers limit the length of the query)
+const rateRequestBatchSize int = 100
+
+// price ids are the ids of the coins on the provider website, e.g. "bitcoin" or "bitcoin-cash"
+// https://www.coingecko.com/en/api
+type CoinGeckoRateProvider struct {
+	// the default URL is used if empty
+	ApiUrl string
+}
+
+// https://coinmarketcap.com/api/documentation/v1/
+type CoinMarketCapRateProvider struct {
+	ApiKey string
+	// the default URL is used if empty
+	ApiUrl string
+}
+
+type coinMarketCapQuote struct {
+	Price json.Number `json:"price"`
+}
+
+type coinMarketCapCurrencyData struct {
+	Slug string `json:"slug"`
+	Quote map[string]coinMarketCapQuote `json:"quote"`
+}
+
+type coinMarketCapQuotesResp struct {
+	Status struct {
+		ErrorCode int `json:"error_code"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"status"`
+	Data map[string]coinMarketCapCurrencyData `json:"data"`
+}
+
+// returns nil if the name is unknown, CoinGecko is used by default as it doesn't need an API key
+// apiUrl can be empty to use the default one
+func MakeRateProvider(name string, apiKey string, apiUrl string) RateProvider {
+	switch name {
+	case "", CoinGeckoRateProviderName:
+		return &CoinGeckoRateProvider{ApiUrl: apiUrl}
+	case CoinMarketCapRateProviderName:
+		if apiKey == "" {
+			log.Print("CoinMarketCap API key is not set")
+		}
+		return &CoinMarketCapRateProvider{ApiKey: apiKey, ApiUrl: apiUrl}
+	default:
+		log.Printf("Unknown rate provider: %s", name)
+		return nil
+	}
+}
+
+// splits the ids into groups that fit in one request, empty ids are skipped
+func makeRateRequestBatches(priceIds []string) (batches [][]string) {
+	batch := make([]string, 0, rateRequestBatchSize)
+	for _, priceId := range priceIds {
+		if priceId == "" {
+			continue
+		}
+
+		batch = append(batch, priceId)
+		if len(batch) >= rateRequestBatchSize {
+			batches = append(batches, batch)
+			batch = make([]string, 0, rateRequestBatchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return
+}
+
+func (rates RatesData) add(fiat string, priceId string, rate *big.Float) {
+	fiatRates, ok := rates[fiat]
+	if !ok {
+		fiatRates = make(map[string]*big.Float)
+		rates[fiat] = fiatRates
+	}
+	fiatRates[priceId] = rate
+}
+
+func (rates RatesData) merge(otherRates RatesData) {
+	for fiat, fiatRates := range otherRates {
+		for priceId, rate := range fiatRates {
+			rates.add(fiat, priceId, rate)
+		}
+	}
+}
+
+func parseRate(number json.Number) *big.Float {
+	// null in the response
+	if number == "" {
+		return nil
+	}
+
+	rate, _, err := new(big.Float).Parse(string(number), 10)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	return rate
+}
+
+func (provider *CoinGeckoRateProvider) GetRates(priceIds []string, fiats []string) (rates RatesData, err error) {
+	if len(fiats) == 0 {
+		return
+	}
+
+	apiUrl := provider.ApiUrl
+	if apiUrl == "" {
+		apiUrl = coinGeckoApiUrl
+	}
+
+	// all the fiats can be requested at once
+	fiatsList := url.QueryEscape(strings.Join(fiats, ","))
+
+	for _, batch := range makeRateRequestBatches(priceIds) {
+		body, requestErr := fetchResponseBody(apiUrl + "simple/price?vs_currencies=" + fiatsList + "&ids=" + url.QueryEscape(strings.Join(batch, ",")))
+		if requestErr != nil {
+			err = requestErr
+			continue
+		}
+
+		batchRates := parseCoinGeckoRates(body)
+		if batchRates == nil {
+			err = fmt.Errorf("can't parse the rates from %s", CoinGeckoRateProviderName)
+			continue
+		}
+
+		if rates == nil {
+			rates = make(RatesData)
+		}
+		rates.merge(batchRates)
+	}
+	return
+}
+
+func parseCoinGeckoRates(body []byte) (rates RatesData) {
+	// {"bitcoin":{"usd":6500.12,"eur":5600.3}}
+	var parsedResp map[string]map[string]json.Number
+	err := json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		log.Print(err)
+		return nil
+	}
+
+	rates = make(RatesData)
+	for priceId, prices := range parsedResp {
+		for fiat, price := range prices {
+			if rate := parseRate(price); rate != nil {
+				rates.add(fiat, priceId, rate)
+			}
+		}
+	}
+	return
+}
+
+func (provider *CoinMarketCapRateProvider) GetRates(priceIds []string, fiats []string) (rates RatesData, err error) {
+	apiUrl := provider.ApiUrl
+	if apiUrl == "" {
+		apiUrl = coinMarketCapApiUrl
+	}
+
+	for _, batch := range makeRateRequestBatches(priceIds) {
+		// the basic plan allows only one conversion per request
+		for _, fiat := range fiats {
+			request, requestErr := http.NewRequest("GET", apiUrl + "cryptocurrency/quotes/latest?convert=" + url.QueryEscape(strings.ToUpper(fiat)) + "&slug=" + url.QueryEscape(strings.Join(batch, ",")), nil)
+			if requestErr != nil {
+				return rates, requestErr
+			}
+			request.Header.Set("X-CMC_PRO_API_KEY", provider.ApiKey)
+			request.Header.Set("Accept", "application/json")
+
+			body, requestErr := fetchRequestResponseBody(request)
+			if requestErr != nil {
+				err = requestErr
+				continue
+			}
+
+			batchRates := parseCoinMarketCapRates(body)
+			if batchRates == nil {
+				err = fmt.Errorf("can't parse the rates from %s", CoinMarketCapRateProviderName)
+				continue
+			}
+
+			if rates == nil {
+				rates = make(RatesData)
+			}
+			rates.merge(batchRates)
+		}
+	}
+	return
+}
+
+func parseCoinMarketCapRates(body []byte) (rates RatesData) {
+	var parsedResp = new(coinMarketCapQuotesResp)
+	err := json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		log.Print(err)
+		return nil
+	}
+
+	if parsedResp.Status.ErrorCode != 0 {
+		log.Printf("CoinMarketCap error %d: %s", parsedResp.Status.ErrorCode, parsedResp.Status.ErrorMessage)
+		return nil
+	}
+
+	rates = make(RatesData)
+	// the data is keyed by the internal CoinMarketCap ids when requested by slugs
+	for _, currencyData := range parsedResp.Data {
+		for fiat, quote := range currencyData.Quote {
+			if rate := parseRate(quote.Price); rate != nil {
+				rates.add(strings.ToLower(fiat), currencyData.Slug, rate)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package cryptoFunctions
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func TestCoinGeckoRatesParsing(t *testing.T) {
+	assert := require.New(t)
+
+	rates := parseCoinGeckoRates(readTestData(t, "coinGeckoRates.json"))
+
+	assert.Equal(2, len(rates))
+	assert.Equal(3, len(rates["usd"]))
+	assert.Equal("6512.37", rates["usd"]["bitcoin"].Text('f', 2))
+	assert.Equal("520.10", rates["usd"]["bitcoin-cash"].Text('f', 2))
+	assert.Equal("0.33916", rates["usd"]["ripple"].Text('f', 5))
+	assert.Nil(rates["usd"]["some-token"])
+	// the rate of Bitcoin Cash in EUR is unknown
+	assert.Equal(2, len(rates["eur"]))
+	assert.Equal("5604.81", rates["eur"]["bitcoin"].Text('f', 2))
+	assert.Equal("0.29182", rates["eur"]["ripple"].Text('f', 5))
+
+	assert.Nil(parseCoinGeckoRates([]byte("<html>")))
+}
+
+func TestCoinMarketCapRatesParsing(t *testing.T) {
+	assert := require.New(t)
+
+	rates := parseCoinMarketCapRates(readTestData(t, "coinMarketCapRates.json"))
+
+	assert.Equal(1, len(rates))
+	assert.Equal(2, len(rates["eur"]))
+	assert.Equal("5604.81", rates["eur"]["bitcoin"].Text('f', 2))
+	assert.Equal("179.812", rates["eur"]["ethereum"].Text('f', 3))
+
+	assert.Nil(parseCoinMarketCapRates([]byte("{\"status\":{\"error_code\":1002,\"error_message\":\"API key missing.\"}}")))
+	assert.Nil(parseCoinMarketCapRates([]byte("<html>")))
+}
+
+func TestRateRequestBatches(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Equal(0, len(makeRateRequestBatches(nil)))
+	assert.Equal([][]string{{"bitcoin", "ripple"}}, makeRateRequestBatches([]string{"bitcoin", "", "ripple"}))
+
+	priceIds := make([]string, 0, rateRequestBatchSize + 1)
+	for i := 0; i <= rateRequestBatchSize; i++ {
+		priceIds = append(priceIds, fmt.Sprintf("coin%d", i))
+	}
+
+	batches := makeRateRequestBatches(priceIds)
+	assert.Equal(2, len(batches))
+	assert.Equal(rateRequestBatchSize, len(batches[0]))
+	assert.Equal([]string{fmt.Sprintf("coin%d", rateRequestBatchSize)}, batches[1])
+}
+
+func TestMakeRateProvider(t *testing.T) {
+	assert := require.New(t)
+
+	assert.IsType(&CoinGeckoRateProvider{}, MakeRateProvider("", "", ""))
+	assert.IsType(&CoinGeckoRateProvider{}, MakeRateProvider(CoinGeckoRateProviderName, "", ""))
+	assert.Equal(&CoinMarketCapRateProvider{ApiKey: "key"}, MakeRateProvider(CoinMarketCapRateProviderName, "key", ""))
+	assert.Equal(&CoinGeckoRateProvider{ApiUrl: "http://localhost/"}, MakeRateProvider(CoinGeckoRateProviderName, "", "http://localhost/"))
+	assert.Nil(MakeRateProvider("unknown", "", ""))
+}
+
+func TestRateFormatting(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Equal("6512.37", FormatRate(big.NewFloat(6512.3713)))
+	assert.Equal("1.50", FormatRate(big.NewFloat(1.5)))
+	assert.Equal("0.33916", FormatRate(big.NewFloat(0.33916)))
+	assert.Equal("0.000012", FormatRate(big.NewFloat(0.0000123)))
+}
@@ -3,16 +3,23 @@ package cryptoFunctions
 import (
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"log"
-	"net/http"
 	"math/big"
+	"net/url"
 	"regexp"
+	"time"
 )
 
 var rippleXrpAddressRegex *regexp.Regexp
 
+const rippleDataApiUrl string = "https://data.ripple.com/v2/"
+
+// the most records the API returns in one page
+const rippleDataMaxPageSize int = 1000
+
 type RippleXrpProcessor struct {
+	processorApiUrl
 }
 
 type RippleXrpRespData struct {
@@ -23,6 +30,24 @@ type RippleXrpResp struct {
 	Balances []RippleXrpRespData `json:"balances"`
 }
 
+type RippleXrpPayment struct {
+	Amount string `json:"amount"`
+	// can differ from amount for partial payments
+	DeliveredAmount string `json:"delivered_amount"`
+	Source string `json:"source"`
+	Destination string `json:"destination"`
+	ExecutedTime string `json:"executed_time"`
+	TxHash string `json:"tx_hash"`
+	LedgerIndex int64 `json:"ledger_index"`
+	TransactionCost string `json:"transaction_cost"`
+}
+
+type RippleXrpPaymentsResp struct {
+	Payments []RippleXrpPayment `json:"payments"`
+	// empty on the last page
+	Marker string `json:"marker"`
+}
+
 func init() {
 	rippleXrpAddressRegex = regexp.MustCompile("^r[1-9a-km-zA-HJ-NP-Z]{24,34}$")
 	if rippleXrpAddressRegex == nil {
@@ -30,53 +55,160 @@ func init() {
 	}
 }
 
-func (processor *RippleXrpProcessor) GetBalance(address currencies.AddressData) *big.Int {
-	resp, err := http.Get("https://data.ripple.com/v2/accounts/" + address.Address + "/balances?currency=XRP&limit=1")
-	if err != nil {
-		log.Print(err)
+type rippleDataBalanceProvider struct {
+	apiUrl string
+}
+
+func makeRippleDataBalanceProvider(currency currencies.Currency, url string) BalanceProvider {
+	if currency != currencies.RippleXrp {
 		return nil
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	if url == "" {
+		url = rippleDataApiUrl
+	}
+
+	return &rippleDataBalanceProvider{
+		apiUrl: url,
+	}
+}
+
+func (provider *rippleDataBalanceProvider) GetName() string {
+	return rippleDataProvider
+}
+
+func (provider *rippleDataBalanceProvider) getBalance(address currencies.AddressData) (*big.Int, error) {
+	body, err := fetchResponseBody(provider.apiUrl + "accounts/" + address.Address + "/balances?currency=XRP&limit=1")
 	if err != nil {
-		log.Print(err)
-		return nil
+		return nil, err
 	}
 
 	var parsedResp = new(RippleXrpResp)
 	err = json.Unmarshal(body, &parsedResp)
 	if(err != nil){
 		log.Print(string(body[:]))
-		log.Print(err)
-		return nil
+		return nil, err
 	}
 
-	if len(parsedResp.Balances) > 0 {
-		floatValue, _, err := new(big.Float).Parse(parsedResp.Balances[0].Value, 10)
+	if len(parsedResp.Balances) == 0 {
+		return nil, fmt.Errorf("no XRP balance for %s", address.Address)
+	}
 
-		if err == nil {
-			intValue, _ := new(big.Float).Mul(floatValue, new(big.Float).SetFloat64(1000000)).Int(nil)
-			return intValue
-		} else {
-			return nil
-		}
-	} else {
-		return nil
+	floatValue, _, err := new(big.Float).Parse(parsedResp.Balances[0].Value, 10)
+	if err != nil {
+		return nil, err
 	}
+
+	intValue, _ := new(big.Float).Mul(floatValue, new(big.Float).SetFloat64(1000000)).Int(nil)
+	return intValue, nil
+}
+
+func (provider *rippleDataBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
+	return getBalancesConcurrently(rippleDataProvider, addresses, provider.getBalance)
+}
+
+func (processor *RippleXrpProcessor) GetBalance(address currencies.AddressData) *big.Int {
+	return getCurrencyBalance(currencies.RippleXrp, address)
 }
 
 func (processor *RippleXrpProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
-	balances := make([]*big.Int, len(addresses))
+	return getCurrencyBalanceBunch(currencies.RippleXrp, addresses)
+}
+
+func (processor *RippleXrpProcessor) GetBalanceResults(addresses []currencies.AddressData) []BalanceResult {
+	return getCurrencyBalanceResults(currencies.RippleXrp, addresses)
+}
+
+func (processor *RippleXrpProcessor) GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error) {
+	// the API pages by markers and limits the page size, so the pages before the offset are requested too
+	// a page that can't be received fails the whole request, a short history would look like its end
+	history = []currencies.TransactionsHistoryItem{}
+	marker := ""
+	for {
+		requestText := processor.getApiUrl(rippleDataApiUrl) + "accounts/" + address.Address + "/payments?currency=XRP&descending=true"
+		if marker != "" {
+			requestText += "&marker=" + url.QueryEscape(marker)
+		}
+
+		pageSize := rippleDataMaxPageSize
+		if limit > 0 && offset + limit - len(history) < pageSize {
+			pageSize = offset + limit - len(history)
+		}
+		requestText += fmt.Sprintf("&limit=%d", pageSize)
 
-	for i, walletAddress := range addresses {
-		balances[i] = processor.GetBalance(walletAddress)
+		body, err := fetchResponseBody(requestText)
+		if err != nil {
+			return nil, err
+		}
+
+		page, nextMarker := parseRippleXrpHistory(body)
+		if page == nil {
+			return nil, fmt.Errorf("can't parse the history of %s", address.Address)
+		}
+		history = append(history, page...)
+
+		if nextMarker == "" || len(page) == 0 || (limit > 0 && len(history) >= offset + limit) {
+			break
+		}
+		marker = nextMarker
 	}
 
-	return balances
+	history = skipHistoryItems(history, offset)
+	if limit > 0 && len(history) > limit {
+		history = history[:limit]
+	}
+	return history, nil
 }
 
-func (processor *RippleXrpProcessor) GetTransactionsHistory(address currencies.AddressData, limit int) (history []currencies.TransactionsHistoryItem) {
+// returns the marker of the next page, empty if it is the last one
+func parseRippleXrpHistory(body []byte) (history []currencies.TransactionsHistoryItem, marker string) {
+	var parsedResp = new(RippleXrpPaymentsResp)
+	err := json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		log.Print(err)
+		return
+	}
+
+	marker = parsedResp.Marker
+
+	history = make([]currencies.TransactionsHistoryItem, 0, len(parsedResp.Payments))
+
+	for _, payment := range parsedResp.Payments {
+		amountText := payment.DeliveredAmount
+		if amountText == "" {
+			amountText = payment.Amount
+		}
+
+		amount, ok := parseDecimalAmount(amountText, currencies.GetCurrencyDecimals(currencies.RippleXrp))
+		if !ok {
+			amount = big.NewInt(0)
+			log.Printf("Wrong amount value: %s", amountText)
+		}
+
+		txTime, err := time.Parse(time.RFC3339, payment.ExecutedTime)
+		if err != nil {
+			log.Print(err.Error())
+		}
+
+		// the fee is paid by the sender
+		fee, ok := parseDecimalAmount(payment.TransactionCost, currencies.GetCurrencyDecimals(currencies.RippleXrp))
+		if !ok {
+			fee = nil
+		}
+
+		// the API returns only validated payments, confirmations don't make sense for XRP ledger
+		history = append(history, currencies.TransactionsHistoryItem {
+				From: payment.Source,
+				To: payment.Destination,
+				Amount: amount,
+				Time: txTime,
+				Hash: payment.TxHash,
+				Fee: fee,
+				BlockHeight: payment.LedgerIndex,
+			})
+	}
+
 	return
 }
 
@@ -0,0 +1,128 @@
+package cryptoFunctions
+
+import (
+	"math/big"
+)
+
+// minimal secp256k1 arithmetic, enough to derive public keys of HD wallets
+// (no secret data is processed here so it doesn't need to be constant-time)
+
+type ecPoint struct {
+	x *big.Int
+	y *big.Int
+}
+
+var secp256k1P *big.Int
+var secp256k1N *big.Int
+var secp256k1G ecPoint
+
+func init() {
+	secp256k1P, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	secp256k1N, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	gx, _ := new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	gy, _ := new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+	secp256k1G = ecPoint{x: gx, y: gy}
+}
+
+// nil coordinates are used for the point at infinity
+func (point *ecPoint) isInfinity() bool {
+	return point.x == nil
+}
+
+func ecAdd(a ecPoint, b ecPoint) ecPoint {
+	if a.isInfinity() {
+		return b
+	}
+	if b.isInfinity() {
+		return a
+	}
+
+	p := secp256k1P
+	var slope *big.Int
+
+	if a.x.Cmp(b.x) == 0 {
+		// a == -b
+		ySum := new(big.Int).Add(a.y, b.y)
+		if ySum.Mod(ySum, p).Sign() == 0 {
+			return ecPoint{}
+		}
+		// doubling: slope = 3x^2 / 2y
+		numerator := new(big.Int).Mul(a.x, a.x)
+		numerator.Mul(numerator, big.NewInt(3))
+		denominator := new(big.Int).Lsh(a.y, 1)
+		slope = numerator.Mul(numerator, denominator.ModInverse(denominator, p))
+	} else {
+		numerator := new(big.Int).Sub(b.y, a.y)
+		denominator := new(big.Int).Sub(b.x, a.x)
+		denominator.Mod(denominator, p)
+		slope = numerator.Mul(numerator, denominator.ModInverse(denominator, p))
+	}
+	slope.Mod(slope, p)
+
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, a.x)
+	x.Sub(x, b.x)
+	x.Mod(x, p)
+
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, slope)
+	y.Sub(y, a.y)
+	y.Mod(y, p)
+
+	return ecPoint{x: x, y: y}
+}
+
+func ecScalarBaseMult(scalar *big.Int) ecPoint {
+	result := ecPoint{}
+	addend := secp256k1G
+
+	for i := 0; i < scalar.BitLen(); i++ {
+		if scalar.Bit(i) == 1 {
+			result = ecAdd(result, addend)
+		}
+		addend = ecAdd(addend, addend)
+	}
+
+	return result
+}
+
+// parses a public key in SEC1 compressed form
+func decompressPoint(data []byte) (point ecPoint, ok bool) {
+	if len(data) != 33 || (data[0] != 2 && data[0] != 3) {
+		return ecPoint{}, false
+	}
+
+	p := secp256k1P
+	x := new(big.Int).SetBytes(data[1:])
+	if x.Cmp(p) >= 0 {
+		return ecPoint{}, false
+	}
+
+	// y^2 = x^3 + 7
+	ySquare := new(big.Int).Exp(x, big.NewInt(3), p)
+	ySquare.Add(ySquare, big.NewInt(7))
+	ySquare.Mod(ySquare, p)
+
+	// p = 3 mod 4, so the square root is ySquare^((p+1)/4)
+	exponent := new(big.Int).Add(p, big.NewInt(1))
+	exponent.Rsh(exponent, 2)
+	y := new(big.Int).Exp(ySquare, exponent, p)
+
+	if new(big.Int).Exp(y, big.NewInt(2), p).Cmp(ySquare) != 0 {
+		return ecPoint{}, false
+	}
+
+	if y.Bit(0) != uint(data[0] & 1) {
+		y.Sub(p, y)
+	}
+
+	return ecPoint{x: x, y: y}, true
+}
+
+func compressPoint(point ecPoint) []byte {
+	result := make([]byte, 33)
+	result[0] = 2 + byte(point.y.Bit(0))
+	xBytes := point.x.Bytes()
+	copy(result[33 - len(xBytes):], xBytes)
+	return result
+}
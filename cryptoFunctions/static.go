@@ -2,17 +2,9 @@ package cryptoFunctions
 
 import (
 	"math/big"
-	"net/http"
-	"io/ioutil"
-	"log"
-	"encoding/json"
 	"strings"
 )
 
-type RateData struct {
-	PriceUsd string `json:"price_usd"`
-}
-
 func GetFloatBalance(intValue *big.Int, digits int) *big.Float {
 	if intValue == nil {
 		return nil
@@ -32,6 +24,14 @@ func FormatFloatCurrencyAmount(floatValue *big.Float, digits int) string {
 	}
 }
 
+// cheap coins need more digits to see the changes of their price
+func FormatRate(rate *big.Float) string {
+	if rate.Cmp(big.NewFloat(1)) >= 0 {
+		return rate.Text('f', 2)
+	}
+	return FormatFloatCurrencyAmount(rate, 6)
+}
+
 func FormatCurrencyAmount(intValue *big.Int, digits int) string {
 
 	var floatValue *big.Float = GetFloatBalance(intValue, digits)
@@ -42,43 +42,3 @@ func FormatCurrencyAmount(intValue *big.Int, digits int) string {
 
 	return FormatFloatCurrencyAmount(floatValue, digits)
 }
-
-// currencyId see here https://coinmarketcap.com/api/
-func GetCurrencyToUsdRate(currencyId string) *big.Float {
-	resp, err := http.Get("https://api.coinmarketcap.com/v1/ticker/" + currencyId + "/")
-	if err != nil {
-		log.Print(err)
-		return nil
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Print(err)
-		return nil
-	}
-
-	jsonStr := string(body[:])
-
-	// remove trailing brackets
-	jsonStr = strings.Replace(jsonStr, "[", "", -1)
-	jsonStr = strings.Replace(jsonStr, "]", "", -1)
-
-	var parsedResp = new(RateData)
-	err = json.Unmarshal([]byte(jsonStr), &parsedResp)
-	if(err != nil){
-		log.Print(string(body[:]))
-		log.Print(err)
-		return nil
-	}
-
-	rate, _, err := new(big.Float).Parse(parsedResp.PriceUsd, 10)
-
-	if err == nil {
-		return rate
-	} else {
-		log.Print(string(body[:]))
-		log.Print(err)
-		return nil
-	}
-}
@@ -2,7 +2,12 @@ package cryptoFunctions
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
 )
 
 func joinAddresses(addresses []currencies.AddressData) string {
@@ -17,3 +22,96 @@ func joinAddresses(addresses []currencies.AddressData) string {
 
 	return b.String()
 }
+
+// makes a GET request and returns the body of a successful response
+func fetchResponseBody(requestText string) ([]byte, error) {
+	request, err := http.NewRequest("GET", requestText, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchRequestResponseBody(request)
+}
+
+// same as fetchResponseBody for requests that need custom headers
+// error responses are never returned as bodies, some of them would be parsed as valid empty data
+func fetchRequestResponseBody(request *http.Request) ([]byte, error) {
+	resp, err := doProviderRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s responded with %s", request.URL.Host, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// parses a decimal text like "0.00012" to an integer amount of the smallest units
+func parseDecimalAmount(text string, decimals int) (*big.Int, bool) {
+	text = strings.TrimSpace(text)
+
+	isNegative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+
+	parts := strings.SplitN(text, ".", 2)
+	integerPart := parts[0]
+	fractionalPart := ""
+	if len(parts) > 1 {
+		fractionalPart = parts[1]
+	}
+
+	if len(fractionalPart) > decimals {
+		// we can't represent this precisely
+		if strings.TrimRight(fractionalPart[decimals:], "0") != "" {
+			return nil, false
+		}
+		fractionalPart = fractionalPart[:decimals]
+	}
+
+	if integerPart + fractionalPart == "" || strings.ContainsAny(integerPart + fractionalPart, "+-") {
+		return nil, false
+	}
+
+	digits := integerPart + fractionalPart + strings.Repeat("0", decimals - len(fractionalPart))
+
+	value, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, false
+	}
+
+	if isNegative {
+		value.Neg(value)
+	}
+	return value, true
+}
+
+// converts offset and limit to parameters of page-based APIs (pages start from 1)
+// skip is the number of records that should be dropped from the beginning of the returned page
+func getPageParams(offset int, limit int) (page int, pageSize int, skip int) {
+	if limit <= 0 {
+		return 1, 0, offset
+	}
+
+	if offset % limit == 0 {
+		return offset / limit + 1, limit, 0
+	}
+
+	// the offset doesn't fit page borders, request everything from the beginning
+	return 1, offset + limit, offset
+}
+
+func skipHistoryItems(history []currencies.TransactionsHistoryItem, skip int) []currencies.TransactionsHistoryItem {
+	// nil means that the history is unknown, it shouldn't look like an empty page
+	if skip <= 0 || history == nil {
+		return history
+	}
+
+	if skip >= len(history) {
+		return []currencies.TransactionsHistoryItem{}
+	}
+
+	return history[skip:]
+}
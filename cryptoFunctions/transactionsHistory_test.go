@@ -0,0 +1,304 @@
+package cryptoFunctions
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func readTestData(t *testing.T, fileName string) []byte {
+	data, err := ioutil.ReadFile("testdata/" + fileName)
+	require.NoError(t, err)
+	return data
+}
+
+func TestBitcoinHistoryParsing(t *testing.T) {
+	assert := require.New(t)
+
+	history := parseBitcoinHistory(readTestData(t, "bitcoinHistory.json"), "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu")
+
+	assert.Equal([]currencies.TransactionsHistoryItem{
+		// unconfirmed
+		{
+			From: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			To: "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu",
+			Amount: big.NewInt(5000),
+			Time: time.Unix(1536900000, 0),
+			Hash: "5f3a8c6c52ef3d8e2f0f39e1d33bd3b3dc0ab3c1b89d9a1bd8c7d2b3f4e8a901",
+			Fee: big.NewInt(226),
+			Status: currencies.TransactionPending,
+		},
+		// two inputs and the change returned to the same address, the fee is included
+		{
+			From: "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu",
+			To: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			Amount: big.NewInt(75000),
+			Time: time.Unix(1536800000, 0),
+			Hash: "c1b8a4f7e2d9031c6a5b4e3f2d1c0b9a8f7e6d5c4b3a29180f7e6d5c4b3a2910",
+			Fee: big.NewInt(5000),
+			BlockHeight: 540210,
+			Confirmations: 110,
+		},
+		{
+			From: "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+			To: "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu",
+			Amount: big.NewInt(100000),
+			Time: time.Unix(1536700000, 0),
+			Hash: "2b7e5d8c9f0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4",
+			Fee: big.NewInt(1000),
+			BlockHeight: 540100,
+			Confirmations: 220,
+		},
+	}, history)
+
+	assert.Equal(0, len(parseBitcoinHistory([]byte("{\"err_no\":1,\"data\":null}"), "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu")))
+	assert.Nil(parseBitcoinHistory([]byte("<html>"), "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu"))
+}
+
+func TestBitcoinCashHistoryParsing(t *testing.T) {
+	assert := require.New(t)
+
+	// the address is requested with the prefix and returned without it
+	address := "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a"
+	addressData := parseBlockchairAddressData(readTestData(t, "bitcoinCashHistory.json"), address)
+	assert.NotNil(addressData)
+	assert.Equal(int64(1500000), addressData.Address.Balance)
+
+	history := makeBlockchairHistory(address, addressData)
+
+	assert.Equal([]currencies.TransactionsHistoryItem{
+		{
+			To: address,
+			Amount: big.NewInt(1000000),
+			Time: time.Date(2018, 9, 10, 12, 30, 0, 0, time.UTC),
+			Hash: "d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3",
+			BlockHeight: 548120,
+			Confirmations: 81,
+		},
+		{
+			From: address,
+			Amount: big.NewInt(1500000),
+			Time: time.Date(2018, 9, 5, 8, 15, 0, 0, time.UTC),
+			Hash: "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90",
+			BlockHeight: 547530,
+			Confirmations: 671,
+		},
+		{
+			To: address,
+			Amount: big.NewInt(2000000),
+			Time: time.Date(2018, 9, 1, 10, 0, 0, 0, time.UTC),
+			Hash: "f90a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8",
+			BlockHeight: 547010,
+			Confirmations: 1191,
+		},
+	}, history)
+
+	assert.Nil(parseBlockchairAddressData([]byte("{\"data\":{}}"), address))
+}
+
+func TestBitcoinGoldHistoryParsing(t *testing.T) {
+	assert := require.New(t)
+
+	history := parseBitcoinGoldHistory(readTestData(t, "bitcoinGoldHistory.json"), "GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk")
+
+	assert.Equal([]currencies.TransactionsHistoryItem{
+		{
+			From: "GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk",
+			To: "AKb942bpuzD3z4VjNMDUZzD2FkfPxYiCB8",
+			Amount: big.NewInt(100010000),
+			Time: time.Unix(1537000000, 0),
+			Hash: "7f2c9b6e4d1a3f5c8e0b2d4f6a8c0e2f4a6c8e0b2d4f6a8c0e2f4a6c8e0b2d4f",
+			Fee: big.NewInt(10000),
+			BlockHeight: 550120,
+			Confirmations: 12,
+		},
+		// coinbase transaction has no sender
+		{
+			To: "GMfBpf59ZhACFZb2jeDFqsHphKXYdnQsdk",
+			Amount: big.NewInt(250000000),
+			Time: time.Unix(1536000000, 0),
+			Hash: "3a5c7e9b1d3f5a7c9e1b3d5f7a9c1e3b5d7f9a1c3e5b7d9f1a3c5e7b9d1f3a5c",
+			Fee: big.NewInt(0),
+			BlockHeight: 549000,
+			Confirmations: 1132,
+		},
+	}, history)
+}
+
+func TestRippleXrpHistoryParsing(t *testing.T) {
+	assert := require.New(t)
+
+	history, marker := parseRippleXrpHistory(readTestData(t, "rippleXrpHistory.json"))
+	// the last page
+	assert.Equal("", marker)
+
+	assert.Equal([]currencies.TransactionsHistoryItem{
+		{
+			From: "rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn",
+			To: "rPEPPER7kfTD9w2To4CQk6UCfuHM9c6GDY",
+			Amount: big.NewInt(25500000),
+			Time: time.Date(2018, 9, 10, 12, 30, 0, 0, time.UTC),
+			Hash: "8C55AFC2A2AA42B5CE624AEECDB3ACFDD1E5379D4E5BF74A8460C5E97EF8706B",
+			Fee: big.NewInt(12),
+			BlockHeight: 41234567,
+		},
+		// partial payment, only the delivered amount counts
+		{
+			From: "rPEPPER7kfTD9w2To4CQk6UCfuHM9c6GDY",
+			To: "rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn",
+			Amount: big.NewInt(40123456),
+			Time: time.Date(2018, 9, 5, 8, 15, 0, 0, time.UTC),
+			Hash: "E08D6E9754025BA2534A78707605E0601F03ACE063687A0CA1BDDACFCD1698C7",
+			Fee: big.NewInt(12),
+			BlockHeight: 41100000,
+		},
+		{
+			From: "rGFuMiw48HdbnrUbkRYuitXTmfrDBNTCnX",
+			To: "rf1BiGeXwwQoi8Z2ueFYTEXSwuJYfV2Jpn",
+			Amount: big.NewInt(1000000000),
+			Time: time.Date(2018, 9, 1, 10, 0, 0, 0, time.UTC),
+			Hash: "2C5F8D2C5CDE3D33E4C3C7C9A2F3D6E7C5B1A4F2E0D9C8B7A6F5E4D3C2B1A0F9",
+			Fee: big.NewInt(10),
+			BlockHeight: 41000000,
+		},
+	}, history)
+}
+
+func TestDecimalAmountParsing(t *testing.T) {
+	assert := require.New(t)
+
+	testCases := []struct {
+		text string
+		decimals int
+		value int64
+		ok bool
+	}{
+		{"1.5", 8, 150000000, true},
+		{"0.00000001", 8, 1, true},
+		{"1.49990000", 8, 149990000, true},
+		{"25", 6, 25000000, true},
+		{".5", 1, 5, true},
+		{"-0.000012", 6, -12, true},
+		{"0.0000000010", 8, 0, false},
+		{"1.000000000", 8, 100000000, true},
+		{"", 8, 0, false},
+		{"abc", 8, 0, false},
+		{"1.-5", 8, 0, false},
+	}
+
+	for _, testCase := range testCases {
+		value, ok := parseDecimalAmount(testCase.text, testCase.decimals)
+		assert.Equal(testCase.ok, ok, testCase.text)
+		if testCase.ok {
+			assert.Equal(big.NewInt(testCase.value), value, testCase.text)
+		}
+	}
+}
+
+func TestEtherHistoryParsing(t *testing.T) {
+	assert := require.New(t)
+
+	history := parseEtherHistory(readTestData(t, "etherHistory.json"))
+
+	amount, _ := new(big.Int).SetString("1500000000000000000", 10)
+	assert.Equal([]currencies.TransactionsHistoryItem{
+		// failed transaction still pays the fee
+		{
+			From: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			To: "0xde709f2102306220921060314715629080e2fb77",
+			Amount: big.NewInt(0),
+			Time: time.Unix(1536900200, 0),
+			Hash: "0x9e1b4c5a2f3d8e7b6a5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a",
+			Fee: big.NewInt(84000000000000),
+			BlockHeight: 6315010,
+			Confirmations: 110,
+			Status: currencies.TransactionFailed,
+		},
+		{
+			From: "0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb",
+			To: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			Amount: amount,
+			Time: time.Unix(1536700000, 0),
+			Hash: "0x2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a",
+			Fee: big.NewInt(210000000000000),
+			BlockHeight: 6300000,
+			Confirmations: 15120,
+		},
+		// contract creation
+		{
+			From: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			To: "0xd1220a0cf47c7b9be7a2e6ba89f429762e7b9adb",
+			Amount: big.NewInt(0),
+			Time: time.Unix(1536000000, 0),
+			Hash: "0x7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b",
+			Fee: big.NewInt(3000000000000000),
+			BlockHeight: 6200000,
+			Confirmations: 115120,
+		},
+	}, history)
+}
+
+func TestErc20HistoryParsing(t *testing.T) {
+	assert := require.New(t)
+
+	// the contract address is stored in the checksum form but the API returns it in lowercase
+	history := parseErc20History(readTestData(t, "erc20History.json"), "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359")
+
+	amount, _ := new(big.Int).SetString("1250000000000000000", 10)
+	assert.Equal([]currencies.TransactionsHistoryItem{
+		{
+			From: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			To: "0xde709f2102306220921060314715629080e2fb77",
+			Amount: amount,
+			Time: time.Unix(1536900000, 0),
+			Hash: "0x6a8b8cd3ce5c1a4a36b2e5e96bd0b0f0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0",
+			Fee: big.NewInt(148000000000000),
+			BlockHeight: 6315000,
+			Confirmations: 120,
+		},
+		{
+			From: "0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb",
+			To: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			Amount: new(big.Int).Mul(big.NewInt(5), big.NewInt(1000000000000000000)),
+			Time: time.Unix(1536800000, 0),
+			Hash: "0x0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9",
+			Fee: big.NewInt(208000000000000),
+			BlockHeight: 6310000,
+			Confirmations: 5120,
+		},
+	}, history)
+
+	assert.Equal(0, len(parseErc20History([]byte("{\"status\":\"0\",\"message\":\"No transactions found\",\"result\":[]}"), "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359")))
+}
+
+func TestHistoryPageParams(t *testing.T) {
+	assert := require.New(t)
+
+	testCases := []struct {
+		offset int
+		limit int
+		page int
+		pageSize int
+		skip int
+	}{
+		{0, 10, 1, 10, 0},
+		{20, 10, 3, 10, 0},
+		{10, 11, 1, 21, 10},
+		{5, 0, 1, 0, 5},
+	}
+
+	for _, testCase := range testCases {
+		page, pageSize, skip := getPageParams(testCase.offset, testCase.limit)
+		assert.Equal(testCase.page, page)
+		assert.Equal(testCase.pageSize, pageSize)
+		assert.Equal(testCase.skip, skip)
+	}
+
+	history, _ := parseRippleXrpHistory(readTestData(t, "rippleXrpHistory.json"))
+	assert.Equal(history, skipHistoryItems(history, 0))
+	assert.Equal(history[2:], skipHistoryItems(history, 2))
+	assert.Equal(0, len(skipHistoryItems(history, 5)))
+}
@@ -0,0 +1,77 @@
+package cryptoFunctions
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"math/big"
+	"time"
+)
+
+// an input or an output of a transaction in UTXO-based currencies (Bitcoin and its forks)
+type utxoEntry struct {
+	addresses []string
+	value *big.Int
+}
+
+func isUtxoEntryOwnedBy(entry utxoEntry, address string) bool {
+	for _, entryAddress := range entry.addresses {
+		if entryAddress == address {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the first address of the entries that doesn't belong to the wallet
+func findUtxoCounterparty(entries []utxoEntry, address string) string {
+	for _, entry := range entries {
+		if !isUtxoEntryOwnedBy(entry, address) && len(entry.addresses) > 0 {
+			return entry.addresses[0]
+		}
+	}
+	return ""
+}
+
+// negative blockHeight means that the transaction is not in a block yet
+func fillUtxoBlockData(item *currencies.TransactionsHistoryItem, blockHeight int64, confirmations int64) {
+	if blockHeight > 0 {
+		item.BlockHeight = blockHeight
+		item.Confirmations = confirmations
+		item.Status = currencies.TransactionConfirmed
+	} else {
+		item.Status = currencies.TransactionPending
+	}
+}
+
+// calculates the net amount that the transaction moved to or from the address
+// change outputs returned to the same address are subtracted, so the sent amount includes the fee
+func makeUtxoHistoryItem(address string, inputs []utxoEntry, outputs []utxoEntry, txTime time.Time) currencies.TransactionsHistoryItem {
+	balanceChange := big.NewInt(0)
+
+	for _, input := range inputs {
+		if input.value != nil && isUtxoEntryOwnedBy(input, address) {
+			balanceChange.Sub(balanceChange, input.value)
+		}
+	}
+
+	for _, output := range outputs {
+		if output.value != nil && isUtxoEntryOwnedBy(output, address) {
+			balanceChange.Add(balanceChange, output.value)
+		}
+	}
+
+	if balanceChange.Sign() < 0 {
+		return currencies.TransactionsHistoryItem{
+			From: address,
+			To: findUtxoCounterparty(outputs, address),
+			Amount: balanceChange.Neg(balanceChange),
+			Time: txTime,
+		}
+	} else {
+		return currencies.TransactionsHistoryItem{
+			From: findUtxoCounterparty(inputs, address),
+			To: address,
+			Amount: balanceChange,
+			Time: txTime,
+		}
+	}
+}
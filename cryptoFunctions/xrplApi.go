@@ -0,0 +1,108 @@
+package cryptoFunctions
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+)
+
+// JSON-RPC API of rippled servers, public ones or a self-hosted node
+
+type XrplAccountData struct {
+	// in drops
+	Balance string `json:"Balance"`
+}
+
+type XrplAccountInfoResult struct {
+	AccountData XrplAccountData `json:"account_data"`
+	Status string `json:"status"`
+	Error string `json:"error"`
+}
+
+type XrplAccountInfoResp struct {
+	Result XrplAccountInfoResult `json:"result"`
+}
+
+// the error that rippled returns for accounts that were never funded
+const xrplAccountNotFoundError string = "actNotFound"
+
+type xrplBalanceProvider struct {
+	apiUrl string
+}
+
+func makeXrplBalanceProvider(currency currencies.Currency, url string) BalanceProvider {
+	if currency != currencies.RippleXrp {
+		return nil
+	}
+
+	if url == "" {
+		url = "https://xrplcluster.com/"
+	}
+
+	return &xrplBalanceProvider{
+		apiUrl: url,
+	}
+}
+
+func (provider *xrplBalanceProvider) GetName() string {
+	return xrplProvider
+}
+
+func (provider *xrplBalanceProvider) getBalance(address currencies.AddressData) (*big.Int, error) {
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"method": "account_info",
+		"params": []interface{}{
+			map[string]string{"account": address.Address, "ledger_index": "validated"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest("POST", provider.apiUrl, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	body, err := fetchRequestResponseBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseXrplAccountInfo(body)
+}
+
+// accounts that don't exist yet have zero balance
+func parseXrplAccountInfo(body []byte) (*big.Int, error) {
+	var parsedResp = new(XrplAccountInfoResp)
+	err := json.Unmarshal(body, &parsedResp)
+	if err != nil {
+		log.Print(string(body[:]))
+		return nil, err
+	}
+
+	result := parsedResp.Result
+	if result.Error == xrplAccountNotFoundError {
+		return big.NewInt(0), nil
+	}
+
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s responded with %s", xrplProvider, result.Error)
+	}
+
+	balance, ok := new(big.Int).SetString(result.AccountData.Balance, 10)
+	if !ok {
+		return nil, fmt.Errorf("can't parse the balance \"%s\"", result.AccountData.Balance)
+	}
+
+	return balance, nil
+}
+
+func (provider *xrplBalanceProvider) GetBalanceBunch(addresses []currencies.AddressData) ([]*big.Int, error) {
+	return getBalancesConcurrently(xrplProvider, addresses, provider.getBalance)
+}
@@ -4,14 +4,58 @@ import (
 	"math/big"
 )
 
+type BalanceNotifyDirection int8
+
+const (
+	BalanceNotifyBoth BalanceNotifyDirection = 0
+	BalanceNotifyIncoming BalanceNotifyDirection = 1
+	BalanceNotifyOutgoing BalanceNotifyDirection = 2
+)
+
+type BalanceNotifySettings struct {
+	Direction BalanceNotifyDirection
+	// nil if every change should be reported
+	MinChange *big.Float
+	// empty if MinChange is in coin units
+	MinChangeFiat string
+}
+
 type BalanceNotify struct {
 	// filled from DB
 	NotifyId int64
 	WalletId int64
 	UserId int64
 	OldBalance *big.Int
+	BalanceNotifySettings
 	// filled in processing
 	IsInitialChange bool // if true, we don't need to show this notification
 	WalletAddress AddressData
 	NewBalance *big.Int
 }
+
+// diff is the absolute value of the change in coin units
+// rate is the price of one coin in MinChangeFiat, nil if unknown
+func (settings *BalanceNotifySettings) IsChangeReported(isIncoming bool, diff *big.Float, rate *big.Float) bool {
+	if isIncoming && settings.Direction == BalanceNotifyOutgoing {
+		return false
+	}
+
+	if !isIncoming && settings.Direction == BalanceNotifyIncoming {
+		return false
+	}
+
+	if settings.MinChange == nil || diff == nil {
+		return true
+	}
+
+	if settings.MinChangeFiat == "" {
+		return diff.Cmp(settings.MinChange) >= 0
+	}
+
+	// better to send an extra notification than to miss an important one
+	if rate == nil {
+		return true
+	}
+
+	return new(big.Float).Mul(diff, rate).Cmp(settings.MinChange) >= 0
+}
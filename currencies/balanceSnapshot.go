@@ -0,0 +1,16 @@
+package currencies
+
+import (
+	"math/big"
+	"time"
+)
+
+// balance of a wallet and the rate of its coin at some moment
+type BalanceSnapshot struct {
+	WalletId int64
+	Time time.Time
+	Balance *big.Int
+	// price of one coin in Fiat, nil if unknown
+	Rate *big.Float
+	Fiat string
+}
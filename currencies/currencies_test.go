@@ -2,7 +2,9 @@ package currencies
 
 import (
 	"github.com/stretchr/testify/require"
+	"math/big"
 	"testing"
+	"time"
 )
 
 func TestGetSymbolAndName(t *testing.T) {
@@ -16,4 +18,338 @@ func TestGetSymbolAndName(t *testing.T) {
 
 	btcName := GetCurrencyFullName(Bitcoin)
 	assert.Equal("Bitcoin", btcName)
+
+	ltcSymbol := GetCurrencySymbol(Litecoin)
+	assert.Equal("LTC", ltcSymbol)
+}
+
+func TestGetCurrencyBySymbol(t *testing.T) {
+	assert := require.New(t)
+
+	currency, ok := GetCurrencyBySymbol("btc")
+	assert.True(ok)
+	assert.Equal(Bitcoin, currency)
+
+	currency, ok = GetCurrencyBySymbol("XRP")
+	assert.True(ok)
+	assert.Equal(RippleXrp, currency)
+
+	_, ok = GetCurrencyBySymbol("")
+	assert.False(ok)
+
+	_, ok = GetCurrencyBySymbol("ABC")
+	assert.False(ok)
+}
+
+func TestTransactionLinks(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Equal("https://etherscan.io/tx/0x2f1a", GetTransactionExplorerUrl(Ether, "0x2f1a"))
+	assert.Equal("https://etherscan.io/tx/0x2f1a", GetTransactionExplorerUrl(Erc20Token, "0x2f1a"))
+	assert.Equal("https://blockchair.com/litecoin/transaction/ab12", GetTransactionExplorerUrl(Litecoin, "ab12"))
+
+	assert.Equal(Ether, GetFeeCurrency(Erc20Token))
+	assert.Equal(Bitcoin, GetFeeCurrency(Bitcoin))
+}
+
+func TestFiatCurrencies(t *testing.T) {
+	assert := require.New(t)
+
+	assert.True(IsFiatCurrencySupported(DefaultFiatCurrency))
+	assert.True(IsFiatCurrencySupported("eur"))
+	assert.False(IsFiatCurrencySupported("EUR"))
+	assert.False(IsFiatCurrencySupported("btc"))
+	assert.False(IsFiatCurrencySupported(""))
+
+	assert.Equal("EUR", GetFiatCurrencyCode("eur"))
+
+	// the list can't be changed from outside
+	fiats := GetAllFiatCurrencies()
+	fiats[0] = "btc"
+	assert.Equal(DefaultFiatCurrency, GetAllFiatCurrencies()[0])
+}
+
+func TestPriceAlertTriggering(t *testing.T) {
+	assert := require.New(t)
+
+	now := time.Unix(1536900000, 0)
+
+	aboveAlert := PriceAlert{
+		Direction: PriceAlertAbove,
+		Threshold: big.NewFloat(7000),
+	}
+
+	assert.True(aboveAlert.IsOneShot())
+	assert.False(aboveAlert.IsTriggered(big.NewFloat(6999.99), now))
+	assert.True(aboveAlert.IsTriggered(big.NewFloat(7000), now))
+	assert.True(aboveAlert.IsTriggered(big.NewFloat(7100), now))
+	assert.False(aboveAlert.IsTriggered(nil, now))
+
+	belowAlert := PriceAlert{
+		Direction: PriceAlertBelow,
+		Threshold: big.NewFloat(0.3),
+		Cooldown: time.Hour,
+	}
+
+	assert.False(belowAlert.IsOneShot())
+	assert.True(belowAlert.IsTriggered(big.NewFloat(0.29), now))
+	assert.False(belowAlert.IsTriggered(big.NewFloat(0.31), now))
+
+	// repeating alerts wait for the cooldown
+	belowAlert.LastTriggerTime = now.Add(-30 * time.Minute)
+	assert.False(belowAlert.IsTriggered(big.NewFloat(0.29), now))
+	belowAlert.LastTriggerTime = now.Add(-time.Hour)
+	assert.True(belowAlert.IsTriggered(big.NewFloat(0.29), now))
+}
+
+func TestBalanceNotifyFilters(t *testing.T) {
+	assert := require.New(t)
+
+	anyChange := BalanceNotifySettings{}
+	assert.True(anyChange.IsChangeReported(true, big.NewFloat(0.0001), nil))
+	assert.True(anyChange.IsChangeReported(false, big.NewFloat(0.0001), nil))
+
+	incomingOnly := BalanceNotifySettings{
+		Direction: BalanceNotifyIncoming,
+	}
+	assert.True(incomingOnly.IsChangeReported(true, big.NewFloat(1), nil))
+	assert.False(incomingOnly.IsChangeReported(false, big.NewFloat(1), nil))
+
+	outgoingOnly := BalanceNotifySettings{
+		Direction: BalanceNotifyOutgoing,
+	}
+	assert.False(outgoingOnly.IsChangeReported(true, big.NewFloat(1), nil))
+	assert.True(outgoingOnly.IsChangeReported(false, big.NewFloat(1), nil))
+
+	minCoins := BalanceNotifySettings{
+		MinChange: big.NewFloat(0.01),
+	}
+	assert.False(minCoins.IsChangeReported(true, big.NewFloat(0.005), big.NewFloat(6000)))
+	assert.True(minCoins.IsChangeReported(true, big.NewFloat(0.01), nil))
+
+	minFiat := BalanceNotifySettings{
+		Direction: BalanceNotifyIncoming,
+		MinChange: big.NewFloat(5),
+		MinChangeFiat: "usd",
+	}
+	assert.False(minFiat.IsChangeReported(true, big.NewFloat(0.0005), big.NewFloat(6000)))
+	assert.True(minFiat.IsChangeReported(true, big.NewFloat(0.001), big.NewFloat(6000)))
+	assert.False(minFiat.IsChangeReported(false, big.NewFloat(1), big.NewFloat(6000)))
+	// unknown rate shouldn't hide the notification
+	assert.True(minFiat.IsChangeReported(true, big.NewFloat(0.0005), nil))
+}
+
+func TestDigestSchedule(t *testing.T) {
+	assert := require.New(t)
+
+	location := time.FixedZone("UTC+3", 3 * 60 * 60)
+	// Wednesday, 12 September 2018, 10:00 local time
+	now := time.Date(2018, time.September, 12, 10, 0, 0, 0, location)
+
+	daily := Digest{
+		DigestSettings: DigestSettings{
+			Period: DigestDaily,
+			Hour: 9,
+		},
+	}
+	assert.Equal(time.Date(2018, time.September, 12, 9, 0, 0, 0, location), daily.GetLastScheduledTime(now, location))
+	assert.True(daily.IsDue(now, location))
+
+	daily.LastSentTime = time.Date(2018, time.September, 12, 9, 1, 0, 0, location)
+	assert.False(daily.IsDue(now, location))
+	assert.True(daily.IsDue(now.Add(23 * time.Hour), location))
+
+	// the scheduled time of today hasn't come yet
+	daily.Hour = 11
+	assert.Equal(time.Date(2018, time.September, 11, 11, 0, 0, 0, location), daily.GetLastScheduledTime(now, location))
+	daily.LastSentTime = now.Add(-time.Hour)
+	assert.False(daily.IsDue(now, location))
+
+	weekly := Digest{
+		DigestSettings: DigestSettings{
+			Period: DigestWeekly,
+			Hour: 9,
+		},
+		LastSentTime: time.Date(2018, time.September, 10, 9, 0, 30, 0, location),
+	}
+	assert.Equal(time.Date(2018, time.September, 10, 9, 0, 0, 0, location), weekly.GetLastScheduledTime(now, location))
+	assert.False(weekly.IsDue(now, location))
+	assert.True(weekly.IsDue(time.Date(2018, time.September, 17, 9, 0, 0, 0, location), location))
+
+	// the time is compared in the user's timezone
+	assert.False(weekly.IsDue(time.Date(2018, time.September, 17, 5, 30, 0, 0, time.UTC), location))
+
+	disabled := Digest{}
+	assert.False(disabled.IsDue(now, location))
+}
+
+func TestDigestChanges(t *testing.T) {
+	assert := require.New(t)
+
+	previousCoins := []DigestCoin{
+		DigestCoin{CoinKey: "btc", Balance: big.NewFloat(1), Value: big.NewFloat(6000)},
+		DigestCoin{CoinKey: "eth", Balance: big.NewFloat(10), Value: big.NewFloat(2000)},
+		DigestCoin{CoinKey: "ltc", Balance: big.NewFloat(3), Value: nil},
+	}
+
+	coins := []DigestCoin{
+		DigestCoin{CoinKey: "btc", Balance: big.NewFloat(1.5), Value: big.NewFloat(9000)},
+		DigestCoin{CoinKey: "eth", Balance: big.NewFloat(10), Value: big.NewFloat(1900)},
+		DigestCoin{CoinKey: "ltc", Balance: big.NewFloat(3), Value: big.NewFloat(150)},
+		DigestCoin{CoinKey: "xrp", Balance: big.NewFloat(100), Value: big.NewFloat(30)},
+	}
+
+	changes := MakeDigestChanges(coins, previousCoins)
+	assert.Equal(4, len(changes))
+	assert.Equal("0.5", changes[0].BalanceDiff.Text('f', -1))
+	assert.Equal("3000", changes[0].ValueDiff.Text('f', -1))
+	assert.Equal("0", changes[1].BalanceDiff.Text('f', -1))
+	assert.Equal("-100", changes[1].ValueDiff.Text('f', -1))
+	assert.True(changes[2].ValueDiff == nil)
+	assert.True(changes[3].BalanceDiff == nil)
+	assert.True(changes[3].ValueDiff == nil)
+
+	movers := GetBiggestMovers(changes, 3)
+	assert.Equal(2, len(movers))
+	assert.Equal("btc", movers[0].CoinKey)
+	assert.Equal("eth", movers[1].CoinKey)
+
+	assert.Equal(1, len(GetBiggestMovers(changes, 1)))
+}
+
+func TestIncompleteDigestCoins(t *testing.T) {
+	assert := require.New(t)
+
+	previousCoins := []DigestCoin{
+		DigestCoin{CoinKey: "btc", Balance: big.NewFloat(1), Value: big.NewFloat(6000)},
+		DigestCoin{CoinKey: "eth", Balance: big.NewFloat(10), Value: big.NewFloat(2000)},
+	}
+
+	coins := []DigestCoin{
+		DigestCoin{CoinKey: "btc", Balance: big.NewFloat(0.5), Value: big.NewFloat(3000), IsIncomplete: true},
+		DigestCoin{CoinKey: "eth", Balance: big.NewFloat(11), Value: big.NewFloat(2200)},
+		DigestCoin{CoinKey: "ltc", IsIncomplete: true},
+	}
+
+	changes := MakeDigestChanges(coins, previousCoins)
+	assert.Equal(3, len(changes))
+	assert.True(changes[0].BalanceDiff == nil)
+	assert.True(changes[0].ValueDiff == nil)
+	assert.Equal("1", changes[1].BalanceDiff.Text('f', -1))
+	assert.True(changes[2].BalanceDiff == nil)
+
+	// the incomplete balances are not remembered
+	remembered := ReplaceIncompleteDigestCoins(coins, previousCoins)
+	assert.Equal(2, len(remembered))
+	assert.Equal("1", remembered[0].Balance.Text('f', -1))
+	assert.Equal("11", remembered[1].Balance.Text('f', -1))
+}
+
+func TestQuietHours(t *testing.T) {
+	assert := require.New(t)
+
+	location := time.FixedZone("UTC+3", 3 * 60 * 60)
+	timeAt := func(hour int, minute int) time.Time {
+		return time.Date(2018, time.September, 12, hour, minute, 0, 0, location)
+	}
+
+	night := QuietHours{StartHour: 23, EndHour: 8}
+	assert.True(night.IsEnabled())
+	assert.True(night.IsQuietTime(timeAt(23, 0), location))
+	assert.True(night.IsQuietTime(timeAt(3, 30), location))
+	assert.True(night.IsQuietTime(timeAt(7, 59), location))
+	assert.False(night.IsQuietTime(timeAt(8, 0), location))
+	assert.False(night.IsQuietTime(timeAt(22, 59), location))
+
+	// the hours are checked in the user's timezone
+	assert.True(night.IsQuietTime(time.Date(2018, time.September, 12, 1, 0, 0, 0, time.UTC), location))
+	assert.False(night.IsQuietTime(time.Date(2018, time.September, 12, 6, 0, 0, 0, time.UTC), location))
+
+	day := QuietHours{StartHour: 13, EndHour: 15}
+	assert.False(day.IsQuietTime(timeAt(12, 59), location))
+	assert.True(day.IsQuietTime(timeAt(14, 0), location))
+	assert.False(day.IsQuietTime(timeAt(15, 0), location))
+
+	disabled := QuietHours{}
+	assert.False(disabled.IsEnabled())
+	assert.False(disabled.IsQuietTime(timeAt(0, 0), location))
+}
+
+func TestProfitLoss(t *testing.T) {
+	assert := require.New(t)
+
+	startTime := time.Unix(1536900000, 0)
+	makeLot := func(isSell bool, amount float64, price float64, days int) TradeLot {
+		return TradeLot{
+			IsSell: isSell,
+			Amount: big.NewFloat(amount),
+			Price: big.NewFloat(price),
+			Time: startTime.AddDate(0, 0, days),
+		}
+	}
+
+	// the order of the lots doesn't matter, only their time
+	lots := []TradeLot{
+		makeLot(true, 1.5, 400, 2),
+		makeLot(false, 1, 100, 0),
+		makeLot(false, 1, 300, 1),
+	}
+
+	fifo := CalculateProfitLoss(lots, CostBasisFifo, big.NewFloat(500))
+	assert.Equal("0.5", fifo.Holding.Text('f', -1))
+	assert.Equal("150", fifo.CostBasis.Text('f', -1))
+	assert.Equal("350", fifo.Realized.Text('f', -1))
+	assert.Equal("100", fifo.Unrealized.Text('f', -1))
+	assert.Equal(0, fifo.UncoveredAmount.Sign())
+	assert.Equal(2, len(fifo.Disposals))
+	assert.Equal(startTime, fifo.Disposals[0].AcquisitionTime)
+	assert.Equal("300", fifo.Disposals[0].GetGain().Text('f', -1))
+
+	lifo := CalculateProfitLoss(lots, CostBasisLifo, big.NewFloat(500))
+	assert.Equal("50", lifo.CostBasis.Text('f', -1))
+	assert.Equal("250", lifo.Realized.Text('f', -1))
+	assert.Equal("200", lifo.Unrealized.Text('f', -1))
+
+	// the prices are different from the order of the purchases
+	hifoLots := append(lots, makeLot(false, 1, 200, 1))
+	hifo := CalculateProfitLoss(hifoLots, CostBasisHifo, nil)
+	assert.Equal("1.5", hifo.Holding.Text('f', -1))
+	assert.Equal("200", hifo.CostBasis.Text('f', -1))
+	assert.Equal("200", hifo.Realized.Text('f', -1))
+	assert.Equal(startTime.AddDate(0, 0, 1), hifo.Disposals[0].AcquisitionTime)
+	assert.Equal("300", hifo.Disposals[0].CostBasis.Text('f', -1))
+
+	average := CalculateProfitLoss(lots, CostBasisAverage, nil)
+	assert.Equal("0.5", average.Holding.Text('f', -1))
+	assert.Equal("100", average.CostBasis.Text('f', -1))
+	assert.Equal("300", average.Realized.Text('f', -1))
+	assert.Nil(average.Unrealized)
+	assert.Equal(1, len(average.Disposals))
+	assert.True(average.Disposals[0].AcquisitionTime.IsZero())
+
+	// selling more than was bought
+	oversold := CalculateProfitLoss(append(lots, makeLot(true, 1, 500, 3)), CostBasisFifo, nil)
+	assert.Equal(0, oversold.Holding.Sign())
+	assert.Equal("0.5", oversold.UncoveredAmount.Text('f', -1))
+	assert.Equal("450", oversold.Realized.Text('f', -1))
+	// the uncovered coins are still disposed, but without the cost basis
+	assert.Equal(4, len(oversold.Disposals))
+	assert.True(oversold.Disposals[3].IsUncovered())
+	assert.Equal("0.5", oversold.Disposals[3].Amount.Text('f', -1))
+	assert.Equal("250", oversold.Disposals[3].Proceeds.Text('f', -1))
+	assert.Nil(oversold.Disposals[3].GetGain())
+	assert.False(oversold.Disposals[2].IsUncovered())
+
+	averageOversold := CalculateProfitLoss(append(lots, makeLot(true, 1, 500, 3)), CostBasisAverage, nil)
+	assert.Equal("0.5", averageOversold.UncoveredAmount.Text('f', -1))
+	assert.True(averageOversold.Disposals[len(averageOversold.Disposals) - 1].IsUncovered())
+}
+
+func TestEthereumAddressNormalization(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Equal("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", NormalizeAddress(Ether, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"))
+	assert.Equal("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", NormalizeAddress(Erc20Token, "0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED"))
+	assert.Equal("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", NormalizeAddress(Bitcoin, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"))
+	assert.Equal("not an address", NormalizeAddress(Ether, "not an address"))
 }
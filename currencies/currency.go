@@ -1,7 +1,9 @@
 package currencies
 
 import (
+	"fmt"
 	"log"
+	"strings"
 )
 
 type Currency int8
@@ -15,6 +17,7 @@ const (
 	BitcoinGold Currency = 3
 	RippleXrp Currency = 4
 	Erc20Token Currency = 5
+	Litecoin Currency = 6
 )
 
 type currencyStaticData struct {
@@ -22,6 +25,8 @@ type currencyStaticData struct {
 	Symbol string
 	Decimals int // how many decimal digits after zero can it have
 	PriceId string
+	// format of a link to the transaction in a block explorer
+	TransactionExplorerUrl string
 	// feature flags
 	IsHistoryEnabled bool
 }
@@ -35,13 +40,15 @@ func init() {
 			Symbol: "BTC",
 			Decimals: 8,
 			PriceId: "bitcoin",
-			IsHistoryEnabled: false,
+			TransactionExplorerUrl: "https://blockchair.com/bitcoin/transaction/%s",
+			IsHistoryEnabled: true,
 		},
 		Ether : {
 			FullName: "Ethereum",
 			Symbol: "ETH",
 			Decimals: 18,
 			PriceId: "ethereum",
+			TransactionExplorerUrl: "https://etherscan.io/tx/%s",
 			IsHistoryEnabled: true,
 		},
 		BitcoinCash : {
@@ -49,28 +56,40 @@ func init() {
 			Symbol: "BCH",
 			Decimals: 8,
 			PriceId: "bitcoin-cash",
-			IsHistoryEnabled: false,
+			TransactionExplorerUrl: "https://blockchair.com/bitcoin-cash/transaction/%s",
+			IsHistoryEnabled: true,
 		},
 		BitcoinGold : {
 			FullName: "Bitcoin Gold",
 			Symbol: "BTG",
 			Decimals: 8,
 			PriceId: "bitcoin-gold",
-			IsHistoryEnabled: false,
+			TransactionExplorerUrl: "https://explorer.bitcoingold.org/insight/tx/%s",
+			IsHistoryEnabled: true,
 		},
 		RippleXrp : {
 			FullName: "XRP",
 			Symbol: "XRP",
 			Decimals: 6,
 			PriceId: "ripple",
-			IsHistoryEnabled: false,
+			TransactionExplorerUrl: "https://xrpcharts.ripple.com/#/transactions/%s",
+			IsHistoryEnabled: true,
 		},
 		Erc20Token : {
 			FullName: "ERC20 Token",
 			Symbol: "",
 			Decimals: 18,
 			PriceId: "",
-			IsHistoryEnabled: false,
+			TransactionExplorerUrl: "https://etherscan.io/tx/%s",
+			IsHistoryEnabled: true,
+		},
+		Litecoin : {
+			FullName: "Litecoin",
+			Symbol: "LTC",
+			Decimals: 8,
+			PriceId: "litecoin",
+			TransactionExplorerUrl: "https://blockchair.com/litecoin/transaction/%s",
+			IsHistoryEnabled: true,
 		},
 	}
 }
@@ -119,6 +138,35 @@ func GetCurrencyDecimals(currency Currency) int {
 	return currencyData.Decimals
 }
 
+func GetTransactionExplorerUrl(currency Currency, hash string) string {
+	currencyData, ok := currencyStaticDataMap[currency]
+
+	if !ok {
+		log.Printf("Unknown currency: %d ", int8(currency))
+		return ""
+	}
+
+	return fmt.Sprintf(currencyData.TransactionExplorerUrl, hash)
+}
+
+// tokens don't have a symbol of their own, so they are never found
+func GetCurrencyBySymbol(symbol string) (currency Currency, ok bool) {
+	for currency, currencyData := range currencyStaticDataMap {
+		if currencyData.Symbol != "" && strings.EqualFold(currencyData.Symbol, symbol) {
+			return currency, true
+		}
+	}
+	return
+}
+
+// tokens don't have their own blockchain, so the fees are paid in the currency of the chain
+func GetFeeCurrency(currency Currency) Currency {
+	if currency == Erc20Token {
+		return Ether
+	}
+	return currency
+}
+
 func IsHistoryEnabled(currency Currency) bool {
 	currencyData, ok := currencyStaticDataMap[currency]
 
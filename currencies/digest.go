@@ -0,0 +1,147 @@
+package currencies
+
+import (
+	"math/big"
+	"sort"
+	"time"
+)
+
+type DigestPeriod int8
+
+const (
+	DigestDisabled DigestPeriod = 0
+	DigestDaily DigestPeriod = 1
+	// weekly digests are sent on Mondays
+	DigestWeekly DigestPeriod = 2
+)
+
+// local hour of the user to send digests at if the user didn't choose one
+const DefaultDigestHour int = 9
+
+type DigestSettings struct {
+	Period DigestPeriod
+	// hour of the day in the user's timezone
+	Hour int
+}
+
+type Digest struct {
+	UserId int64
+	DigestSettings
+	LastSentTime time.Time
+}
+
+// summarized balance of one coin at the moment a digest was made
+type DigestCoin struct {
+	// identifies the same coin between digests
+	CoinKey string
+	Symbol string
+	// nil if none of the balances of the coin are known
+	Balance *big.Float
+	// cost in the user's fiat currency, nil if unknown
+	Value *big.Float
+	// some balances of the coin are unknown, the balance and the cost include only the known ones
+	IsIncomplete bool
+}
+
+type DigestCoinChange struct {
+	DigestCoin
+	// nil if the coin is not found in the previous digest
+	BalanceDiff *big.Float
+	// nil if the cost is unknown now or in the previous digest
+	ValueDiff *big.Float
+}
+
+// the latest moment not after now when the digest should have been sent
+func (settings *DigestSettings) GetLastScheduledTime(now time.Time, location *time.Location) time.Time {
+	localNow := now.In(location)
+	scheduledTime := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), settings.Hour, 0, 0, 0, location)
+
+	if scheduledTime.After(localNow) {
+		scheduledTime = scheduledTime.AddDate(0, 0, -1)
+	}
+
+	if settings.Period == DigestWeekly {
+		for scheduledTime.Weekday() != time.Monday {
+			scheduledTime = scheduledTime.AddDate(0, 0, -1)
+		}
+	}
+
+	return scheduledTime
+}
+
+// true if the scheduled time passed after the last digest had been sent
+// if a few scheduled times were missed (e.g. the bot was down) only one digest is due
+func (digest *Digest) IsDue(now time.Time, location *time.Location) bool {
+	if digest.Period == DigestDisabled {
+		return false
+	}
+
+	return digest.LastSentTime.Before(digest.GetLastScheduledTime(now, location))
+}
+
+func MakeDigestChanges(coins []DigestCoin, previousCoins []DigestCoin) (changes []DigestCoinChange) {
+	previousCoinsMap := make(map[string]DigestCoin)
+	for _, previousCoin := range previousCoins {
+		previousCoinsMap[previousCoin.CoinKey] = previousCoin
+	}
+
+	for _, coin := range coins {
+		change := DigestCoinChange{
+			DigestCoin: coin,
+		}
+
+		// a partly known balance would show a change that didn't happen
+		previousCoin, ok := previousCoinsMap[coin.CoinKey]
+		if ok && !coin.IsIncomplete {
+			if coin.Balance != nil && previousCoin.Balance != nil {
+				change.BalanceDiff = new(big.Float).Sub(coin.Balance, previousCoin.Balance)
+			}
+
+			if coin.Value != nil && previousCoin.Value != nil {
+				change.ValueDiff = new(big.Float).Sub(coin.Value, previousCoin.Value)
+			}
+		}
+
+		changes = append(changes, change)
+	}
+	return
+}
+
+// incomplete coins are replaced with their previous values, so the next digest is compared with the known balances
+func ReplaceIncompleteDigestCoins(coins []DigestCoin, previousCoins []DigestCoin) (result []DigestCoin) {
+	previousCoinsMap := make(map[string]DigestCoin)
+	for _, previousCoin := range previousCoins {
+		previousCoinsMap[previousCoin.CoinKey] = previousCoin
+	}
+
+	for _, coin := range coins {
+		if coin.IsIncomplete {
+			previousCoin, ok := previousCoinsMap[coin.CoinKey]
+			if !ok {
+				continue
+			}
+			coin = previousCoin
+		}
+
+		result = append(result, coin)
+	}
+	return
+}
+
+// coins with the biggest changes of the cost, the coins with unknown or zero changes are skipped
+func GetBiggestMovers(changes []DigestCoinChange, count int) (movers []DigestCoinChange) {
+	for _, change := range changes {
+		if change.ValueDiff != nil && change.ValueDiff.Sign() != 0 {
+			movers = append(movers, change)
+		}
+	}
+
+	sort.SliceStable(movers, func(i, j int) bool {
+		return new(big.Float).Abs(movers[i].ValueDiff).Cmp(new(big.Float).Abs(movers[j].ValueDiff)) > 0
+	})
+
+	if len(movers) > count {
+		movers = movers[:count]
+	}
+	return
+}
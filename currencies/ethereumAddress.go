@@ -0,0 +1,40 @@
+package currencies
+
+import (
+	"encoding/hex"
+	"golang.org/x/crypto/sha3"
+	"regexp"
+	"strings"
+)
+
+// hex form of an address, the checksum is not checked
+var EthereumAddressRegex *regexp.Regexp = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
+
+// returns the address in EIP-55 mixed-case checksum form, the address should be a valid hex address
+func ToEthereumChecksumAddress(address string) string {
+	hexAddress := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X"))
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(hexAddress))
+	hashHex := hex.EncodeToString(hash.Sum(nil))
+
+	result := []byte(hexAddress)
+	for i, char := range result {
+		if char >= 'a' && char <= 'f' && hashHex[i] >= '8' {
+			result[i] = char - 'a' + 'A'
+		}
+	}
+
+	return "0x" + string(result)
+}
+
+// converts a valid address to the form it is stored in, so the same address typed differently is the same wallet
+func NormalizeAddress(currency Currency, address string) string {
+	switch currency {
+	case Ether, Erc20Token:
+		if EthereumAddressRegex.MatchString(address) {
+			return ToEthereumChecksumAddress(address)
+		}
+	}
+	return address
+}
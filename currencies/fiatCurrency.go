@@ -0,0 +1,35 @@
+package currencies
+
+import (
+	"strings"
+)
+
+// fiat currencies are identified by lowercase ISO 4217 codes, the same way the rate providers do it
+const DefaultFiatCurrency string = "usd"
+
+// the order is the order of the buttons in the settings
+var fiatCurrencies []string = []string{
+	"usd", "eur", "gbp",
+	"chf", "pln", "czk",
+	"sek", "nok", "dkk",
+	"rub", "uah", "try",
+	"jpy", "cny", "krw",
+	"cad", "aud", "inr",
+}
+
+func GetAllFiatCurrencies() []string {
+	return append([]string{}, fiatCurrencies...)
+}
+
+func IsFiatCurrencySupported(fiat string) bool {
+	for _, supportedFiat := range fiatCurrencies {
+		if supportedFiat == fiat {
+			return true
+		}
+	}
+	return false
+}
+
+func GetFiatCurrencyCode(fiat string) string {
+	return strings.ToUpper(fiat)
+}
@@ -0,0 +1,54 @@
+package currencies
+
+import (
+	"math/big"
+	"time"
+)
+
+type PriceAlertDirection int8
+
+const (
+	// don't change already assigned numbers, they are stored in the DB
+	PriceAlertAbove PriceAlertDirection = 0
+	PriceAlertBelow PriceAlertDirection = 1
+)
+
+type PriceAlert struct {
+	// filled from DB
+	AlertId int64
+	UserId int64
+	PriceId string
+	Fiat string
+	Direction PriceAlertDirection
+	Threshold *big.Float
+	// zero for one-shot alerts that are removed after they are sent
+	Cooldown time.Duration
+	// zero time if the alert has never been sent
+	LastTriggerTime time.Time
+	// filled in processing
+	Rate *big.Float
+}
+
+func (alert *PriceAlert) IsOneShot() bool {
+	return alert.Cooldown <= 0
+}
+
+// checks if the alert should be sent for the rate at the given time
+func (alert *PriceAlert) IsTriggered(rate *big.Float, now time.Time) bool {
+	if rate == nil || alert.Threshold == nil {
+		return false
+	}
+
+	if !alert.IsOneShot() && !alert.LastTriggerTime.IsZero() && now.Sub(alert.LastTriggerTime) < alert.Cooldown {
+		return false
+	}
+
+	switch alert.Direction {
+	case PriceAlertAbove:
+		return rate.Cmp(alert.Threshold) >= 0
+	case PriceAlertBelow:
+		return rate.Cmp(alert.Threshold) <= 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,30 @@
+package currencies
+
+import (
+	"time"
+)
+
+// hours of the day in the user's timezone when the notifications are not sent
+// the period can pass midnight, e.g. from 23 to 8
+type QuietHours struct {
+	StartHour int
+	EndHour int
+}
+
+func (quietHours QuietHours) IsEnabled() bool {
+	return quietHours.StartHour != quietHours.EndHour
+}
+
+func (quietHours QuietHours) IsQuietTime(now time.Time, location *time.Location) bool {
+	if !quietHours.IsEnabled() {
+		return false
+	}
+
+	hour := now.In(location).Hour()
+
+	if quietHours.StartHour < quietHours.EndHour {
+		return hour >= quietHours.StartHour && hour < quietHours.EndHour
+	} else {
+		return hour >= quietHours.StartHour || hour < quietHours.EndHour
+	}
+}
@@ -0,0 +1,228 @@
+package currencies
+
+import (
+	"math/big"
+	"sort"
+	"time"
+)
+
+type CostBasisMethod int8
+
+const (
+	// don't change already assigned numbers, they are stored in the DB
+	CostBasisFifo CostBasisMethod = 0
+	CostBasisLifo CostBasisMethod = 1
+	CostBasisAverage CostBasisMethod = 2
+	CostBasisHifo CostBasisMethod = 3
+)
+
+// a purchase or a sale of coins of one wallet
+type TradeLot struct {
+	LotId int64
+	WalletId int64
+	IsSell bool
+	// in coins, always positive
+	Amount *big.Float
+	// price of one coin
+	Price *big.Float
+	Fiat string
+	Time time.Time
+	// hash of the transaction the lot is attached to, empty for the lots added manually
+	TransactionHash string
+}
+
+// a part of a sale matched with the purchase it was paid for
+type Disposal struct {
+	// zero time if the cost is averaged over many purchases or unknown
+	AcquisitionTime time.Time
+	DisposalTime time.Time
+	Amount *big.Float
+	Proceeds *big.Float
+	// nil if the coins were sold over the recorded purchases
+	CostBasis *big.Float
+}
+
+// the sold coins were not bought in the recorded purchases, so the cost basis is unknown
+func (disposal *Disposal) IsUncovered() bool {
+	return disposal.CostBasis == nil
+}
+
+// nil if the cost basis is unknown
+func (disposal *Disposal) GetGain() *big.Float {
+	if disposal.IsUncovered() {
+		return nil
+	}
+	return new(big.Float).Sub(disposal.Proceeds, disposal.CostBasis)
+}
+
+type ProfitLoss struct {
+	// coins left from the purchases
+	Holding *big.Float
+	// what was paid for the coins that are left
+	CostBasis *big.Float
+	Realized *big.Float
+	// nil if the rate is unknown
+	Unrealized *big.Float
+	// coins sold over the recorded purchases, they are not counted in the realized profit
+	// but they are in the disposals with unknown cost basis
+	UncoveredAmount *big.Float
+	Disposals []Disposal
+}
+
+type heldLot struct {
+	time time.Time
+	amount *big.Float
+	price *big.Float
+}
+
+// all the lots should be in the same fiat currency as the rate, the rate can be nil if unknown
+func CalculateProfitLoss(lots []TradeLot, method CostBasisMethod, rate *big.Float) (result ProfitLoss) {
+	sortedLots := make([]TradeLot, len(lots))
+	copy(sortedLots, lots)
+	sort.SliceStable(sortedLots, func(i, j int) bool {
+		return sortedLots[i].Time.Before(sortedLots[j].Time)
+	})
+
+	result.Realized = new(big.Float)
+	result.UncoveredAmount = new(big.Float)
+
+	held := make([]heldLot, 0)
+
+	for _, lot := range sortedLots {
+		if !lot.IsSell {
+			held = append(held, heldLot{
+				time: lot.Time,
+				amount: new(big.Float).Set(lot.Amount),
+				price: lot.Price,
+			})
+			continue
+		}
+
+		if method == CostBasisAverage {
+			held = sellAverage(held, lot, &result)
+		} else {
+			held = sellMatched(held, lot, method, &result)
+		}
+	}
+
+	result.Holding = new(big.Float)
+	result.CostBasis = new(big.Float)
+	for _, lot := range held {
+		result.Holding.Add(result.Holding, lot.amount)
+		result.CostBasis.Add(result.CostBasis, new(big.Float).Mul(lot.amount, lot.price))
+	}
+
+	if rate != nil {
+		result.Unrealized = new(big.Float).Mul(result.Holding, rate)
+		result.Unrealized.Sub(result.Unrealized, result.CostBasis)
+	}
+
+	return
+}
+
+func addDisposal(result *ProfitLoss, acquisitionTime time.Time, lot TradeLot, amount *big.Float, costBasis *big.Float) {
+	disposal := Disposal{
+		AcquisitionTime: acquisitionTime,
+		DisposalTime: lot.Time,
+		Amount: amount,
+		Proceeds: new(big.Float).Mul(amount, lot.Price),
+		CostBasis: costBasis,
+	}
+	result.Realized.Add(result.Realized, disposal.GetGain())
+	result.Disposals = append(result.Disposals, disposal)
+}
+
+func addUncoveredDisposal(result *ProfitLoss, lot TradeLot, amount *big.Float) {
+	result.UncoveredAmount.Add(result.UncoveredAmount, amount)
+	result.Disposals = append(result.Disposals, Disposal{
+		DisposalTime: lot.Time,
+		Amount: amount,
+		Proceeds: new(big.Float).Mul(amount, lot.Price),
+	})
+}
+
+// index of the purchase the next sold coins are taken from
+func getMatchedLotIndex(held []heldLot, method CostBasisMethod) (index int) {
+	switch method {
+	case CostBasisLifo:
+		return len(held) - 1
+	case CostBasisHifo:
+		for i := range held {
+			if held[i].price.Cmp(held[index].price) > 0 {
+				index = i
+			}
+		}
+		return
+	default:
+		return 0
+	}
+}
+
+// takes the sold coins from the oldest (FIFO), the newest (LIFO) or the most expensive (HIFO) purchases
+func sellMatched(held []heldLot, lot TradeLot, method CostBasisMethod, result *ProfitLoss) []heldLot {
+	left := new(big.Float).Set(lot.Amount)
+
+	for left.Sign() > 0 && len(held) > 0 {
+		index := getMatchedLotIndex(held, method)
+
+		amount := new(big.Float).Set(left)
+		if held[index].amount.Cmp(left) <= 0 {
+			amount.Set(held[index].amount)
+		}
+
+		addDisposal(result, held[index].time, lot, amount, new(big.Float).Mul(amount, held[index].price))
+
+		left.Sub(left, amount)
+		held[index].amount = new(big.Float).Sub(held[index].amount, amount)
+		if held[index].amount.Sign() <= 0 {
+			held = append(held[:index], held[index+1:]...)
+		}
+	}
+
+	if left.Sign() > 0 {
+		addUncoveredDisposal(result, lot, left)
+	}
+	return held
+}
+
+// the sold coins take the average price of all the coins held at the moment
+func sellAverage(held []heldLot, lot TradeLot, result *ProfitLoss) []heldLot {
+	totalAmount := new(big.Float)
+	totalCost := new(big.Float)
+	for _, item := range held {
+		totalAmount.Add(totalAmount, item.amount)
+		totalCost.Add(totalCost, new(big.Float).Mul(item.amount, item.price))
+	}
+
+	if totalAmount.Sign() <= 0 {
+		addUncoveredDisposal(result, lot, new(big.Float).Set(lot.Amount))
+		return held
+	}
+
+	amount := new(big.Float).Set(lot.Amount)
+	uncovered := new(big.Float)
+	if amount.Cmp(totalAmount) > 0 {
+		uncovered.Sub(amount, totalAmount)
+		amount.Set(totalAmount)
+	}
+
+	averagePrice := new(big.Float).Quo(totalCost, totalAmount)
+	addDisposal(result, time.Time{}, lot, amount, new(big.Float).Mul(amount, averagePrice))
+	if uncovered.Sign() > 0 {
+		addUncoveredDisposal(result, lot, uncovered)
+	}
+
+	leftAmount := new(big.Float).Sub(totalAmount, amount)
+	if leftAmount.Sign() <= 0 {
+		return held[:0]
+	}
+
+	// what is left is kept as one lot with the average price
+	return []heldLot{
+		heldLot{
+			time: held[len(held) - 1].time,
+			amount: leftAmount,
+			price: averagePrice,
+		},
+	}
+}
@@ -5,9 +5,22 @@ import (
 	"time"
 )
 
+type TransactionStatus int8
+
+const (
+	TransactionConfirmed TransactionStatus = 0
+	TransactionPending TransactionStatus = 1
+	TransactionFailed TransactionStatus = 2
+)
+
 type TransactionsHistoryItem struct {
 	From string
 	To string
 	Amount *big.Int
 	Time time.Time
+	Hash string
+	Fee *big.Int // nil if unknown
+	BlockHeight int64 // 0 if the transaction is not in a block yet
+	Confirmations int64 // 0 if unknown
+	Status TransactionStatus
 }
@@ -2,6 +2,7 @@ package database
 
 import (
 	"bytes"
+	"database/sql"
 	"fmt"
 	"math/big"
 	_ "github.com/mattn/go-sqlite3"
@@ -11,13 +12,20 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"time"
 )
 
 type AccountDb struct {
 	db dbBase.Database
+	// the same database for the things the skeleton wrapper doesn't support (e.g. transactions)
+	conn *sql.DB
 	mutex sync.Mutex
 }
 
+// how long a write waits for the file lock held by another connection (ms)
+// there are two connections to the same file, so a write can meet the other one's lock
+const sqliteBusyTimeout int = 5000
+
 func init() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 }
@@ -25,9 +33,17 @@ func init() {
 func ConnectDb(path string) (database *AccountDb, err error) {
 	database = &AccountDb{}
 
-	err = database.db.Connect(path)
+	err = database.db.Connect(fmt.Sprintf("%s?_busy_timeout=%d", path, sqliteBusyTimeout))
+
+	if err != nil {
+		return
+	}
+
+	// foreign keys are enabled per connection, so it is done for all the connections of the pool
+	database.conn, err = sql.Open("sqlite3", fmt.Sprintf("%s?_foreign_keys=1&_busy_timeout=%d", path, sqliteBusyTimeout))
 
 	if err != nil {
+		database.db.Disconnect()
 		return
 	}
 
@@ -44,6 +60,8 @@ func ConnectDb(path string) (database *AccountDb, err error) {
 		",chat_id INTEGER UNIQUE NOT NULL" +
 		",language TEXT NOT NULL" +
 		",timezone TEXT NOT NULL" +
+		",fiat_currency TEXT NOT NULL DEFAULT('usd')" +
+		",cost_basis_method INTEGER NOT NULL DEFAULT(0)" + // 0 FIFO, 1 LIFO, 2 average
 		")")
 
 	database.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS" +
@@ -66,12 +84,152 @@ func ConnectDb(path string) (database *AccountDb, err error) {
 		" balance_notifies(id INTEGER NOT NULL PRIMARY KEY" +
 		",wallet_id INTEGER NOT NULL UNIQUE" +
 		",last_balance TEXT NOT NULL" + // always save balances as TEXT
+		",direction INTEGER NOT NULL DEFAULT(0)" + // 0 both, 1 only incoming, 2 only outgoing
+		",min_change TEXT NOT NULL DEFAULT('')" + // empty if any change is reported
+		",min_change_fiat TEXT NOT NULL DEFAULT('')" + // empty if min_change is in coin units
+		",FOREIGN KEY(wallet_id) REFERENCES wallets(id) ON DELETE CASCADE" +
+		")")
+
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" hd_wallet_addresses(id INTEGER NOT NULL PRIMARY KEY" +
+		",wallet_id INTEGER NOT NULL" +
+		",chain INTEGER NOT NULL" + // 0 for receiving addresses, 1 for change
+		",address_index INTEGER NOT NULL" +
+		",address TEXT NOT NULL" +
+		",is_used INTEGER NOT NULL" + // 1 if the address has any transactions
+		",UNIQUE(wallet_id, chain, address_index)" +
 		",FOREIGN KEY(wallet_id) REFERENCES wallets(id) ON DELETE CASCADE" +
 		")")
 
+	createTransactionsTable(database)
+	createPriceAlertsTable(database)
+	createDigestsTables(database)
+	createQuietHoursTables(database)
+	createBalanceSnapshotsTable(database)
+	createTradeLotsTable(database)
+
 	return
 }
 
+func createTradeLotsTable(database *AccountDb) {
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" trade_lots(id INTEGER NOT NULL PRIMARY KEY" +
+		",wallet_id INTEGER NOT NULL" +
+		",is_sell INTEGER NOT NULL" + // 0 for purchases, 1 for sales
+		",amount TEXT NOT NULL" + // in coins
+		",price TEXT NOT NULL" + // price of one coin
+		",fiat_currency TEXT NOT NULL" +
+		",time INTEGER NOT NULL" + // unix timestamp
+		",transaction_hash TEXT" + // NULL for the lots added manually
+		",UNIQUE(wallet_id, transaction_hash)" +
+		",FOREIGN KEY(wallet_id) REFERENCES wallets(id) ON DELETE CASCADE" +
+		")")
+}
+
+func createBalanceSnapshotsTable(database *AccountDb) {
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" balance_snapshots(id INTEGER NOT NULL PRIMARY KEY" +
+		",wallet_id INTEGER NOT NULL" +
+		",time INTEGER NOT NULL" + // unix timestamp
+		",balance TEXT NOT NULL" + // always save balances as TEXT
+		",rate TEXT NOT NULL" + // empty if unknown
+		",fiat_currency TEXT NOT NULL" +
+		",FOREIGN KEY(wallet_id) REFERENCES wallets(id) ON DELETE CASCADE" +
+		")")
+
+	database.db.Exec("CREATE INDEX IF NOT EXISTS" +
+		" balance_snapshots_time_index ON balance_snapshots(wallet_id, time)")
+}
+
+func createQuietHoursTables(database *AccountDb) {
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" quiet_hours(user_id INTEGER NOT NULL PRIMARY KEY" +
+		",start_hour INTEGER NOT NULL" + // in the user's timezone
+		",end_hour INTEGER NOT NULL" +
+		",FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE" +
+		")")
+
+	// notifications that came during quiet hours
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" deferred_notifications(id INTEGER NOT NULL PRIMARY KEY" +
+		",user_id INTEGER NOT NULL" +
+		",text TEXT NOT NULL" +
+		",time INTEGER NOT NULL" + // unix timestamp
+		",FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE" +
+		")")
+}
+
+func createDigestsTables(database *AccountDb) {
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" digests(user_id INTEGER NOT NULL PRIMARY KEY" +
+		",period INTEGER NOT NULL" + // 1 daily, 2 weekly
+		",hour INTEGER NOT NULL" + // in the user's timezone
+		",last_sent_time INTEGER NOT NULL" + // unix timestamp
+		",fiat_currency TEXT NOT NULL" + // currency of the values of the last digest
+		",FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE" +
+		")")
+
+	// balances from the last digest to calculate the changes for the next one
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" digest_coins(id INTEGER NOT NULL PRIMARY KEY" +
+		",user_id INTEGER NOT NULL" +
+		",coin TEXT NOT NULL" +
+		",symbol TEXT NOT NULL" +
+		",balance TEXT NOT NULL" +
+		",value TEXT NOT NULL" + // empty if unknown
+		",UNIQUE(user_id, coin)" +
+		",FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE" +
+		")")
+}
+
+func createPriceAlertsTable(database *AccountDb) {
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" price_alerts(id INTEGER NOT NULL PRIMARY KEY" +
+		",user_id INTEGER NOT NULL" +
+		",price_id TEXT NOT NULL" +
+		",fiat_currency TEXT NOT NULL" +
+		",direction INTEGER NOT NULL" + // 0 if we wait for the price to rise above the threshold, 1 to fall below
+		",threshold TEXT NOT NULL" +
+		",cooldown INTEGER NOT NULL" + // seconds between repeating alerts, 0 for one-shot alerts
+		",last_trigger_time INTEGER" + // unix timestamp, NULL if never triggered
+		",FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE" +
+		")")
+}
+
+func createTransactionsTable(database *AccountDb) {
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" transactions(id INTEGER NOT NULL PRIMARY KEY" +
+		",wallet_id INTEGER NOT NULL" +
+		",hash TEXT NOT NULL" +
+		",time INTEGER NOT NULL" + // unix timestamp
+		",from_address TEXT NOT NULL" +
+		",to_address TEXT NOT NULL" +
+		",amount TEXT NOT NULL" + // always save balances as TEXT
+		",fee TEXT" + // NULL if unknown
+		",block_height INTEGER NOT NULL" +
+		",confirmations INTEGER NOT NULL" +
+		",status INTEGER NOT NULL" +
+		",UNIQUE(wallet_id, hash)" +
+		",FOREIGN KEY(wallet_id) REFERENCES wallets(id) ON DELETE CASCADE" +
+		")")
+
+	database.db.Exec("CREATE INDEX IF NOT EXISTS" +
+		" transactions_time_index ON transactions(wallet_id, time)")
+
+	// wallets that have all the old transactions downloaded
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" synced_histories(wallet_id INTEGER NOT NULL PRIMARY KEY" +
+		",FOREIGN KEY(wallet_id) REFERENCES wallets(id) ON DELETE CASCADE" +
+		")")
+
+	// where the download of old transactions stopped in the history of the provider
+	database.db.Exec("CREATE TABLE IF NOT EXISTS" +
+		" history_sync_offsets(wallet_id INTEGER NOT NULL PRIMARY KEY" +
+		",provider_offset INTEGER NOT NULL" + // records from the newest one
+		",FOREIGN KEY(wallet_id) REFERENCES wallets(id) ON DELETE CASCADE" +
+		")")
+}
+
 func (database *AccountDb) IsConnectionOpened() bool {
 	database.mutex.Lock()
 	defer database.mutex.Unlock()
@@ -84,6 +242,7 @@ func (database *AccountDb) Disconnect() {
 	defer database.mutex.Unlock()
 
 	database.db.Disconnect()
+	database.conn.Close()
 }
 
 func (database *AccountDb) GetDatabaseVersion() (version string) {
@@ -253,6 +412,15 @@ func (database *AccountDb) getLastInsertedItemId() (id int64) {
 }
 
 func (database *AccountDb) CreateWatchOnlyWallet(userId int64, name string, address currencies.AddressData) (newWalletId int64) {
+	return database.createWallet(userId, name, address, wallettypes.WatchOnly)
+}
+
+// address.Address is the extended public key of the wallet
+func (database *AccountDb) CreateHdWatchOnlyWallet(userId int64, name string, address currencies.AddressData) (newWalletId int64) {
+	return database.createWallet(userId, name, address, wallettypes.HdWatchOnly)
+}
+
+func (database *AccountDb) createWallet(userId int64, name string, address currencies.AddressData, walletType wallettypes.WalletType) (newWalletId int64) {
 	database.mutex.Lock()
 	defer database.mutex.Unlock()
 
@@ -270,7 +438,7 @@ func (database *AccountDb) CreateWatchOnlyWallet(userId int64, name string, addr
 		dbBase.SanitizeString(name),
 		address.Currency,
 		dbBase.SanitizeString(address.Address),
-		wallettypes.WatchOnly,
+		walletType,
 		dbBase.SanitizeString(address.ContractAddress),
 		dbBase.SanitizeString(address.PriceId),
 	))
@@ -278,6 +446,61 @@ func (database *AccountDb) CreateWatchOnlyWallet(userId int64, name string, addr
 	return database.getLastInsertedItemId()
 }
 
+// creates all the wallets with enabled balance notifications in one transaction
+// returns ids of the new wallets, nil if none of them were created
+func (database *AccountDb) CreateWallets(userId int64, wallets []NewWalletData) (newWalletIds []int64) {
+	if len(wallets) == 0 {
+		return
+	}
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	tx, err := database.conn.Begin()
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	ids := make([]int64, 0, len(wallets))
+	for _, wallet := range wallets {
+		result, err := tx.Exec("INSERT INTO wallets(user_id, name, currency, address, type, contract_address, price_id) VALUES(?,?,?,?,?,?,?)",
+			userId,
+			wallet.Name,
+			wallet.Address.Currency,
+			wallet.Address.Address,
+			wallet.Type,
+			wallet.Address.ContractAddress,
+			wallet.Address.PriceId,
+		)
+		if err != nil {
+			log.Print(err)
+			tx.Rollback()
+			return nil
+		}
+
+		walletId, err := result.LastInsertId()
+		if err == nil {
+			_, err = tx.Exec("INSERT OR IGNORE INTO balance_notifies(wallet_id, last_balance) VALUES(?,'')", walletId)
+		}
+		if err != nil {
+			log.Print(err)
+			tx.Rollback()
+			return nil
+		}
+
+		ids = append(ids, walletId)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	return ids
+}
+
 func (database *AccountDb) DeleteWallet(walletId int64) {
 	database.mutex.Lock()
 	defer database.mutex.Unlock()
@@ -381,6 +604,62 @@ func (database *AccountDb) GetUserTimezone(userId int64) (timezone string) {
 	return
 }
 
+func (database *AccountDb) SetUserFiatCurrency(userId int64, fiat string) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("UPDATE OR ROLLBACK users SET fiat_currency='%s' WHERE id=%d", dbBase.SanitizeString(fiat), userId))
+}
+
+func (database *AccountDb) GetUserFiatCurrency(userId int64) (fiat string) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT fiat_currency FROM users WHERE id=%d", userId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		err := rows.Scan(&fiat)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	if fiat == "" {
+		fiat = currencies.DefaultFiatCurrency
+	}
+
+	return
+}
+
+// fiat currencies that we need to know rates for
+func (database *AccountDb) GetAllFiatCurrencies() (fiats []string) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query("SELECT DISTINCT fiat_currency FROM users WHERE fiat_currency!=''")
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fiat string
+
+		err := rows.Scan(&fiat)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		fiats = append(fiats, fiat)
+	}
+
+	return
+}
+
 func (database *AccountDb) RenameWallet(walletId int64, newName string) {
 	database.mutex.Lock()
 	defer database.mutex.Unlock()
@@ -422,6 +701,30 @@ func (database *AccountDb) GetWalletAddress(walletId int64) (addressData currenc
 	return
 }
 
+func (database *AccountDb) GetWalletType(walletId int64) (walletType wallettypes.WalletType) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT type FROM wallets WHERE id=%d AND is_removed IS NULL LIMIT 1", walletId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var intType int64
+		err := rows.Scan(&intType)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		walletType = wallettypes.WalletType(intType)
+	} else {
+		log.Fatalf("No wallet found with id %d", walletId)
+	}
+
+	return
+}
+
 func (database *AccountDb) GetUserWalletAddresses(userId int64) (addresses []currencies.AddressData) {
 	database.mutex.Lock()
 	defer database.mutex.Unlock()
@@ -461,7 +764,7 @@ func (database *AccountDb) GetAllWalletAddresses() (addresses []WalletAddressDbW
 	database.mutex.Lock()
 	defer database.mutex.Unlock()
 
-	rows, err := database.db.Query("SELECT id, currency, address, contract_address, price_id FROM wallets WHERE is_removed IS NULL")
+	rows, err := database.db.Query("SELECT id, currency, address, contract_address, price_id, type FROM wallets WHERE is_removed IS NULL")
 	if err != nil {
 		log.Fatal(err.Error())
 	}
@@ -473,8 +776,9 @@ func (database *AccountDb) GetAllWalletAddresses() (addresses []WalletAddressDbW
 		var address string
 		var contractAddress string
 		var priceId string
+		var walletType int64
 
-		err := rows.Scan(&walletId, &currency, &address, &contractAddress, &priceId)
+		err := rows.Scan(&walletId, &currency, &address, &contractAddress, &priceId, &walletType)
 		if err != nil {
 			log.Fatal(err.Error())
 		}
@@ -489,6 +793,7 @@ func (database *AccountDb) GetAllWalletAddresses() (addresses []WalletAddressDbW
 					PriceId: priceId,
 				},
 				WalletId: walletId,
+				Type: wallettypes.WalletType(walletType),
 			},
 		)
 	}
@@ -582,7 +887,7 @@ func (database *AccountDb) GetBalanceNotifies(walletIds []int64) (notifies []cur
 	defer database.mutex.Unlock()
 
 	idsString := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(walletIds)), ","), "[]")
-	rows, err := database.db.Query(fmt.Sprintf("SELECT n.id, w.user_id, n.wallet_id, n.last_balance FROM balance_notifies AS n LEFT JOIN wallets AS w ON n.wallet_id=w.id WHERE n.wallet_id IN (%s)", idsString))
+	rows, err := database.db.Query(fmt.Sprintf("SELECT n.id, w.user_id, n.wallet_id, n.last_balance, n.direction, n.min_change, n.min_change_fiat FROM balance_notifies AS n LEFT JOIN wallets AS w ON n.wallet_id=w.id WHERE n.wallet_id IN (%s)", idsString))
 	if err != nil {
 		log.Fatal(err.Error())
 	}
@@ -593,8 +898,11 @@ func (database *AccountDb) GetBalanceNotifies(walletIds []int64) (notifies []cur
 		var userId int64
 		var walletId int64
 		var lastBalance string
+		var direction int64
+		var minChange string
+		var minChangeFiat string
 
-		err := rows.Scan(&notifyId, &userId, &walletId, &lastBalance)
+		err := rows.Scan(&notifyId, &userId, &walletId, &lastBalance, &direction, &minChange, &minChangeFiat)
 		if err != nil {
 			log.Fatal(err.Error())
 		}
@@ -610,12 +918,28 @@ func (database *AccountDb) GetBalanceNotifies(walletIds []int64) (notifies []cur
 				NotifyId: notifyId,
 				WalletId: walletId,
 				OldBalance: intBalance,
+				BalanceNotifySettings: makeBalanceNotifySettings(direction, minChange, minChangeFiat),
 			})
 	}
 
 	return
 }
 
+func makeBalanceNotifySettings(direction int64, minChange string, minChangeFiat string) (settings currencies.BalanceNotifySettings) {
+	settings.Direction = currencies.BalanceNotifyDirection(direction)
+
+	if minChange != "" {
+		floatMinChange, _, err := new(big.Float).Parse(minChange, 10)
+		if err == nil {
+			settings.MinChange = floatMinChange
+			settings.MinChangeFiat = minChangeFiat
+		} else {
+			log.Printf("Wrong minimal change of balance notify: %s", minChange)
+		}
+	}
+	return
+}
+
 func (database *AccountDb) UpdateBalanceNotifies(updatedNotifies []currencies.BalanceNotify) {
 	if len(updatedNotifies) <= 0 {
 		return
@@ -651,6 +975,67 @@ func (database *AccountDb) DisableBalanceNotifies(walletId int64) {
 	database.db.Exec(fmt.Sprintf("DELETE FROM balance_notifies WHERE wallet_id=%d", walletId))
 }
 
+// returns default settings if the notifications are disabled
+func (database *AccountDb) GetBalanceNotifySettings(walletId int64) currencies.BalanceNotifySettings {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT direction, min_change, min_change_fiat FROM balance_notifies WHERE wallet_id=%d", walletId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var direction int64
+		var minChange string
+		var minChangeFiat string
+
+		err := rows.Scan(&direction, &minChange, &minChangeFiat)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		return makeBalanceNotifySettings(direction, minChange, minChangeFiat)
+	}
+
+	return currencies.BalanceNotifySettings{}
+}
+
+func (database *AccountDb) SetBalanceNotifyDirection(walletId int64, direction currencies.BalanceNotifyDirection) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("UPDATE OR ROLLBACK balance_notifies SET direction=%d WHERE wallet_id=%d", direction, walletId))
+}
+
+// minChange is nil to report any change, fiat is empty if minChange is in coin units
+func (database *AccountDb) SetBalanceNotifyMinChange(walletId int64, minChange *big.Float, fiat string) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	minChangeStr := ""
+	if minChange != nil {
+		minChangeStr = minChange.Text('f', -1)
+	} else {
+		fiat = ""
+	}
+
+	database.db.Exec(fmt.Sprintf("UPDATE OR ROLLBACK balance_notifies SET min_change='%s', min_change_fiat='%s' WHERE wallet_id=%d",
+		minChangeStr,
+		dbBase.SanitizeString(fiat),
+		walletId,
+	))
+}
+
+// fiat currencies that we need to know rates for to check minimal changes of the balances
+func (database *AccountDb) GetBalanceNotifiesFiats() []string {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	return database.queryStrings("SELECT DISTINCT min_change_fiat FROM balance_notifies WHERE min_change_fiat!=''")
+}
+
 func (database *AccountDb) IsBalanceNotifiesEnabled(walletId int64) bool {
 	database.mutex.Lock()
 	defer database.mutex.Unlock()
@@ -679,3 +1064,963 @@ func (database *AccountDb) IsBalanceNotifiesEnabled(walletId int64) bool {
 
 	return false
 }
+
+func (database *AccountDb) GetHdWalletAddresses(walletId int64) (addresses []HdWalletAddressDbWrapper) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT chain, address_index, address, is_used FROM hd_wallet_addresses WHERE wallet_id=%d ORDER BY chain, address_index", walletId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address HdWalletAddressDbWrapper
+		var isUsed int64
+
+		err := rows.Scan(&address.Chain, &address.Index, &address.Address, &isUsed)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		address.IsUsed = (isUsed != 0)
+		addresses = append(addresses, address)
+	}
+
+	return
+}
+
+// adds new derived addresses and updates already existing ones
+func (database *AccountDb) UpdateHdWalletAddresses(walletId int64, addresses []HdWalletAddressDbWrapper) {
+	if len(addresses) <= 0 {
+		return
+	}
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	var b bytes.Buffer
+
+	for _, address := range addresses {
+		isUsed := 0
+		if address.IsUsed {
+			isUsed = 1
+		}
+
+		b.WriteString(fmt.Sprintf("INSERT OR REPLACE INTO hd_wallet_addresses(wallet_id, chain, address_index, address, is_used) VALUES(%d,%d,%d,'%s',%d);",
+			walletId,
+			address.Chain,
+			address.Index,
+			dbBase.SanitizeString(address.Address),
+			isUsed,
+		))
+	}
+
+	database.db.Exec(b.String())
+}
+
+func (database *AccountDb) IsWalletHistorySynced(walletId int64) bool {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT wallet_id FROM synced_histories WHERE wallet_id=%d LIMIT 1", walletId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	return rows.Next()
+}
+
+func (database *AccountDb) SetWalletHistorySynced(walletId int64, isSynced bool) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if isSynced {
+		database.db.Exec(fmt.Sprintf("INSERT OR IGNORE INTO synced_histories(wallet_id) VALUES(%d)", walletId))
+	} else {
+		database.db.Exec(fmt.Sprintf("DELETE FROM synced_histories WHERE wallet_id=%d", walletId))
+	}
+}
+
+// returns 0 if the download of old transactions of the wallet was never started
+func (database *AccountDb) GetHistorySyncOffset(walletId int64) (offset int) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT provider_offset FROM history_sync_offsets WHERE wallet_id=%d", walletId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		err := rows.Scan(&offset)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	return
+}
+
+func (database *AccountDb) SetHistorySyncOffset(walletId int64, offset int) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("INSERT OR REPLACE INTO history_sync_offsets(wallet_id, provider_offset) VALUES(%d,%d)", walletId, offset))
+}
+
+// adds new transactions and updates the known ones (e.g. new confirmations), returns how many of them were new
+// and the index of the first transaction that had been stored before, -1 if there are no such transactions
+func (database *AccountDb) UpdateTransactions(walletId int64, transactions []currencies.TransactionsHistoryItem) (newCount int, firstKnownIndex int) {
+	firstKnownIndex = -1
+
+	if len(transactions) <= 0 {
+		return
+	}
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	hashes := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		hashes = append(hashes, "'" + dbBase.SanitizeString(transaction.Hash) + "'")
+	}
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT hash FROM transactions WHERE wallet_id=%d AND hash IN (%s)", walletId, strings.Join(hashes, ",")))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	storedHashes := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		err := rows.Scan(&hash)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		storedHashes[hash] = true
+	}
+	rows.Close()
+
+	knownHashes := make(map[string]bool)
+	for hash := range storedHashes {
+		knownHashes[hash] = true
+	}
+
+	var b bytes.Buffer
+
+	for i, transaction := range transactions {
+		if transaction.Hash == "" {
+			// we can't tell such transactions apart
+			continue
+		}
+
+		if firstKnownIndex < 0 && storedHashes[transaction.Hash] {
+			firstKnownIndex = i
+		}
+
+		if !knownHashes[transaction.Hash] {
+			knownHashes[transaction.Hash] = true
+			newCount++
+		}
+
+		fee := "NULL"
+		if transaction.Fee != nil {
+			fee = "'" + transaction.Fee.String() + "'"
+		}
+
+		amount := "0"
+		if transaction.Amount != nil {
+			amount = transaction.Amount.String()
+		}
+
+		b.WriteString(fmt.Sprintf("INSERT OR REPLACE INTO transactions(wallet_id, hash, time, from_address, to_address, amount, fee, block_height, confirmations, status) VALUES(%d,'%s',%d,'%s','%s','%s',%s,%d,%d,%d);",
+			walletId,
+			dbBase.SanitizeString(transaction.Hash),
+			transaction.Time.Unix(),
+			dbBase.SanitizeString(transaction.From),
+			dbBase.SanitizeString(transaction.To),
+			amount,
+			fee,
+			transaction.BlockHeight,
+			transaction.Confirmations,
+			transaction.Status,
+		))
+	}
+
+	if b.Len() > 0 {
+		database.db.Exec(b.String())
+	}
+
+	return
+}
+
+// returns stored transactions sorted from new to old, limit <= 0 means no limit
+func (database *AccountDb) GetTransactionsHistory(walletId int64, offset int, limit int) (history []currencies.TransactionsHistoryItem) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if limit <= 0 {
+		// SQLite way to say "no limit"
+		limit = -1
+	}
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT hash, time, from_address, to_address, amount, fee, block_height, confirmations, status FROM transactions WHERE wallet_id=%d ORDER BY time DESC, id DESC LIMIT %d OFFSET %d", walletId, limit, offset))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	history = make([]currencies.TransactionsHistoryItem, 0)
+
+	for rows.Next() {
+		var item currencies.TransactionsHistoryItem
+		var txTime int64
+		var amount string
+		var fee sql.NullString
+		var status int64
+
+		err := rows.Scan(&item.Hash, &txTime, &item.From, &item.To, &amount, &fee, &item.BlockHeight, &item.Confirmations, &status)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		item.Time = time.Unix(txTime, 0)
+		item.Status = currencies.TransactionStatus(status)
+
+		intAmount, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			intAmount = big.NewInt(0)
+		}
+		item.Amount = intAmount
+
+		if fee.Valid {
+			if intFee, ok := new(big.Int).SetString(fee.String, 10); ok {
+				item.Fee = intFee
+			}
+		}
+
+		history = append(history, item)
+	}
+
+	return
+}
+
+func (database *AccountDb) GetTransactionsCount(walletId int64) (count int) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT COUNT(*) FROM transactions WHERE wallet_id=%d", walletId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		err := rows.Scan(&count)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	return
+}
+
+func (database *AccountDb) CreatePriceAlert(alert currencies.PriceAlert) (alertId int64) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("INSERT INTO price_alerts(user_id, price_id, fiat_currency, direction, threshold, cooldown) VALUES(%d,'%s','%s',%d,'%s',%d)",
+		alert.UserId,
+		dbBase.SanitizeString(alert.PriceId),
+		dbBase.SanitizeString(alert.Fiat),
+		alert.Direction,
+		alert.Threshold.Text('f', -1),
+		int64(alert.Cooldown / time.Second),
+	))
+
+	return database.getLastInsertedItemId()
+}
+
+func (database *AccountDb) DeletePriceAlert(alertId int64) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("DELETE FROM price_alerts WHERE id=%d", alertId))
+}
+
+func (database *AccountDb) IsPriceAlertBelongsToUser(userId int64, alertId int64) bool {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT COUNT(*) FROM price_alerts WHERE id=%d AND user_id=%d", alertId, userId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var count int64
+		err := rows.Scan(&count)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		return count > 0
+	}
+
+	return false
+}
+
+func (database *AccountDb) SetPriceAlertTriggered(alertId int64, triggerTime time.Time) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("UPDATE OR ROLLBACK price_alerts SET last_trigger_time=%d WHERE id=%d", triggerTime.Unix(), alertId))
+}
+
+func (database *AccountDb) GetUserPriceAlerts(userId int64) []currencies.PriceAlert {
+	return database.queryPriceAlerts(fmt.Sprintf("WHERE user_id=%d", userId))
+}
+
+func (database *AccountDb) GetAllPriceAlerts() []currencies.PriceAlert {
+	return database.queryPriceAlerts("")
+}
+
+func (database *AccountDb) queryPriceAlerts(condition string) (alerts []currencies.PriceAlert) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query("SELECT id, user_id, price_id, fiat_currency, direction, threshold, cooldown, last_trigger_time FROM price_alerts " + condition + " ORDER BY id")
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var alert currencies.PriceAlert
+		var direction int64
+		var threshold string
+		var cooldown int64
+		var lastTriggerTime sql.NullInt64
+
+		err := rows.Scan(&alert.AlertId, &alert.UserId, &alert.PriceId, &alert.Fiat, &direction, &threshold, &cooldown, &lastTriggerTime)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		floatThreshold, _, err := new(big.Float).Parse(threshold, 10)
+		if err != nil {
+			log.Printf("Wrong threshold of price alert %d: %s", alert.AlertId, threshold)
+			continue
+		}
+
+		alert.Direction = currencies.PriceAlertDirection(direction)
+		alert.Threshold = floatThreshold
+		alert.Cooldown = time.Duration(cooldown) * time.Second
+		if lastTriggerTime.Valid {
+			alert.LastTriggerTime = time.Unix(lastTriggerTime.Int64, 0)
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	return
+}
+
+// price ids and fiat currencies that we need to know rates for to check the alerts
+func (database *AccountDb) GetPriceAlertsRateIds() (priceIds []string, fiats []string) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	priceIds = database.queryStrings("SELECT DISTINCT price_id FROM price_alerts")
+	fiats = database.queryStrings("SELECT DISTINCT fiat_currency FROM price_alerts")
+	return
+}
+
+func (database *AccountDb) queryStrings(query string) (values []string) {
+	rows, err := database.db.Query(query)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+
+		err := rows.Scan(&value)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		values = append(values, value)
+	}
+
+	return
+}
+
+// disabled digests are removed, lastSentTime is set to not to send the digest for the time already passed
+func (database *AccountDb) SetUserDigest(userId int64, settings currencies.DigestSettings, lastSentTime time.Time) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if settings.Period == currencies.DigestDisabled {
+		database.db.Exec(fmt.Sprintf("DELETE FROM digests WHERE user_id=%d", userId))
+		return
+	}
+
+	database.db.Exec(fmt.Sprintf("INSERT OR IGNORE INTO digests(user_id, period, hour, last_sent_time, fiat_currency) VALUES(%d,%d,%d,%d,'');" +
+		"UPDATE OR ROLLBACK digests SET period=%d, hour=%d, last_sent_time=%d WHERE user_id=%d",
+		userId, settings.Period, settings.Hour, lastSentTime.Unix(),
+		settings.Period, settings.Hour, lastSentTime.Unix(), userId,
+	))
+}
+
+func (database *AccountDb) GetUserDigest(userId int64) (settings currencies.DigestSettings) {
+	digests := database.queryDigests(fmt.Sprintf("WHERE user_id=%d", userId))
+	if len(digests) > 0 {
+		settings = digests[0].DigestSettings
+	}
+	return
+}
+
+func (database *AccountDb) GetAllDigests() []currencies.Digest {
+	return database.queryDigests("")
+}
+
+func (database *AccountDb) queryDigests(condition string) (digests []currencies.Digest) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query("SELECT user_id, period, hour, last_sent_time FROM digests " + condition)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var digest currencies.Digest
+		var period int64
+		var lastSentTime int64
+
+		err := rows.Scan(&digest.UserId, &period, &digest.Hour, &lastSentTime)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		digest.Period = currencies.DigestPeriod(period)
+		digest.LastSentTime = time.Unix(lastSentTime, 0)
+
+		digests = append(digests, digest)
+	}
+
+	return
+}
+
+// remembers the sent values to compare the next digest with them
+func (database *AccountDb) SetDigestSent(userId int64, sentTime time.Time, fiat string, coins []currencies.DigestCoin) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	var b bytes.Buffer
+
+	b.WriteString(fmt.Sprintf("UPDATE OR ROLLBACK digests SET last_sent_time=%d, fiat_currency='%s' WHERE user_id=%d;",
+		sentTime.Unix(),
+		dbBase.SanitizeString(fiat),
+		userId,
+	))
+
+	b.WriteString(fmt.Sprintf("DELETE FROM digest_coins WHERE user_id=%d;", userId))
+
+	for _, coin := range coins {
+		if coin.Balance == nil {
+			continue
+		}
+
+		value := ""
+		if coin.Value != nil {
+			value = coin.Value.Text('f', -1)
+		}
+
+		b.WriteString(fmt.Sprintf("INSERT INTO digest_coins(user_id, coin, symbol, balance, value) VALUES(%d,'%s','%s','%s','%s');",
+			userId,
+			dbBase.SanitizeString(coin.CoinKey),
+			dbBase.SanitizeString(coin.Symbol),
+			coin.Balance.Text('f', -1),
+			value,
+		))
+	}
+
+	database.db.Exec(b.String())
+}
+
+// returns the coins of the last digest and the fiat currency of their values
+func (database *AccountDb) GetLastDigestCoins(userId int64) (coins []currencies.DigestCoin, fiat string) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	fiats := database.queryStrings(fmt.Sprintf("SELECT fiat_currency FROM digests WHERE user_id=%d", userId))
+	if len(fiats) > 0 {
+		fiat = fiats[0]
+	}
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT coin, symbol, balance, value FROM digest_coins WHERE user_id=%d ORDER BY id", userId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var coin currencies.DigestCoin
+		var balance string
+		var value string
+
+		err := rows.Scan(&coin.CoinKey, &coin.Symbol, &balance, &value)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		floatBalance, _, err := new(big.Float).Parse(balance, 10)
+		if err != nil {
+			log.Printf("Wrong balance of digest coin %s: %s", coin.CoinKey, balance)
+			continue
+		}
+		coin.Balance = floatBalance
+
+		if value != "" {
+			floatValue, _, err := new(big.Float).Parse(value, 10)
+			if err == nil {
+				coin.Value = floatValue
+			}
+		}
+
+		coins = append(coins, coin)
+	}
+
+	return
+}
+
+// quiet hours with the same start and end are removed
+func (database *AccountDb) SetUserQuietHours(userId int64, quietHours currencies.QuietHours) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if !quietHours.IsEnabled() {
+		database.db.Exec(fmt.Sprintf("DELETE FROM quiet_hours WHERE user_id=%d", userId))
+		return
+	}
+
+	database.db.Exec(fmt.Sprintf("INSERT OR REPLACE INTO quiet_hours(user_id, start_hour, end_hour) VALUES(%d,%d,%d)",
+		userId,
+		quietHours.StartHour,
+		quietHours.EndHour,
+	))
+}
+
+func (database *AccountDb) GetUserQuietHours(userId int64) (quietHours currencies.QuietHours) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT start_hour, end_hour FROM quiet_hours WHERE user_id=%d", userId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		err := rows.Scan(&quietHours.StartHour, &quietHours.EndHour)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	return
+}
+
+func (database *AccountDb) AddDeferredNotification(userId int64, text string, notificationTime time.Time) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("INSERT INTO deferred_notifications(user_id, text, time) VALUES(%d,'%s',%d)",
+		userId,
+		dbBase.SanitizeString(text),
+		notificationTime.Unix(),
+	))
+}
+
+func (database *AccountDb) GetUsersWithDeferredNotifications() (userIds []int64) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query("SELECT DISTINCT user_id FROM deferred_notifications")
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userId int64
+
+		err := rows.Scan(&userId)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		userIds = append(userIds, userId)
+	}
+
+	return
+}
+
+// returns the notifications in the order they came
+func (database *AccountDb) GetDeferredNotifications(userId int64) (ids []int64, texts []string, times []time.Time) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT id, text, time FROM deferred_notifications WHERE user_id=%d ORDER BY time, id", userId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var text string
+		var notificationTime int64
+
+		err := rows.Scan(&id, &text, &notificationTime)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		ids = append(ids, id)
+		texts = append(texts, text)
+		times = append(times, time.Unix(notificationTime, 0))
+	}
+
+	return
+}
+
+func (database *AccountDb) DeleteDeferredNotifications(ids []int64) {
+	if len(ids) == 0 {
+		return
+	}
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	idsString := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(ids)), ","), "[]")
+	database.db.Exec(fmt.Sprintf("DELETE FROM deferred_notifications WHERE id IN (%s)", idsString))
+}
+
+// fiat currencies of the owners of the wallets
+func (database *AccountDb) GetWalletsFiatCurrencies() (fiats map[int64]string) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query("SELECT w.id, u.fiat_currency FROM wallets AS w INNER JOIN users AS u ON w.user_id=u.id WHERE w.is_removed IS NULL")
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	fiats = make(map[int64]string)
+
+	for rows.Next() {
+		var walletId int64
+		var fiat string
+
+		err := rows.Scan(&walletId, &fiat)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		fiats[walletId] = fiat
+	}
+
+	return
+}
+
+func (database *AccountDb) AddBalanceSnapshots(snapshots []currencies.BalanceSnapshot) {
+	if len(snapshots) == 0 {
+		return
+	}
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	var b bytes.Buffer
+
+	for _, snapshot := range snapshots {
+		if snapshot.Balance == nil {
+			continue
+		}
+
+		rate := ""
+		if snapshot.Rate != nil {
+			rate = snapshot.Rate.Text('f', -1)
+		}
+
+		b.WriteString(fmt.Sprintf("INSERT INTO balance_snapshots(wallet_id, time, balance, rate, fiat_currency) VALUES(%d,%d,'%s','%s','%s');",
+			snapshot.WalletId,
+			snapshot.Time.Unix(),
+			snapshot.Balance.String(),
+			rate,
+			dbBase.SanitizeString(snapshot.Fiat),
+		))
+	}
+
+	if b.Len() > 0 {
+		database.db.Exec(b.String())
+	}
+}
+
+// returns zero time if there are no snapshots
+func (database *AccountDb) GetLastBalanceSnapshotTime() (lastTime time.Time) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query("SELECT MAX(time) FROM balance_snapshots")
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var maxTime sql.NullInt64
+		err := rows.Scan(&maxTime)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		if maxTime.Valid {
+			lastTime = time.Unix(maxTime.Int64, 0)
+		}
+	}
+
+	return
+}
+
+// returns the snapshots of the wallets made not earlier than startTime ordered by time
+func (database *AccountDb) GetBalanceSnapshots(walletIds []int64, startTime time.Time) (snapshots []currencies.BalanceSnapshot) {
+	if len(walletIds) == 0 {
+		return
+	}
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	idsString := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(walletIds)), ","), "[]")
+	rows, err := database.db.Query(fmt.Sprintf("SELECT wallet_id, time, balance, rate, fiat_currency FROM balance_snapshots WHERE wallet_id IN (%s) AND time>=%d ORDER BY time, id", idsString, startTime.Unix()))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snapshot currencies.BalanceSnapshot
+		var snapshotTime int64
+		var balance string
+		var rate string
+
+		err := rows.Scan(&snapshot.WalletId, &snapshotTime, &balance, &rate, &snapshot.Fiat)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		intBalance, ok := new(big.Int).SetString(balance, 10)
+		if !ok {
+			log.Printf("Wrong balance in snapshot of wallet %d: %s", snapshot.WalletId, balance)
+			continue
+		}
+
+		snapshot.Time = time.Unix(snapshotTime, 0)
+		snapshot.Balance = intBalance
+
+		if rate != "" {
+			floatRate, _, err := new(big.Float).Parse(rate, 10)
+			if err == nil {
+				snapshot.Rate = floatRate
+			}
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return
+}
+
+// leaves only the last snapshot of each wallet in every interval for the snapshots made before endTime
+func (database *AccountDb) DownsampleBalanceSnapshots(endTime time.Time, interval time.Duration) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	intervalSec := int64(interval / time.Second)
+	if intervalSec <= 0 {
+		return
+	}
+
+	database.db.Exec(fmt.Sprintf("DELETE FROM balance_snapshots WHERE time<%d AND id NOT IN" +
+		" (SELECT MAX(id) FROM balance_snapshots WHERE time<%d GROUP BY wallet_id, time/%d)",
+		endTime.Unix(),
+		endTime.Unix(),
+		intervalSec,
+	))
+}
+
+func (database *AccountDb) DeleteBalanceSnapshotsBefore(endTime time.Time) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("DELETE FROM balance_snapshots WHERE time<%d", endTime.Unix()))
+}
+
+func (database *AccountDb) SetUserCostBasisMethod(userId int64, method currencies.CostBasisMethod) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("UPDATE OR ROLLBACK users SET cost_basis_method=%d WHERE id=%d", method, userId))
+}
+
+func (database *AccountDb) GetUserCostBasisMethod(userId int64) (method currencies.CostBasisMethod) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT cost_basis_method FROM users WHERE id=%d", userId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var value int64
+		err := rows.Scan(&value)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		method = currencies.CostBasisMethod(value)
+	}
+
+	return
+}
+
+// a lot attached to a transaction replaces the previous lot of the same transaction
+func (database *AccountDb) AddTradeLot(lot currencies.TradeLot) (lotId int64) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	transactionHash := "NULL"
+	if lot.TransactionHash != "" {
+		transactionHash = "'" + dbBase.SanitizeString(lot.TransactionHash) + "'"
+	}
+
+	isSell := 0
+	if lot.IsSell {
+		isSell = 1
+	}
+
+	database.db.Exec(fmt.Sprintf("INSERT OR REPLACE INTO trade_lots(wallet_id, is_sell, amount, price, fiat_currency, time, transaction_hash) VALUES(%d,%d,'%s','%s','%s',%d,%s)",
+		lot.WalletId,
+		isSell,
+		lot.Amount.Text('f', -1),
+		lot.Price.Text('f', -1),
+		dbBase.SanitizeString(lot.Fiat),
+		lot.Time.Unix(),
+		transactionHash,
+	))
+
+	return database.getLastInsertedItemId()
+}
+
+func (database *AccountDb) DeleteTradeLot(lotId int64) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.db.Exec(fmt.Sprintf("DELETE FROM trade_lots WHERE id=%d", lotId))
+}
+
+func (database *AccountDb) IsTradeLotBelongsToUser(userId int64, lotId int64) bool {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query(fmt.Sprintf("SELECT COUNT(*) FROM trade_lots l INNER JOIN wallets w ON w.id=l.wallet_id WHERE l.id=%d AND w.user_id=%d AND w.is_removed IS NULL", lotId, userId))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var count int64
+		err := rows.Scan(&count)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		return count > 0
+	}
+
+	return false
+}
+
+// returns the lots sorted from old to new
+func (database *AccountDb) GetWalletTradeLots(walletId int64) []currencies.TradeLot {
+	return database.queryTradeLots(fmt.Sprintf("WHERE wallet_id=%d", walletId))
+}
+
+func (database *AccountDb) GetTransactionTradeLot(walletId int64, transactionHash string) (lot currencies.TradeLot, ok bool) {
+	lots := database.queryTradeLots(fmt.Sprintf("WHERE wallet_id=%d AND transaction_hash='%s'", walletId, dbBase.SanitizeString(transactionHash)))
+	if len(lots) > 0 {
+		lot = lots[0]
+		ok = true
+	}
+	return
+}
+
+func (database *AccountDb) queryTradeLots(condition string) (lots []currencies.TradeLot) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	rows, err := database.db.Query("SELECT id, wallet_id, is_sell, amount, price, fiat_currency, time, transaction_hash FROM trade_lots " + condition + " ORDER BY time, id")
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lot currencies.TradeLot
+		var isSell int64
+		var amount string
+		var price string
+		var lotTime int64
+		var transactionHash sql.NullString
+
+		err := rows.Scan(&lot.LotId, &lot.WalletId, &isSell, &amount, &price, &lot.Fiat, &lotTime, &transactionHash)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		floatAmount, _, err := new(big.Float).Parse(amount, 10)
+		if err != nil {
+			log.Printf("Wrong amount of trade lot %d: %s", lot.LotId, amount)
+			continue
+		}
+
+		floatPrice, _, err := new(big.Float).Parse(price, 10)
+		if err != nil {
+			log.Printf("Wrong price of trade lot %d: %s", lot.LotId, price)
+			continue
+		}
+
+		lot.IsSell = (isSell != 0)
+		lot.Amount = floatAmount
+		lot.Price = floatPrice
+		lot.Time = time.Unix(lotTime, 0)
+		if transactionHash.Valid {
+			lot.TransactionHash = transactionHash.String
+		}
+
+		lots = append(lots, lot)
+	}
+
+	return
+}
@@ -2,9 +2,24 @@ package database
 
 import (
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/wallettypes"
 )
 
 type WalletAddressDbWrapper struct {
 	Data currencies.AddressData
 	WalletId int64
+	Type wallettypes.WalletType
+}
+
+type HdWalletAddressDbWrapper struct {
+	Chain uint32 // 0 for receiving addresses, 1 for change
+	Index uint32
+	Address string
+	IsUsed bool
+}
+
+type NewWalletData struct {
+	Name string
+	Address currencies.AddressData
+	Type wallettypes.WalletType
 }
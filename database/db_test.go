@@ -4,8 +4,10 @@ import (
 	"math/big"
 	"github.com/stretchr/testify/require"
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/wallettypes"
 	"os"
 	"testing"
+	"time"
 )
 
 const (
@@ -251,6 +253,29 @@ func TestUsersLanguage(t *testing.T) {
 	}
 }
 
+func TestUsersFiatCurrency(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId1 := db.GetUserId(123, "")
+	userId2 := db.GetUserId(321, "")
+
+	assert.Equal(currencies.DefaultFiatCurrency, db.GetUserFiatCurrency(userId1))
+	assert.Equal([]string{currencies.DefaultFiatCurrency}, db.GetAllFiatCurrencies())
+
+	db.SetUserFiatCurrency(userId1, "eur")
+
+	assert.Equal("eur", db.GetUserFiatCurrency(userId1))
+	assert.Equal(currencies.DefaultFiatCurrency, db.GetUserFiatCurrency(userId2))
+	assert.ElementsMatch([]string{"eur", currencies.DefaultFiatCurrency}, db.GetAllFiatCurrencies())
+}
+
 func TestWalletRenaming(t *testing.T) {
 	assert := require.New(t)
 	db := createDbAndConnect(t)
@@ -544,6 +569,39 @@ func TestNotifications(t *testing.T) {
 	assert.True(db.IsBalanceNotifiesEnabled(walletId1))
 	assert.True(db.IsBalanceNotifiesEnabled(walletId2))
 
+	// test filters
+	{
+		settings := db.GetBalanceNotifySettings(walletId1)
+		assert.Equal(currencies.BalanceNotifyBoth, settings.Direction)
+		assert.True(settings.MinChange == nil)
+
+		db.SetBalanceNotifyDirection(walletId1, currencies.BalanceNotifyIncoming)
+		db.SetBalanceNotifyMinChange(walletId1, big.NewFloat(5.5), "eur")
+
+		settings = db.GetBalanceNotifySettings(walletId1)
+		assert.Equal(currencies.BalanceNotifyIncoming, settings.Direction)
+		assert.Equal("5.5", settings.MinChange.Text('f', -1))
+		assert.Equal("eur", settings.MinChangeFiat)
+		assert.Equal([]string{"eur"}, db.GetBalanceNotifiesFiats())
+
+		notifies := db.GetBalanceNotifies([]int64{walletId1})
+		assert.Equal(1, len(notifies))
+		if len(notifies) > 0 {
+			assert.Equal(currencies.BalanceNotifyIncoming, notifies[0].Direction)
+			assert.Equal("5.5", notifies[0].MinChange.Text('f', -1))
+			assert.Equal("eur", notifies[0].MinChangeFiat)
+		}
+
+		db.SetBalanceNotifyMinChange(walletId1, nil, "eur")
+		settings = db.GetBalanceNotifySettings(walletId1)
+		assert.True(settings.MinChange == nil)
+		assert.Equal("", settings.MinChangeFiat)
+		assert.Equal(0, len(db.GetBalanceNotifiesFiats()))
+
+		// settings of other wallets are not affected
+		assert.Equal(currencies.BalanceNotifyBoth, db.GetBalanceNotifySettings(walletId2).Direction)
+	}
+
 	// test disabling
 	db.DisableBalanceNotifies(walletId1)
 
@@ -552,3 +610,620 @@ func TestNotifications(t *testing.T) {
 	assert.True(db.IsBalanceNotifiesEnabled(walletId2))
 }
 
+
+func TestEthereumAddressesNormalizationUpdate(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId := db.GetUserId(123, "")
+
+	etherWalletId := db.CreateWatchOnlyWallet(userId, "ether", currencies.AddressData{
+		Currency: currencies.Ether,
+		Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+	})
+
+	tokenWalletId := db.CreateWatchOnlyWallet(userId, "token", currencies.AddressData{
+		Currency: currencies.Erc20Token,
+		Address: "0xFB6916095CA1DF60BB79CE92CE3EA74C37C5D359",
+		ContractAddress: "0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb",
+	})
+
+	bitcoinWalletId := db.CreateWatchOnlyWallet(userId, "bitcoin", currencies.AddressData{
+		Currency: currencies.Bitcoin,
+		Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+	})
+
+	// the tables are already created with the latest schema, so apply only the normalization
+	for _, updater := range makeUpdaters("0.4", "0.5") {
+		updater.updateDb(db)
+	}
+
+	assert.Equal("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", db.GetWalletAddress(etherWalletId).Address)
+	assert.Equal("", db.GetWalletAddress(etherWalletId).ContractAddress)
+
+	assert.Equal("0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", db.GetWalletAddress(tokenWalletId).Address)
+	assert.Equal("0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB", db.GetWalletAddress(tokenWalletId).ContractAddress)
+
+	assert.Equal("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", db.GetWalletAddress(bitcoinWalletId).Address)
+}
+
+func TestHdWallets(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId := db.GetUserId(123, "")
+
+	walletId := db.CreateHdWatchOnlyWallet(userId, "hd", currencies.AddressData{
+		Currency: currencies.Bitcoin,
+		Address: "xpub",
+	})
+	simpleWalletId := db.CreateWatchOnlyWallet(userId, "simple", currencies.AddressData{
+		Currency: currencies.Bitcoin,
+		Address: "adr",
+	})
+
+	assert.Equal(wallettypes.HdWatchOnly, db.GetWalletType(walletId))
+	assert.Equal(wallettypes.WatchOnly, db.GetWalletType(simpleWalletId))
+
+	{
+		addresses := db.GetAllWalletAddresses()
+		assert.Equal(2, len(addresses))
+		for _, address := range addresses {
+			if address.WalletId == walletId {
+				assert.Equal(wallettypes.HdWatchOnly, address.Type)
+				assert.Equal("xpub", address.Data.Address)
+			} else {
+				assert.Equal(wallettypes.WatchOnly, address.Type)
+			}
+		}
+	}
+
+	assert.Equal(0, len(db.GetHdWalletAddresses(walletId)))
+
+	db.UpdateHdWalletAddresses(walletId, []HdWalletAddressDbWrapper{
+		{Chain: 1, Index: 0, Address: "change0"},
+		{Chain: 0, Index: 1, Address: "receive1"},
+		{Chain: 0, Index: 0, Address: "receive0", IsUsed: true},
+	})
+
+	{
+		addresses := db.GetHdWalletAddresses(walletId)
+		assert.Equal([]HdWalletAddressDbWrapper{
+			{Chain: 0, Index: 0, Address: "receive0", IsUsed: true},
+			{Chain: 0, Index: 1, Address: "receive1"},
+			{Chain: 1, Index: 0, Address: "change0"},
+		}, addresses)
+	}
+
+	db.UpdateHdWalletAddresses(walletId, []HdWalletAddressDbWrapper{
+		{Chain: 0, Index: 1, Address: "receive1", IsUsed: true},
+	})
+
+	{
+		addresses := db.GetHdWalletAddresses(walletId)
+		assert.Equal(3, len(addresses))
+		assert.True(addresses[1].IsUsed)
+	}
+
+	assert.Equal(0, len(db.GetHdWalletAddresses(simpleWalletId)))
+}
+
+func TestTransactions(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId := db.GetUserId(123, "")
+
+	walletId := db.CreateWatchOnlyWallet(userId, "test", currencies.AddressData{
+		Currency: currencies.Bitcoin,
+		Address: "adr",
+	})
+
+	assert.False(db.IsWalletHistorySynced(walletId))
+	assert.Equal(0, db.GetTransactionsCount(walletId))
+	assert.Equal(0, len(db.GetTransactionsHistory(walletId, 0, 10)))
+
+	newCount, firstKnownIndex := db.UpdateTransactions(walletId, []currencies.TransactionsHistoryItem{
+		{Hash: "tx2", From: "adr", To: "other", Amount: big.NewInt(-20), Time: time.Unix(2000, 0), Fee: big.NewInt(1), BlockHeight: 12, Confirmations: 1},
+		{Hash: "tx1", From: "other", To: "adr", Amount: big.NewInt(10), Time: time.Unix(1000, 0), BlockHeight: 10, Confirmations: 3},
+		// can't be stored without a hash
+		{From: "other", To: "adr", Amount: big.NewInt(5), Time: time.Unix(1500, 0)},
+	})
+	assert.Equal(2, newCount)
+	assert.Equal(-1, firstKnownIndex)
+	assert.Equal(2, db.GetTransactionsCount(walletId))
+
+	newCount, firstKnownIndex = db.UpdateTransactions(walletId, []currencies.TransactionsHistoryItem{
+		{Hash: "tx3", From: "adr", To: "other", Amount: big.NewInt(-30), Time: time.Unix(3000, 0), Status: currencies.TransactionPending},
+		{Hash: "tx2", From: "adr", To: "other", Amount: big.NewInt(-20), Time: time.Unix(2000, 0), Fee: big.NewInt(1), BlockHeight: 12, Confirmations: 2},
+	})
+	assert.Equal(1, newCount)
+	assert.Equal(1, firstKnownIndex)
+	assert.Equal(3, db.GetTransactionsCount(walletId))
+
+	{
+		history := db.GetTransactionsHistory(walletId, 0, 10)
+		assert.Equal(3, len(history))
+		assert.Equal("tx3", history[0].Hash)
+		assert.Equal(currencies.TransactionPending, history[0].Status)
+		assert.Nil(history[0].Fee)
+		assert.Equal("tx2", history[1].Hash)
+		assert.Equal(int64(2), history[1].Confirmations)
+		assert.Equal(int64(12), history[1].BlockHeight)
+		assert.Equal(big.NewInt(1), history[1].Fee)
+		assert.Equal(big.NewInt(-20), history[1].Amount)
+		assert.Equal("adr", history[1].From)
+		assert.Equal("other", history[1].To)
+		assert.Equal(int64(2000), history[1].Time.Unix())
+		assert.Equal("tx1", history[2].Hash)
+	}
+
+	{
+		history := db.GetTransactionsHistory(walletId, 1, 1)
+		assert.Equal(1, len(history))
+		assert.Equal("tx2", history[0].Hash)
+	}
+
+	assert.Equal(3, len(db.GetTransactionsHistory(walletId, 0, 0)))
+
+	db.SetWalletHistorySynced(walletId, true)
+	assert.True(db.IsWalletHistorySynced(walletId))
+	db.SetWalletHistorySynced(walletId, false)
+	assert.False(db.IsWalletHistorySynced(walletId))
+
+	assert.Equal(0, db.GetHistorySyncOffset(walletId))
+	db.SetHistorySyncOffset(walletId, 150)
+	db.SetHistorySyncOffset(walletId, 200)
+	assert.Equal(200, db.GetHistorySyncOffset(walletId))
+}
+
+func TestPriceAlerts(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId1 := db.GetUserId(123, "")
+	userId2 := db.GetUserId(321, "")
+
+	assert.Equal(0, len(db.GetAllPriceAlerts()))
+
+	alertId1 := db.CreatePriceAlert(currencies.PriceAlert{
+		UserId: userId1,
+		PriceId: "bitcoin",
+		Fiat: "eur",
+		Direction: currencies.PriceAlertAbove,
+		Threshold: big.NewFloat(7000.5),
+	})
+
+	alertId2 := db.CreatePriceAlert(currencies.PriceAlert{
+		UserId: userId2,
+		PriceId: "ripple",
+		Fiat: "usd",
+		Direction: currencies.PriceAlertBelow,
+		Threshold: big.NewFloat(0.25),
+		Cooldown: time.Hour,
+	})
+
+	{
+		alerts := db.GetUserPriceAlerts(userId1)
+		assert.Equal(1, len(alerts))
+		assert.Equal(alertId1, alerts[0].AlertId)
+		assert.Equal(userId1, alerts[0].UserId)
+		assert.Equal("bitcoin", alerts[0].PriceId)
+		assert.Equal("eur", alerts[0].Fiat)
+		assert.Equal(currencies.PriceAlertAbove, alerts[0].Direction)
+		assert.Equal("7000.5", alerts[0].Threshold.Text('f', -1))
+		assert.True(alerts[0].IsOneShot())
+		assert.True(alerts[0].LastTriggerTime.IsZero())
+	}
+
+	assert.True(db.IsPriceAlertBelongsToUser(userId2, alertId2))
+	assert.False(db.IsPriceAlertBelongsToUser(userId1, alertId2))
+
+	db.SetPriceAlertTriggered(alertId2, time.Unix(1536900000, 0))
+
+	{
+		alerts := db.GetAllPriceAlerts()
+		assert.Equal(2, len(alerts))
+		assert.Equal(alertId2, alerts[1].AlertId)
+		assert.Equal(currencies.PriceAlertBelow, alerts[1].Direction)
+		assert.Equal(time.Hour, alerts[1].Cooldown)
+		assert.Equal(int64(1536900000), alerts[1].LastTriggerTime.Unix())
+	}
+
+	{
+		priceIds, fiats := db.GetPriceAlertsRateIds()
+		assert.ElementsMatch([]string{"bitcoin", "ripple"}, priceIds)
+		assert.ElementsMatch([]string{"eur", "usd"}, fiats)
+	}
+
+	db.DeletePriceAlert(alertId1)
+	assert.Equal(0, len(db.GetUserPriceAlerts(userId1)))
+	assert.Equal(1, len(db.GetUserPriceAlerts(userId2)))
+}
+
+func TestDigests(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId1 := db.GetUserId(123, "")
+	userId2 := db.GetUserId(321, "")
+
+	assert.Equal(currencies.DigestDisabled, db.GetUserDigest(userId1).Period)
+	assert.Equal(0, len(db.GetAllDigests()))
+
+	enableTime := time.Unix(1536900000, 0)
+
+	db.SetUserDigest(userId1, currencies.DigestSettings{Period: currencies.DigestDaily, Hour: 9}, enableTime)
+	db.SetUserDigest(userId2, currencies.DigestSettings{Period: currencies.DigestWeekly, Hour: 21}, enableTime)
+
+	assert.Equal(currencies.DigestSettings{Period: currencies.DigestDaily, Hour: 9}, db.GetUserDigest(userId1))
+	assert.Equal(currencies.DigestSettings{Period: currencies.DigestWeekly, Hour: 21}, db.GetUserDigest(userId2))
+
+	{
+		digests := db.GetAllDigests()
+		assert.Equal(2, len(digests))
+		if len(digests) > 0 {
+			assert.Equal(enableTime, digests[0].LastSentTime)
+		}
+	}
+
+	// changing of the schedule
+	db.SetUserDigest(userId1, currencies.DigestSettings{Period: currencies.DigestDaily, Hour: 18}, enableTime)
+	assert.Equal(18, db.GetUserDigest(userId1).Hour)
+	assert.Equal(2, len(db.GetAllDigests()))
+
+	{
+		coins, _ := db.GetLastDigestCoins(userId1)
+		assert.Equal(0, len(coins))
+	}
+
+	sentTime := enableTime.Add(24 * time.Hour)
+	db.SetDigestSent(userId1, sentTime, "eur", []currencies.DigestCoin{
+		currencies.DigestCoin{CoinKey: "0:", Symbol: "BTC", Balance: big.NewFloat(1.5), Value: big.NewFloat(9000.25)},
+		currencies.DigestCoin{CoinKey: "1:", Symbol: "ETH", Balance: big.NewFloat(2)},
+	})
+
+	{
+		coins, fiat := db.GetLastDigestCoins(userId1)
+		assert.Equal("eur", fiat)
+		assert.Equal(2, len(coins))
+		if len(coins) > 1 {
+			assert.Equal("0:", coins[0].CoinKey)
+			assert.Equal("BTC", coins[0].Symbol)
+			assert.Equal("1.5", coins[0].Balance.Text('f', -1))
+			assert.Equal("9000.25", coins[0].Value.Text('f', -1))
+			assert.Equal("ETH", coins[1].Symbol)
+			assert.True(coins[1].Value == nil)
+		}
+	}
+
+	for _, digest := range db.GetAllDigests() {
+		if digest.UserId == userId1 {
+			assert.Equal(sentTime, digest.LastSentTime)
+		} else {
+			assert.Equal(enableTime, digest.LastSentTime)
+		}
+	}
+
+	// the next digest replaces the coins
+	db.SetDigestSent(userId1, sentTime.Add(24 * time.Hour), "eur", []currencies.DigestCoin{
+		currencies.DigestCoin{CoinKey: "1:", Symbol: "ETH", Balance: big.NewFloat(3)},
+	})
+
+	{
+		coins, _ := db.GetLastDigestCoins(userId1)
+		assert.Equal(1, len(coins))
+	}
+
+	db.SetUserDigest(userId2, currencies.DigestSettings{Period: currencies.DigestDisabled}, enableTime)
+	assert.Equal(currencies.DigestDisabled, db.GetUserDigest(userId2).Period)
+	assert.Equal(1, len(db.GetAllDigests()))
+}
+
+func TestQuietHours(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId1 := db.GetUserId(123, "")
+	userId2 := db.GetUserId(321, "")
+
+	assert.False(db.GetUserQuietHours(userId1).IsEnabled())
+
+	db.SetUserQuietHours(userId1, currencies.QuietHours{StartHour: 23, EndHour: 8})
+	assert.Equal(currencies.QuietHours{StartHour: 23, EndHour: 8}, db.GetUserQuietHours(userId1))
+	assert.False(db.GetUserQuietHours(userId2).IsEnabled())
+
+	db.SetUserQuietHours(userId1, currencies.QuietHours{StartHour: 22, EndHour: 7})
+	assert.Equal(currencies.QuietHours{StartHour: 22, EndHour: 7}, db.GetUserQuietHours(userId1))
+
+	db.SetUserQuietHours(userId1, currencies.QuietHours{})
+	assert.False(db.GetUserQuietHours(userId1).IsEnabled())
+}
+
+func TestDeferredNotifications(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId1 := db.GetUserId(123, "")
+	userId2 := db.GetUserId(321, "")
+
+	assert.Equal(0, len(db.GetUsersWithDeferredNotifications()))
+
+	notificationTime := time.Unix(1536900000, 0)
+	db.AddDeferredNotification(userId1, "second 'text'", notificationTime.Add(time.Minute))
+	db.AddDeferredNotification(userId1, "first", notificationTime)
+	db.AddDeferredNotification(userId2, "other", notificationTime)
+
+	assert.ElementsMatch([]int64{userId1, userId2}, db.GetUsersWithDeferredNotifications())
+
+	ids, texts, times := db.GetDeferredNotifications(userId1)
+	assert.Equal(2, len(ids))
+	assert.Equal([]string{"first", "second 'text'"}, texts)
+	assert.Equal([]time.Time{notificationTime, notificationTime.Add(time.Minute)}, times)
+
+	// notifications that came after we read the list stay in the queue
+	db.AddDeferredNotification(userId1, "third", notificationTime.Add(time.Hour))
+	db.DeleteDeferredNotifications(ids)
+
+	_, texts, _ = db.GetDeferredNotifications(userId1)
+	assert.Equal([]string{"third"}, texts)
+
+	_, texts, _ = db.GetDeferredNotifications(userId2)
+	assert.Equal([]string{"other"}, texts)
+}
+
+func TestBalanceSnapshots(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId := db.GetUserId(123, "")
+	db.SetUserFiatCurrency(userId, "eur")
+
+	walletAddress := currencies.AddressData{
+		Currency: currencies.Bitcoin,
+		Address: "key",
+	}
+
+	walletId1 := db.CreateWatchOnlyWallet(userId, "testwallet1", walletAddress)
+	walletId2 := db.CreateWatchOnlyWallet(userId, "testwallet2", walletAddress)
+
+	assert.Equal(map[int64]string{walletId1: "eur", walletId2: "eur"}, db.GetWalletsFiatCurrencies())
+	assert.True(db.GetLastBalanceSnapshotTime().IsZero())
+
+	// hourly snapshots during two days
+	startTime := time.Unix(1536883200, 0) // midnight UTC
+	for hour := 0; hour < 48; hour++ {
+		snapshotTime := startTime.Add(time.Duration(hour) * time.Hour)
+		db.AddBalanceSnapshots([]currencies.BalanceSnapshot{
+			currencies.BalanceSnapshot{WalletId: walletId1, Time: snapshotTime, Balance: big.NewInt(int64(hour)), Rate: big.NewFloat(6000.5), Fiat: "eur"},
+			currencies.BalanceSnapshot{WalletId: walletId2, Time: snapshotTime, Balance: big.NewInt(10), Fiat: "eur"},
+		})
+	}
+
+	lastTime := startTime.Add(47 * time.Hour)
+	assert.Equal(lastTime, db.GetLastBalanceSnapshotTime())
+
+	{
+		snapshots := db.GetBalanceSnapshots([]int64{walletId1}, startTime.Add(46 * time.Hour))
+		assert.Equal(2, len(snapshots))
+		if len(snapshots) > 1 {
+			assert.Equal(walletId1, snapshots[0].WalletId)
+			assert.Equal(big.NewInt(46), snapshots[0].Balance)
+			assert.Equal("6000.5", snapshots[0].Rate.Text('f', -1))
+			assert.Equal("eur", snapshots[0].Fiat)
+			assert.Equal(lastTime, snapshots[1].Time)
+		}
+	}
+
+	assert.Equal(96, len(db.GetBalanceSnapshots([]int64{walletId1, walletId2}, startTime)))
+	assert.True(db.GetBalanceSnapshots([]int64{walletId2}, startTime)[0].Rate == nil)
+
+	// the first day becomes one snapshot per wallet
+	db.DownsampleBalanceSnapshots(startTime.Add(24 * time.Hour), 24 * time.Hour)
+	{
+		snapshots := db.GetBalanceSnapshots([]int64{walletId1}, startTime)
+		assert.Equal(25, len(snapshots))
+		if len(snapshots) > 0 {
+			assert.Equal(big.NewInt(23), snapshots[0].Balance)
+		}
+		assert.Equal(25, len(db.GetBalanceSnapshots([]int64{walletId2}, startTime)))
+	}
+
+	db.DeleteBalanceSnapshotsBefore(startTime.Add(40 * time.Hour))
+	assert.Equal(8, len(db.GetBalanceSnapshots([]int64{walletId1}, startTime)))
+}
+
+func TestTradeLots(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId1 := db.GetUserId(123, "")
+	userId2 := db.GetUserId(321, "")
+
+	assert.Equal(currencies.CostBasisFifo, db.GetUserCostBasisMethod(userId1))
+	db.SetUserCostBasisMethod(userId1, currencies.CostBasisAverage)
+	assert.Equal(currencies.CostBasisAverage, db.GetUserCostBasisMethod(userId1))
+	assert.Equal(currencies.CostBasisFifo, db.GetUserCostBasisMethod(userId2))
+
+	walletAddress := currencies.AddressData{
+		Currency: currencies.Bitcoin,
+		Address: "key",
+	}
+
+	walletId := db.CreateWatchOnlyWallet(userId1, "testwallet", walletAddress)
+
+	lotId1 := db.AddTradeLot(currencies.TradeLot{
+		WalletId: walletId,
+		Amount: big.NewFloat(0.5),
+		Price: big.NewFloat(6500.25),
+		Fiat: "usd",
+		Time: time.Unix(1536900000, 0),
+	})
+
+	db.AddTradeLot(currencies.TradeLot{
+		WalletId: walletId,
+		IsSell: true,
+		Amount: big.NewFloat(0.1),
+		Price: big.NewFloat(7000),
+		Fiat: "usd",
+		Time: time.Unix(1536800000, 0),
+		TransactionHash: "hash1",
+	})
+
+	// the lot of the same transaction is replaced
+	db.AddTradeLot(currencies.TradeLot{
+		WalletId: walletId,
+		IsSell: true,
+		Amount: big.NewFloat(0.1),
+		Price: big.NewFloat(7100),
+		Fiat: "eur",
+		Time: time.Unix(1536800000, 0),
+		TransactionHash: "hash1",
+	})
+
+	{
+		lots := db.GetWalletTradeLots(walletId)
+		assert.Equal(2, len(lots))
+		// sorted by time
+		assert.True(lots[0].IsSell)
+		assert.Equal("7100", lots[0].Price.Text('f', -1))
+		assert.Equal("eur", lots[0].Fiat)
+		assert.Equal("hash1", lots[0].TransactionHash)
+		assert.Equal(lotId1, lots[1].LotId)
+		assert.False(lots[1].IsSell)
+		assert.Equal("0.5", lots[1].Amount.Text('f', -1))
+		assert.Equal("6500.25", lots[1].Price.Text('f', -1))
+		assert.Equal(int64(1536900000), lots[1].Time.Unix())
+		assert.Equal("", lots[1].TransactionHash)
+	}
+
+	{
+		lot, ok := db.GetTransactionTradeLot(walletId, "hash1")
+		assert.True(ok)
+		assert.Equal("7100", lot.Price.Text('f', -1))
+
+		_, ok = db.GetTransactionTradeLot(walletId, "hash2")
+		assert.False(ok)
+	}
+
+	assert.True(db.IsTradeLotBelongsToUser(userId1, lotId1))
+	assert.False(db.IsTradeLotBelongsToUser(userId2, lotId1))
+
+	db.DeleteTradeLot(lotId1)
+	assert.Equal(1, len(db.GetWalletTradeLots(walletId)))
+
+	db.DeleteWallet(walletId)
+	assert.False(db.IsTradeLotBelongsToUser(userId1, lotId1))
+}
+
+func TestCreateWallets(t *testing.T) {
+	assert := require.New(t)
+	db := createDbAndConnect(t)
+	defer clearDb()
+	if db == nil {
+		t.Fail()
+		return
+	}
+	defer db.Disconnect()
+
+	userId := db.GetUserId(123, "")
+
+	assert.Equal(0, len(db.CreateWallets(userId, nil)))
+
+	walletIds := db.CreateWallets(userId, []NewWalletData{
+		NewWalletData{
+			Name: "first's",
+			Address: currencies.AddressData{
+				Currency: currencies.Bitcoin,
+				Address: "addr1",
+				PriceId: "bitcoin",
+			},
+			Type: wallettypes.WatchOnly,
+		},
+		NewWalletData{
+			Name: "second",
+			Address: currencies.AddressData{
+				Currency: currencies.Erc20Token,
+				Address: "addr2",
+				ContractAddress: "contract",
+				PriceId: "token",
+			},
+			Type: wallettypes.HdWatchOnly,
+		},
+	})
+
+	assert.Equal(2, len(walletIds))
+	assert.Equal("first's", db.GetWalletName(walletIds[0]))
+	assert.Equal("second", db.GetWalletName(walletIds[1]))
+	assert.Equal(wallettypes.HdWatchOnly, db.GetWalletType(walletIds[1]))
+	assert.Equal("contract", db.GetWalletAddress(walletIds[1]).ContractAddress)
+	assert.True(db.IsWalletBelongsToUser(userId, walletIds[0]))
+	assert.True(db.IsBalanceNotifiesEnabled(walletIds[0]))
+	assert.True(db.IsBalanceNotifiesEnabled(walletIds[1]))
+
+	// the user doesn't exist, so nothing is created
+	wrongUserId := userId + 100
+	assert.Nil(db.CreateWallets(wrongUserId, []NewWalletData{
+		NewWalletData{
+			Name: "third",
+			Address: currencies.AddressData{Currency: currencies.Bitcoin, Address: "addr3"},
+			Type: wallettypes.WatchOnly,
+		},
+	}))
+	wrongUserWalletIds, _ := db.GetUserWallets(wrongUserId)
+	assert.Equal(0, len(wrongUserWalletIds))
+}
@@ -9,7 +9,7 @@ import (
 
 const (
 	minimalVersion = "0.1"
-	latestVersion  = "0.4"
+	latestVersion  = "0.13"
 )
 
 type dbUpdater struct {
@@ -92,6 +92,98 @@ func makeAllUpdaters() (updaters []dbUpdater) {
 				db.db.Exec("DROP TABLE rates")
 			},
 		},
+		dbUpdater{
+			version: "0.5",
+			updateDb: func(db *AccountDb) {
+				// store Ethereum addresses in EIP-55 checksum form
+				normalizeEthereumAddresses(db)
+			},
+		},
+		dbUpdater{
+			version: "0.6",
+			updateDb: func(db *AccountDb) {
+				// local copy of transactions history
+				createTransactionsTable(db)
+			},
+		},
+		dbUpdater{
+			version: "0.7",
+			updateDb: func(db *AccountDb) {
+				// add new field 'fiat_currency'
+				db.db.Exec("ALTER TABLE users ADD COLUMN fiat_currency TEXT NOT NULL DEFAULT('usd')")
+			},
+		},
+		dbUpdater{
+			version: "0.8",
+			updateDb: func(db *AccountDb) {
+				createPriceAlertsTable(db)
+			},
+		},
+		dbUpdater{
+			version: "0.9",
+			updateDb: func(db *AccountDb) {
+				// filters of balance notifications
+				db.db.Exec("ALTER TABLE balance_notifies ADD COLUMN direction INTEGER NOT NULL DEFAULT(0)")
+				db.db.Exec("ALTER TABLE balance_notifies ADD COLUMN min_change TEXT NOT NULL DEFAULT('')")
+				db.db.Exec("ALTER TABLE balance_notifies ADD COLUMN min_change_fiat TEXT NOT NULL DEFAULT('')")
+			},
+		},
+		dbUpdater{
+			version: "0.10",
+			updateDb: func(db *AccountDb) {
+				createDigestsTables(db)
+			},
+		},
+		dbUpdater{
+			version: "0.11",
+			updateDb: func(db *AccountDb) {
+				createQuietHoursTables(db)
+			},
+		},
+		dbUpdater{
+			version: "0.12",
+			updateDb: func(db *AccountDb) {
+				createBalanceSnapshotsTable(db)
+			},
+		},
+		dbUpdater{
+			version: "0.13",
+			updateDb: func(db *AccountDb) {
+				db.db.Exec("ALTER TABLE users ADD COLUMN cost_basis_method INTEGER NOT NULL DEFAULT(0)")
+				createTradeLotsTable(db)
+			},
+		},
 	}
 	return
 }
+
+func normalizeEthereumAddresses(db *AccountDb) {
+	rows, err := db.db.Query(fmt.Sprintf("SELECT id, currency, address, contract_address FROM wallets WHERE currency IN (%d,%d)", currencies.Ether, currencies.Erc20Token))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	var b bytes.Buffer
+	for rows.Next() {
+		var walletId int64
+		var currency int64
+		var address string
+		var contractAddress string
+
+		err := rows.Scan(&walletId, &currency, &address, &contractAddress)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		b.WriteString(fmt.Sprintf("UPDATE wallets SET address='%s', contract_address='%s' WHERE id=%d;",
+			currencies.NormalizeAddress(currencies.Currency(currency), address),
+			currencies.NormalizeAddress(currencies.Currency(currency), contractAddress),
+			walletId,
+		))
+	}
+	rows.Close()
+
+	if b.Len() > 0 {
+		db.db.Exec(b.String())
+	}
+}
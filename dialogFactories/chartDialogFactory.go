@@ -0,0 +1,263 @@
+package dialogFactories
+
+import (
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/charts"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/serverData"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"image/color"
+	"log"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+const (
+	chartWidth int = 800
+	chartHeight int = 500
+)
+
+var (
+	chartBalanceColor = color.RGBA{52, 120, 200, 255}
+	chartValueColor = color.RGBA{40, 160, 80, 255}
+)
+
+type chartVariantPrototype struct {
+	id string
+	textId string
+	// zero for the buttons that don't show a chart
+	period time.Duration
+	rowId int
+}
+
+type chartDialogFactory struct {
+	variants []chartVariantPrototype
+}
+
+// the dialog is made for a wallet id, or for zero to show the whole portfolio of the user
+func MakeChartDialogFactory() dialogFactory.DialogFactory {
+	return &(chartDialogFactory{
+		variants: []chartVariantPrototype{
+			chartVariantPrototype{
+				id: "7d",
+				textId: "chart_week",
+				period: 7 * 24 * time.Hour,
+				rowId: 1,
+			},
+			chartVariantPrototype{
+				id: "30d",
+				textId: "chart_month",
+				period: 30 * 24 * time.Hour,
+				rowId: 1,
+			},
+			chartVariantPrototype{
+				id: "1y",
+				textId: "chart_year",
+				period: 365 * 24 * time.Hour,
+				rowId: 1,
+			},
+			chartVariantPrototype{
+				id: "back",
+				textId: "back_btn",
+				rowId: 2,
+			},
+		},
+	})
+}
+
+func getFloatOrNaN(value *big.Float) float64 {
+	if value == nil {
+		return math.NaN()
+	}
+	floatValue, _ := value.Float64()
+	return floatValue
+}
+
+// the range of the values is shown in the caption instead of the axis labels
+func formatChartRange(points []charts.Point, digits int, unit string, trans i18n.TranslateFunc) string {
+	minValue, maxValue, ok := charts.GetValueRange(points)
+	if !ok {
+		return trans("no_data")
+	}
+
+	return cryptoFunctions.FormatFloatCurrencyAmount(big.NewFloat(minValue), digits) + " – " +
+		cryptoFunctions.FormatFloatCurrencyAmount(big.NewFloat(maxValue), digits) + " " + unit
+}
+
+func sendWalletChart(walletId int64, period time.Duration, data *processing.ProcessData) bool {
+	db := staticFunctions.GetDb(data.Static)
+
+	serverData := serverData.GetServerData(data.Static)
+	if serverData == nil {
+		return false
+	}
+
+	walletAddress := db.GetWalletAddress(walletId)
+	currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+	fiat := db.GetUserFiatCurrency(data.UserId)
+
+	snapshots := db.GetBalanceSnapshots([]int64{walletId}, time.Now().Add(-period))
+	if len(snapshots) < 2 {
+		data.SendMessage(data.Trans("chart_no_data"))
+		return true
+	}
+
+	balancePoints := make([]charts.Point, 0, len(snapshots))
+	valuePoints := make([]charts.Point, 0, len(snapshots))
+
+	for _, snapshot := range snapshots {
+		balance := cryptoFunctions.GetFloatBalance(snapshot.Balance, currencyDecimals)
+
+		balancePoints = append(balancePoints, charts.Point{
+			Time: snapshot.Time,
+			Value: getFloatOrNaN(balance),
+		})
+
+		// the values in other currencies can't be shown on the same chart
+		var value *big.Float
+		if snapshot.Rate != nil && snapshot.Fiat == fiat {
+			value = new(big.Float).Mul(balance, snapshot.Rate)
+		}
+
+		valuePoints = append(valuePoints, charts.Point{
+			Time: snapshot.Time,
+			Value: getFloatOrNaN(value),
+		})
+	}
+
+	imageData, err := charts.RenderPng([]charts.Panel{
+		charts.Panel{Points: balancePoints, Color: chartBalanceColor},
+		charts.Panel{Points: valuePoints, Color: chartValueColor},
+	}, chartWidth, chartHeight)
+
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+
+	caption := data.Trans("chart_wallet_caption", map[string]interface{}{
+		"Name":    db.GetWalletName(walletId),
+		"Balance": formatChartRange(balancePoints, currencyDecimals, currencySymbol, data.Trans),
+		"Value":   formatChartRange(valuePoints, 2, currencies.GetFiatCurrencyCode(fiat), data.Trans),
+	})
+
+	staticFunctions.SendPhoto(data.Static, data.ChatId, "chart.png", imageData, caption)
+	return true
+}
+
+// sums the values of all the wallets of the user, the time points are the same for all the wallets of one snapshot
+func sendPortfolioChart(period time.Duration, data *processing.ProcessData) bool {
+	db := staticFunctions.GetDb(data.Static)
+
+	serverData := serverData.GetServerData(data.Static)
+	if serverData == nil {
+		return false
+	}
+
+	fiat := db.GetUserFiatCurrency(data.UserId)
+	walletIds, _ := db.GetUserWallets(data.UserId)
+
+	walletDecimals := make(map[int64]int)
+	for _, walletId := range walletIds {
+		walletAddress := db.GetWalletAddress(walletId)
+		_, walletDecimals[walletId] = staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+	}
+
+	valuePoints := make([]charts.Point, 0)
+
+	for _, snapshot := range db.GetBalanceSnapshots(walletIds, time.Now().Add(-period)) {
+		var value *big.Float
+		if snapshot.Rate != nil && snapshot.Fiat == fiat {
+			value = new(big.Float).Mul(cryptoFunctions.GetFloatBalance(snapshot.Balance, walletDecimals[snapshot.WalletId]), snapshot.Rate)
+		}
+
+		lastIndex := len(valuePoints) - 1
+		if lastIndex >= 0 && valuePoints[lastIndex].Time.Equal(snapshot.Time) {
+			// unknown value of one wallet makes the sum unknown
+			valuePoints[lastIndex].Value += getFloatOrNaN(value)
+		} else {
+			valuePoints = append(valuePoints, charts.Point{
+				Time: snapshot.Time,
+				Value: getFloatOrNaN(value),
+			})
+		}
+	}
+
+	if len(valuePoints) < 2 {
+		data.SendMessage(data.Trans("chart_no_data"))
+		return true
+	}
+
+	imageData, err := charts.RenderPng([]charts.Panel{
+		charts.Panel{Points: valuePoints, Color: chartValueColor},
+	}, chartWidth, chartHeight / 2)
+
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+
+	caption := data.Trans("chart_portfolio_caption", map[string]interface{}{
+		"Value": formatChartRange(valuePoints, 2, currencies.GetFiatCurrencyCode(fiat), data.Trans),
+	})
+
+	staticFunctions.SendPhoto(data.Static, data.ChatId, "chart.png", imageData, caption)
+	return true
+}
+
+func (factory *chartDialogFactory) createVariants(walletId int64, trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	for _, variant := range factory.variants {
+		variants = append(variants, dialog.Variant{
+			Id:   variant.id,
+			Text: trans(variant.textId),
+			AdditionalId: strconv.FormatInt(walletId, 10),
+			RowId: variant.rowId,
+		})
+	}
+	return
+}
+
+func (factory *chartDialogFactory) MakeDialog(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	return &dialog.Dialog{
+		Text:     trans("choose_chart_period"),
+		Variants: factory.createVariants(walletId, trans),
+	}
+}
+
+func (factory *chartDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	walletId, err := strconv.ParseInt(additionalId, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	if walletId != 0 && !staticFunctions.GetDb(data.Static).IsWalletBelongsToUser(data.UserId, walletId) {
+		return false
+	}
+
+	for _, variant := range factory.variants {
+		if variant.id != variantId {
+			continue
+		}
+
+		if variant.period == 0 {
+			if walletId == 0 {
+				return backToList(walletId, data)
+			}
+			return backToWallet(walletId, data)
+		}
+
+		if walletId == 0 {
+			return sendPortfolioChart(variant.period, data)
+		}
+		return sendWalletChart(walletId, variant.period, data)
+	}
+	return false
+}
@@ -51,6 +51,11 @@ func MakeChooseCurrencyDialogFactory() dialogFactory.DialogFactory {
 				currencyId: currencies.Erc20Token,
 				rowId: 3,
 			},
+			chooseCurrencyItemVariantPrototype{
+				id: "ltc",
+				currencyId: currencies.Litecoin,
+				rowId: 4,
+			},
 		},
 	})
 }
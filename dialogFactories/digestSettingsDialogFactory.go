@@ -0,0 +1,181 @@
+package dialogFactories
+
+import (
+	"fmt"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"strconv"
+	"time"
+)
+
+// hours of the day that can be chosen to get digests at
+var digestHours = []int{7, 9, 12, 18, 21}
+
+type digestSettingsVariantPrototype struct {
+	isListItem bool
+	id string
+	textId string
+	period currencies.DigestPeriod
+	process func(currencies.DigestPeriod, *processing.ProcessData) bool
+	rowId int
+	isActiveFn func(currencies.DigestSettings) bool
+}
+
+type digestSettingsDialogFactory struct {
+	variants []digestSettingsVariantPrototype
+}
+
+func MakeDigestSettingsDialogFactory() dialogFactory.DialogFactory {
+	return &(digestSettingsDialogFactory{
+		variants: []digestSettingsVariantPrototype{
+			digestSettingsVariantPrototype{
+				id: "off",
+				textId: "digest_off",
+				period: currencies.DigestDisabled,
+				process: setDigestPeriod,
+				rowId: 1,
+			},
+			digestSettingsVariantPrototype{
+				id: "day",
+				textId: "digest_daily",
+				period: currencies.DigestDaily,
+				process: setDigestPeriod,
+				rowId: 1,
+			},
+			digestSettingsVariantPrototype{
+				id: "week",
+				textId: "digest_weekly",
+				period: currencies.DigestWeekly,
+				process: setDigestPeriod,
+				rowId: 1,
+			},
+			digestSettingsVariantPrototype{
+				isListItem: true,
+				id: "hr",
+				rowId: 2,
+				isActiveFn: isDigestEnabled,
+			},
+			digestSettingsVariantPrototype{
+				id: "back",
+				textId: "back_btn",
+				process: backToUserSettings,
+				rowId: 3,
+			},
+		},
+	})
+}
+
+func isDigestEnabled(settings currencies.DigestSettings) bool {
+	return settings.Period != currencies.DigestDisabled
+}
+
+func formatDigestHour(hour int) string {
+	return fmt.Sprintf("%02d:00", hour)
+}
+
+func getDigestDescription(settings currencies.DigestSettings, trans i18n.TranslateFunc) string {
+	switch settings.Period {
+	case currencies.DigestDaily:
+		return trans("digest_daily_description", map[string]interface{}{
+			"Time": formatDigestHour(settings.Hour),
+		})
+	case currencies.DigestWeekly:
+		return trans("digest_weekly_description", map[string]interface{}{
+			"Time": formatDigestHour(settings.Hour),
+		})
+	default:
+		return trans("digest_off")
+	}
+}
+
+func applyDigestSettings(data *processing.ProcessData, settings currencies.DigestSettings) bool {
+	// the digests are counted from now, so we don't send one for the time that is already passed
+	staticFunctions.GetDb(data.Static).SetUserDigest(data.UserId, settings, time.Now())
+	data.SubstitudeDialog(data.Static.MakeDialogFn("ds", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func setDigestPeriod(period currencies.DigestPeriod, data *processing.ProcessData) bool {
+	settings := staticFunctions.GetDb(data.Static).GetUserDigest(data.UserId)
+
+	if !isDigestEnabled(settings) {
+		settings.Hour = currencies.DefaultDigestHour
+	}
+	settings.Period = period
+	return applyDigestSettings(data, settings)
+}
+
+func setDigestHour(hour int, data *processing.ProcessData) bool {
+	settings := staticFunctions.GetDb(data.Static).GetUserDigest(data.UserId)
+
+	if hour < 0 || hour > 23 || !isDigestEnabled(settings) {
+		return false
+	}
+
+	settings.Hour = hour
+	return applyDigestSettings(data, settings)
+}
+
+func backToUserSettings(period currencies.DigestPeriod, data *processing.ProcessData) bool {
+	data.SubstitudeDialog(data.Static.MakeDialogFn("us", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func (factory *digestSettingsDialogFactory) createVariants(settings currencies.DigestSettings, trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	for _, variant := range factory.variants {
+		if variant.isActiveFn != nil && !variant.isActiveFn(settings) {
+			continue
+		}
+
+		if variant.isListItem {
+			for _, hour := range digestHours {
+				variants = append(variants, dialog.Variant{
+					Id:   variant.id + strconv.Itoa(hour),
+					Text: formatDigestHour(hour),
+					RowId: variant.rowId,
+				})
+			}
+		} else {
+			variants = append(variants, dialog.Variant{
+				Id:   variant.id,
+				Text: trans(variant.textId),
+				RowId: variant.rowId,
+			})
+		}
+	}
+	return
+}
+
+func (factory *digestSettingsDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	settings := staticFunctions.GetDb(staticData).GetUserDigest(userId)
+
+	return &dialog.Dialog{
+		Text:     trans("digest_settings_title", map[string]interface{}{
+			"Digest": getDigestDescription(settings, trans),
+		}),
+		Variants: factory.createVariants(settings, trans),
+	}
+}
+
+func (factory *digestSettingsDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	for _, variant := range factory.variants {
+		if variant.isListItem {
+			if len(variantId) > 2 && variant.id == variantId[0:2] {
+				hour, err := strconv.Atoi(variantId[2:])
+				if err != nil {
+					return false
+				}
+				return setDigestHour(hour, data)
+			}
+		} else if variant.id == variantId {
+			return variant.process(variant.period, data)
+		}
+	}
+	return false
+}
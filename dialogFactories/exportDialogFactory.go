@@ -0,0 +1,206 @@
+package dialogFactories
+
+import (
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/reports"
+	"github.com/gameraccoon/telegram-accountant-bot/serverData"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"log"
+	"math/big"
+	"time"
+)
+
+type exportVariantPrototype struct {
+	id string
+	textId string
+	process func(*reports.Export, *processing.ProcessData) bool
+	rowId int
+}
+
+type exportDialogFactory struct {
+	variants []exportVariantPrototype
+}
+
+func MakeExportDialogFactory() dialogFactory.DialogFactory {
+	return &(exportDialogFactory{
+		variants: []exportVariantPrototype{
+			exportVariantPrototype{
+				id: "csv",
+				textId: "export_csv",
+				process: sendExportCsv,
+				rowId: 1,
+			},
+			exportVariantPrototype{
+				id: "json",
+				textId: "export_json",
+				process: sendExportJson,
+				rowId: 1,
+			},
+		},
+	})
+}
+
+func getTransactionStatusName(status currencies.TransactionStatus) string {
+	switch status {
+	case currencies.TransactionPending:
+		return "pending"
+	case currencies.TransactionFailed:
+		return "failed"
+	default:
+		return "confirmed"
+	}
+}
+
+func makeExportTransactions(history []currencies.TransactionsHistoryItem, walletAddress *currencies.AddressData, currencyDecimals int) (transactions []reports.ExportTransaction) {
+	transactions = make([]reports.ExportTransaction, 0, len(history))
+
+	feeCurrency := currencies.GetFeeCurrency(walletAddress.Currency)
+	feeDecimals := currencyDecimals
+	if feeCurrency != walletAddress.Currency {
+		feeDecimals = currencies.GetCurrencyDecimals(feeCurrency)
+	}
+
+	for i := range history {
+		item := &history[i]
+
+		transaction := reports.ExportTransaction{
+			Hash: item.Hash,
+			Time: item.Time.UTC(),
+			IsSent: isHistoryItemSent(item, walletAddress),
+			From: item.From,
+			To: item.To,
+			Amount: cryptoFunctions.FormatCurrencyAmount(item.Amount, currencyDecimals),
+			Status: getTransactionStatusName(item.Status),
+		}
+
+		if item.Fee != nil {
+			fee := cryptoFunctions.FormatCurrencyAmount(item.Fee, feeDecimals)
+			transaction.Fee = &fee
+		}
+
+		transactions = append(transactions, transaction)
+	}
+	return
+}
+
+// all the user's wallets with the cached balances and the stored history
+func makeUserExport(userId int64, staticData *processing.StaticProccessStructs) (export *reports.Export, ok bool) {
+	db := staticFunctions.GetDb(staticData)
+
+	serverData := serverData.GetServerData(staticData)
+	if serverData == nil {
+		return
+	}
+
+	fiat := db.GetUserFiatCurrency(userId)
+
+	export = &reports.Export{
+		Time: time.Now().UTC(),
+		Fiat: currencies.GetFiatCurrencyCode(fiat),
+		Wallets: make([]reports.ExportWallet, 0),
+	}
+
+	walletIds, walletNames := db.GetUserWallets(userId)
+	for i, walletId := range walletIds {
+		walletAddress := db.GetWalletAddress(walletId)
+		currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+
+		wallet := reports.ExportWallet{
+			Name: walletNames[i],
+			Symbol: currencySymbol,
+			Address: walletAddress.Address,
+			ContractAddress: walletAddress.ContractAddress,
+			PriceId: walletAddress.PriceId,
+			Transactions: makeExportTransactions(db.GetTransactionsHistory(walletId, 0, 0), &walletAddress, currencyDecimals),
+		}
+
+		// unknown balances stay nil to not to be confused with zero
+		if floatBalance := cryptoFunctions.GetFloatBalance(serverData.GetBalance(walletAddress), currencyDecimals); floatBalance != nil {
+			balance := cryptoFunctions.FormatFloatCurrencyAmount(floatBalance, currencyDecimals)
+			wallet.Balance = &balance
+
+			if rate := serverData.GetRate(walletAddress.PriceId, fiat); rate != nil {
+				value := new(big.Float).Mul(floatBalance, rate).Text('f', 2)
+				wallet.Value = &value
+			}
+		}
+
+		export.Wallets = append(export.Wallets, wallet)
+	}
+
+	ok = true
+	return
+}
+
+func sendExportCsv(export *reports.Export, data *processing.ProcessData) bool {
+	walletsCsv, err := reports.MakeWalletsCsv(export)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+
+	transactionsCsv, err := reports.MakeTransactionsCsv(export, getUserLocation(data.UserId, data.Static))
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+
+	staticFunctions.SendDocument(data.Static, data.ChatId, "wallets.csv", walletsCsv, data.Trans("export_wallets_caption"))
+	staticFunctions.SendDocument(data.Static, data.ChatId, "transactions.csv", transactionsCsv, data.Trans("export_transactions_caption"))
+	return true
+}
+
+func sendExportJson(export *reports.Export, data *processing.ProcessData) bool {
+	jsonData, err := reports.MakeExportJson(export)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+
+	staticFunctions.SendDocument(data.Static, data.ChatId, "wallets.json", jsonData, data.Trans("export_json_caption"))
+	return true
+}
+
+func (factory *exportDialogFactory) createVariants(trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	for _, variant := range factory.variants {
+		variants = append(variants, dialog.Variant{
+			Id:   variant.id,
+			Text: trans(variant.textId),
+			RowId: variant.rowId,
+		})
+	}
+	return
+}
+
+func (factory *exportDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	return &dialog.Dialog{
+		Text:     trans("export_title"),
+		Variants: factory.createVariants(trans),
+	}
+}
+
+func (factory *exportDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	for _, variant := range factory.variants {
+		if variant.id == variantId {
+			export, ok := makeUserExport(data.UserId, data.Static)
+			if !ok {
+				return false
+			}
+
+			if len(export.Wallets) == 0 {
+				data.SendMessage(data.Trans("export_no_wallets"))
+				return true
+			}
+
+			return variant.process(export, data)
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package dialogFactories
+
+import (
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+)
+
+type fiatCurrencySelectDialogFactory struct {
+}
+
+func MakeFiatCurrencySelectDialogFactory() dialogFactory.DialogFactory {
+	return &(fiatCurrencySelectDialogFactory{})
+}
+
+func applyNewFiatCurrency(data *processing.ProcessData, newFiat string) bool {
+	if !currencies.IsFiatCurrencySupported(newFiat) {
+		return false
+	}
+
+	staticFunctions.GetDb(data.Static).SetUserFiatCurrency(data.UserId, newFiat)
+	data.SubstitudeDialog(data.Static.MakeDialogFn("us", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func (factory *fiatCurrencySelectDialogFactory) createVariants() (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	itemsInRow := 3
+
+	for itemId, fiat := range currencies.GetAllFiatCurrencies() {
+		variants = append(variants, dialog.Variant{
+			Id:   fiat,
+			Text: currencies.GetFiatCurrencyCode(fiat),
+			RowId: itemId / itemsInRow + 1,
+		})
+	}
+	return
+}
+
+func (factory *fiatCurrencySelectDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	return &dialog.Dialog{
+		Text:     trans("select_fiat_currency"),
+		Variants: factory.createVariants(),
+	}
+}
+
+func (factory *fiatCurrencySelectDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	return applyNewFiatCurrency(data, variantId)
+}
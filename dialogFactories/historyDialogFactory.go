@@ -11,21 +11,31 @@ import (
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"github.com/gameraccoon/telegram-accountant-bot/serverData"
 	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"log"
 	"strconv"
 	"strings"
 )
 
-const (
-	maxHistoryRecords = 25
-)
+const historyRecordsOnPage int = 10
 
 type historyVariantPrototype struct {
+	isListItem bool
 	id string
 	textId string
 	process func(int64, *processing.ProcessData) bool
+	// for list items, receives the index of the item on the page
+	processItem func(int64, int, *processing.ProcessData) bool
+	// nil if the variant is always active
+	isActiveFn func(*historyPageData) bool
 	rowId int
 }
 
+type historyPageData struct {
+	history []currencies.TransactionsHistoryItem
+	currentPage int
+	hasOlderRecords bool
+}
+
 type historyDialogFactory struct {
 	variants []historyVariantPrototype
 }
@@ -33,17 +43,139 @@ type historyDialogFactory struct {
 func MakeHistoryDialogFactory() dialogFactory.DialogFactory {
 	return &(historyDialogFactory{
 		variants: []historyVariantPrototype{
+			historyVariantPrototype{
+				isListItem: true,
+				id: "it",
+				processItem: showTransaction,
+			},
+			historyVariantPrototype{
+				id: "new",
+				textId: "back_btn",
+				process: showNewerHistory,
+				isActiveFn: hasNewerHistory,
+				rowId:1,
+			},
+			historyVariantPrototype{
+				id: "old",
+				textId: "fwd_btn",
+				process: showOlderHistory,
+				isActiveFn: hasOlderHistory,
+				rowId:1,
+			},
 			historyVariantPrototype{
 				id: "back",
 				textId: "back_to_wallet",
 				process: backToWallet, // declared in walletSettingsDialogFactory.go
-				rowId:1,
+				rowId:2,
 			},
 		},
 	})
 }
 
-func (factory *historyDialogFactory) createText(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) string {
+func hasNewerHistory(pageData *historyPageData) bool {
+	return pageData.currentPage > 0
+}
+
+func hasOlderHistory(pageData *historyPageData) bool {
+	return pageData.hasOlderRecords
+}
+
+// the history state is kept per wallet, so the dialogs of different wallets don't get the pages of each other
+func getHistoryStateKey(name string, walletId int64) string {
+	return name + strconv.FormatInt(walletId, 10)
+}
+
+func getHistoryPage(userId int64, walletId int64, staticData *processing.StaticProccessStructs) int {
+	page, ok := staticData.GetUserStateValue(userId, getHistoryStateKey("historyPage", walletId)).(int)
+	if !ok {
+		return 0
+	}
+	return page
+}
+
+func setHistoryPage(userId int64, walletId int64, page int, staticData *processing.StaticProccessStructs) {
+	staticData.SetUserStateValue(userId, getHistoryStateKey("historyPage", walletId), page)
+}
+
+// the transaction opened from the history of the wallet
+func getHistoryTransaction(userId int64, walletId int64, staticData *processing.StaticProccessStructs) (item currencies.TransactionsHistoryItem, ok bool) {
+	item, ok = staticData.GetUserStateValue(userId, getHistoryStateKey("historyTransaction", walletId)).(currencies.TransactionsHistoryItem)
+	return
+}
+
+func showNewerHistory(walletId int64, data *processing.ProcessData) bool {
+	currentPage := getHistoryPage(data.UserId, walletId, data.Static)
+	if currentPage > 0 {
+		setHistoryPage(data.UserId, walletId, currentPage - 1, data.Static)
+	}
+	data.SubstitudeDialog(data.Static.MakeDialogFn("hi", walletId, data.Trans, data.Static))
+	return true
+}
+
+func showOlderHistory(walletId int64, data *processing.ProcessData) bool {
+	setHistoryPage(data.UserId, walletId, getHistoryPage(data.UserId, walletId, data.Static) + 1, data.Static)
+	data.SubstitudeDialog(data.Static.MakeDialogFn("hi", walletId, data.Trans, data.Static))
+	return true
+}
+
+func showTransaction(walletId int64, itemIndex int, data *processing.ProcessData) bool {
+	pageItems, ok := data.Static.GetUserStateValue(data.UserId, getHistoryStateKey("historyItems", walletId)).([]currencies.TransactionsHistoryItem)
+	if !ok || itemIndex < 0 || itemIndex >= len(pageItems) {
+		return false
+	}
+
+	data.Static.SetUserStateValue(data.UserId, getHistoryStateKey("historyTransaction", walletId), pageItems[itemIndex])
+	data.SubstitudeDialog(data.Static.MakeDialogFn("tx", walletId, data.Trans, data.Static))
+	return true
+}
+
+func getHistoryPageData(walletId int64, staticData *processing.StaticProccessStructs) (pageData *historyPageData) {
+	db := staticFunctions.GetDb(staticData)
+
+	pageData = &historyPageData{
+		currentPage: getHistoryPage(db.GetWalletOwner(walletId), walletId, staticData),
+	}
+
+	walletAddress := db.GetWalletAddress(walletId)
+
+	if !currencies.IsHistoryEnabled(walletAddress.Currency) {
+		return
+	}
+
+	// request one record more to know if there is the next page
+	pageData.history = db.GetTransactionsHistory(walletId, pageData.currentPage * historyRecordsOnPage, historyRecordsOnPage + 1)
+
+	// the wallet wasn't synced yet, ask the provider directly
+	if len(pageData.history) == 0 && db.GetTransactionsCount(walletId) == 0 && !db.IsWalletHistorySynced(walletId) {
+		processor := cryptoFunctions.GetProcessor(walletAddress.Currency)
+
+		if processor == nil {
+			return
+		}
+
+		history, err := (*processor).GetTransactionsHistory(walletAddress, pageData.currentPage * historyRecordsOnPage, historyRecordsOnPage + 1)
+		if err != nil {
+			log.Print(err)
+		}
+		pageData.history = history
+	}
+
+	if len(pageData.history) > historyRecordsOnPage {
+		pageData.hasOlderRecords = true
+		pageData.history = pageData.history[:historyRecordsOnPage]
+	}
+
+	// remember what is shown to open details of the exact transaction the user taps on
+	staticData.SetUserStateValue(db.GetWalletOwner(walletId), getHistoryStateKey("historyItems", walletId), pageData.history)
+
+	return
+}
+
+func isHistoryItemSent(item *currencies.TransactionsHistoryItem, walletAddress *currencies.AddressData) bool {
+	return strings.EqualFold(item.From, walletAddress.Address)
+}
+
+func (factory *historyDialogFactory) createText(walletId int64, pageData *historyPageData, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) string {
 	serverData := serverData.GetServerData(staticData)
 
 	if serverData == nil {
@@ -58,32 +190,47 @@ func (factory *historyDialogFactory) createText(walletId int64, trans i18n.Trans
 
 	walletAddress := db.GetWalletAddress(walletId)
 
-	processor := cryptoFunctions.GetProcessor(walletAddress.Currency)
+	history := pageData.history
 
-	if processor != nil {
-		if currencies.IsHistoryEnabled(walletAddress.Currency) {
-			history := (*processor).GetTransactionsHistory(walletAddress, 25)
+	if len(history) == 0 {
+		if pageData.currentPage == 0 {
+			textBuffer.WriteString(trans("history_empty"))
+		} else {
+			textBuffer.WriteString(trans("history_page_empty"))
+		}
+		return textBuffer.String()
+	}
 
-			if (len(history) == maxHistoryRecords) {
-				textBuffer.WriteString(fmt.Sprintf(trans("history_cut_title"), len(history)))
-			} else {
-				textBuffer.WriteString(fmt.Sprintf(trans("history_title")))
-			}
-			
-			for i := len(history)-1; i >= 0; i-- {
-				item := history[i]
-				textBuffer.WriteString("\n\n")
+	if pageData.currentPage == 0 && !pageData.hasOlderRecords {
+		textBuffer.WriteString(trans("history_title"))
+	} else {
+		firstRecord := pageData.currentPage * historyRecordsOnPage + 1
+		textBuffer.WriteString(fmt.Sprintf(trans("history_page_title"), firstRecord, firstRecord + len(history) - 1))
+	}
 
-				textBuffer.WriteString(staticFunctions.FormatTimestamp(item.Time, userTimezone))
+	currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
 
-				currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
-				amountText := cryptoFunctions.FormatCurrencyAmount(item.Amount, currencyDecimals)
+	for i := len(history)-1; i >= 0; i-- {
+		item := history[i]
+		textBuffer.WriteString("\n\n")
 
-				if strings.EqualFold(item.From, walletAddress.Address) {
-					textBuffer.WriteString(fmt.Sprintf(trans("sent_format"), amountText, currencySymbol, item.To))
-				} else if strings.EqualFold(item.To, walletAddress.Address) {
-					textBuffer.WriteString(fmt.Sprintf(trans("recieved_format"), amountText, currencySymbol, item.From))
-				}
+		textBuffer.WriteString(fmt.Sprintf("%d. ", len(history) - i))
+
+		textBuffer.WriteString(staticFunctions.FormatTimestamp(item.Time, userTimezone))
+
+		amountText := cryptoFunctions.FormatCurrencyAmount(item.Amount, currencyDecimals)
+
+		if isHistoryItemSent(&item, &walletAddress) {
+			if item.To != "" {
+				textBuffer.WriteString(fmt.Sprintf(trans("sent_format"), amountText, currencySymbol, item.To))
+			} else {
+				textBuffer.WriteString(fmt.Sprintf(trans("sent_short_format"), amountText, currencySymbol))
+			}
+		} else if strings.EqualFold(item.To, walletAddress.Address) {
+			if item.From != "" {
+				textBuffer.WriteString(fmt.Sprintf(trans("recieved_format"), amountText, currencySymbol, item.From))
+			} else {
+				textBuffer.WriteString(fmt.Sprintf(trans("recieved_short_format"), amountText, currencySymbol))
 			}
 		}
 	}
@@ -91,24 +238,56 @@ func (factory *historyDialogFactory) createText(walletId int64, trans i18n.Trans
 	return textBuffer.String()
 }
 
-func (factory *historyDialogFactory) createVariants(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) (variants []dialog.Variant) {
+func (factory *historyDialogFactory) createVariants(walletId int64, pageData *historyPageData, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) (variants []dialog.Variant) {
 	variants = make([]dialog.Variant, 0)
 
+	serverData := serverData.GetServerData(staticData)
+	walletAddress := staticFunctions.GetDb(staticData).GetWalletAddress(walletId)
+
+	// every transaction takes its own row above the other buttons
+	itemsCount := len(pageData.history)
+
 	for _, variant := range factory.variants {
-		variants = append(variants, dialog.Variant{
-			Id:   variant.id,
-			Text: trans(variant.textId),
-			AdditionalId: strconv.FormatInt(walletId, 10),
-			RowId: variant.rowId,
-		})
+		if variant.isListItem {
+			if serverData == nil {
+				continue
+			}
+
+			currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+
+			// the same order as in the text
+			for i := itemsCount - 1; i >= 0; i-- {
+				item := pageData.history[i]
+				sign := "+"
+				if isHistoryItemSent(&item, &walletAddress) {
+					sign = "-"
+				}
+
+				variants = append(variants, dialog.Variant{
+					Id:   variant.id + strconv.Itoa(i),
+					Text: fmt.Sprintf("%d. %s%s %s", itemsCount - i, sign, cryptoFunctions.FormatCurrencyAmount(item.Amount, currencyDecimals), currencySymbol),
+					AdditionalId: strconv.FormatInt(walletId, 10),
+					RowId: itemsCount - i,
+				})
+			}
+		} else if variant.isActiveFn == nil || variant.isActiveFn(pageData) {
+			variants = append(variants, dialog.Variant{
+				Id:   variant.id,
+				Text: trans(variant.textId),
+				AdditionalId: strconv.FormatInt(walletId, 10),
+				RowId: itemsCount + variant.rowId,
+			})
+		}
 	}
 	return
 }
 
 func (factory *historyDialogFactory) MakeDialog(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	pageData := getHistoryPageData(walletId, staticData)
+
 	return &dialog.Dialog{
-		Text:     factory.createText(walletId, trans, staticData),
-		Variants: factory.createVariants(walletId, trans, staticData),
+		Text:     factory.createText(walletId, pageData, trans, staticData),
+		Variants: factory.createVariants(walletId, pageData, trans, staticData),
 	}
 }
 
@@ -124,7 +303,15 @@ func (factory *historyDialogFactory) ProcessVariant(variantId string, additional
 	}
 
 	for _, variant := range factory.variants {
-		if variant.id == variantId {
+		if variant.isListItem {
+			if len(variantId) > 2 && variant.id == variantId[0:2] {
+				itemIndex, err := strconv.Atoi(variantId[2:])
+				if err != nil {
+					return false
+				}
+				return variant.processItem(walletId, itemIndex, data)
+			}
+		} else if variant.id == variantId {
 			return variant.process(walletId, data)
 		}
 	}
@@ -0,0 +1,273 @@
+package dialogFactories
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/database"
+	"github.com/gameraccoon/telegram-accountant-bot/reports"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/wallettypes"
+)
+
+// how many wallets can be imported from one file
+const maxImportedWallets int = 200
+
+// how many wallets and errors are listed in the preview
+const maxImportPreviewItems int = 20
+
+type importRowError struct {
+	line int
+	textId string
+}
+
+type importVariantPrototype struct {
+	id string
+	textId string
+	process func(*processing.ProcessData) bool
+	// nil if the variant is always active
+	isActiveFn func([]database.NewWalletData) bool
+	rowId int
+}
+
+type importDialogFactory struct {
+	variants []importVariantPrototype
+}
+
+func MakeImportDialogFactory() dialogFactory.DialogFactory {
+	return &(importDialogFactory{
+		variants: []importVariantPrototype{
+			importVariantPrototype{
+				id: "ok",
+				textId: "import_confirm",
+				process: confirmImport,
+				isActiveFn: hasImportedWallets,
+				rowId: 1,
+			},
+			importVariantPrototype{
+				id: "no",
+				textId: "import_cancel",
+				process: cancelImport,
+				rowId: 1,
+			},
+		},
+	})
+}
+
+func hasImportedWallets(wallets []database.NewWalletData) bool {
+	return len(wallets) > 0
+}
+
+// returns the text id of the error if the row is wrong
+func validateImportRow(row *reports.ImportRow) (wallet database.NewWalletData, errorTextId string) {
+	if row.Name == "" {
+		errorTextId = "import_error_name"
+		return
+	}
+
+	var currency currencies.Currency
+	if row.ContractAddress != "" {
+		currency = currencies.Erc20Token
+
+		erc20TokenProcessor := cryptoFunctions.GetErc20TokenProcessor()
+		if erc20TokenProcessor == nil || !(*erc20TokenProcessor).IsContractAddressValid(row.ContractAddress) {
+			errorTextId = "import_error_contract"
+			return
+		}
+	} else {
+		var ok bool
+		currency, ok = currencies.GetCurrencyBySymbol(row.Symbol)
+		if !ok {
+			errorTextId = "import_error_currency"
+			return
+		}
+	}
+
+	currencyProcessor := cryptoFunctions.GetProcessor(currency)
+	if currencyProcessor == nil {
+		errorTextId = "import_error_currency"
+		return
+	}
+
+	walletType := wallettypes.WatchOnly
+	if cryptoFunctions.IsHdWalletSupported(currency) && cryptoFunctions.IsExtendedPublicKeyValid(currency, row.Address) {
+		walletType = wallettypes.HdWatchOnly
+	} else if !(*currencyProcessor).IsAddressValid(row.Address) {
+		errorTextId = "import_error_address"
+		return
+	}
+
+	priceId := row.PriceId
+	if priceId == "" {
+		priceId = currencies.GetCurrencyPriceId(currency)
+	}
+
+	wallet = database.NewWalletData{
+		Name: row.Name,
+		Address: currencies.AddressData{
+			Currency: currency,
+			ContractAddress: currencies.NormalizeAddress(currency, row.ContractAddress),
+			Address: currencies.NormalizeAddress(currency, row.Address),
+			PriceId: priceId,
+		},
+		Type: walletType,
+	}
+	return
+}
+
+// parses an uploaded file and shows what is going to be imported
+func StartWalletsImport(fileData []byte, data *processing.ProcessData) {
+	rows, err := reports.ParseWalletsImport(fileData)
+	if err != nil {
+		data.SendMessage(data.Trans("import_wrong_file") + "\n" + data.Trans("import_info"))
+		return
+	}
+
+	if len(rows) == 0 {
+		data.SendMessage(data.Trans("import_no_wallets") + "\n" + data.Trans("import_info"))
+		return
+	}
+
+	if len(rows) > maxImportedWallets {
+		data.SendMessage(data.Trans("import_too_many_wallets", map[string]interface{}{
+			"Count": maxImportedWallets,
+		}))
+		return
+	}
+
+	wallets := make([]database.NewWalletData, 0, len(rows))
+	rowErrors := make([]importRowError, 0)
+
+	for i := range rows {
+		wallet, errorTextId := validateImportRow(&rows[i])
+		if errorTextId != "" {
+			rowErrors = append(rowErrors, importRowError{
+				line: rows[i].Line,
+				textId: errorTextId,
+			})
+		} else {
+			wallets = append(wallets, wallet)
+		}
+	}
+
+	data.Static.SetUserStateValue(data.UserId, "importWallets", wallets)
+	data.Static.SetUserStateValue(data.UserId, "importErrors", rowErrors)
+	data.SendDialog(data.Static.MakeDialogFn("im", data.UserId, data.Trans, data.Static))
+}
+
+func getImportState(userId int64, staticData *processing.StaticProccessStructs) (wallets []database.NewWalletData, rowErrors []importRowError) {
+	wallets, _ = staticData.GetUserStateValue(userId, "importWallets").([]database.NewWalletData)
+	rowErrors, _ = staticData.GetUserStateValue(userId, "importErrors").([]importRowError)
+	return
+}
+
+func clearImportState(data *processing.ProcessData) {
+	data.Static.SetUserStateValue(data.UserId, "importWallets", nil)
+	data.Static.SetUserStateValue(data.UserId, "importErrors", nil)
+}
+
+func confirmImport(data *processing.ProcessData) bool {
+	wallets, _ := getImportState(data.UserId, data.Static)
+	if len(wallets) == 0 {
+		return false
+	}
+
+	clearImportState(data)
+
+	walletIds := staticFunctions.GetDb(data.Static).CreateWallets(data.UserId, wallets)
+	data.SubstitudeMessage(data.Trans("import_done", map[string]interface{}{
+		"Count": len(walletIds),
+	}))
+	data.SendDialog(data.Static.MakeDialogFn("wl", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func cancelImport(data *processing.ProcessData) bool {
+	clearImportState(data)
+	data.SubstitudeMessage(data.Trans("import_canceled"))
+	return true
+}
+
+func (factory *importDialogFactory) createText(wallets []database.NewWalletData, rowErrors []importRowError, trans i18n.TranslateFunc) string {
+	var textBuffer bytes.Buffer
+
+	textBuffer.WriteString(trans("import_preview_title", map[string]interface{}{
+		"Count": len(wallets),
+	}))
+
+	for i, wallet := range wallets {
+		if i >= maxImportPreviewItems {
+			textBuffer.WriteString("\n…")
+			break
+		}
+
+		symbol := currencies.GetCurrencySymbol(wallet.Address.Currency)
+		if wallet.Address.Currency == currencies.Erc20Token {
+			symbol = trans("import_token")
+		}
+
+		textBuffer.WriteString(fmt.Sprintf("\n• %s, %s", wallet.Name, symbol))
+	}
+
+	if len(rowErrors) > 0 {
+		textBuffer.WriteString("\n\n")
+		textBuffer.WriteString(trans("import_errors_title", map[string]interface{}{
+			"Count": len(rowErrors),
+		}))
+
+		for i, rowError := range rowErrors {
+			if i >= maxImportPreviewItems {
+				textBuffer.WriteString("\n…")
+				break
+			}
+
+			textBuffer.WriteString("\n")
+			textBuffer.WriteString(trans("import_error_row", map[string]interface{}{
+				"Line":  rowError.line,
+				"Error": trans(rowError.textId),
+			}))
+		}
+	}
+
+	return textBuffer.String()
+}
+
+func (factory *importDialogFactory) createVariants(wallets []database.NewWalletData, trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	for _, variant := range factory.variants {
+		if variant.isActiveFn == nil || variant.isActiveFn(wallets) {
+			variants = append(variants, dialog.Variant{
+				Id:   variant.id,
+				Text: trans(variant.textId, map[string]interface{}{
+					"Count": len(wallets),
+				}),
+				RowId: variant.rowId,
+			})
+		}
+	}
+	return
+}
+
+func (factory *importDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	wallets, rowErrors := getImportState(userId, staticData)
+
+	return &dialog.Dialog{
+		Text:     factory.createText(wallets, rowErrors, trans),
+		Variants: factory.createVariants(wallets, trans),
+	}
+}
+
+func (factory *importDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	for _, variant := range factory.variants {
+		if variant.id == variantId {
+			return variant.process(data)
+		}
+	}
+	return false
+}
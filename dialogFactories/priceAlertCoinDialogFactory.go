@@ -0,0 +1,179 @@
+package dialogFactories
+
+import (
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/serverData"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"strconv"
+)
+
+type priceAlertCoinVariantPrototype struct {
+	isListItem bool
+	id string
+	textId string
+	process func(*processing.ProcessData) bool
+	// for list items, receives the index of the coin in the list
+	processItem func(int, *processing.ProcessData) bool
+}
+
+type priceAlertCoinDialogFactory struct {
+	variants []priceAlertCoinVariantPrototype
+}
+
+func MakePriceAlertCoinDialogFactory() dialogFactory.DialogFactory {
+	return &(priceAlertCoinDialogFactory{
+		variants: []priceAlertCoinVariantPrototype{
+			priceAlertCoinVariantPrototype{
+				isListItem: true,
+				id: "it",
+				processItem: selectPriceAlertCoin,
+			},
+			priceAlertCoinVariantPrototype{
+				id: "other",
+				textId: "other_price_alert_coin_btn",
+				process: sendPriceAlertCoin,
+			},
+			priceAlertCoinVariantPrototype{
+				id: "back",
+				textId: "back_btn",
+				process: backToPriceAlerts,
+			},
+		},
+	})
+}
+
+// price ids of the user's wallets, the coins that most probably will be watched
+func getUserPriceIds(userId int64, staticData *processing.StaticProccessStructs) (priceIds []string) {
+	priceIds = make([]string, 0)
+	for _, walletAddress := range staticFunctions.GetDb(staticData).GetUserWalletAddresses(userId) {
+		if walletAddress.PriceId == "" {
+			continue
+		}
+		priceIds = appendMissingPriceId(priceIds, walletAddress.PriceId)
+	}
+	return
+}
+
+func appendMissingPriceId(priceIds []string, priceId string) []string {
+	for _, knownPriceId := range priceIds {
+		if knownPriceId == priceId {
+			return priceIds
+		}
+	}
+	return append(priceIds, priceId)
+}
+
+func selectPriceAlertCoin(itemIndex int, data *processing.ProcessData) bool {
+	priceIds, ok := data.Static.GetUserStateValue(data.UserId, "alertPriceIds").([]string)
+	if !ok || itemIndex < 0 || itemIndex >= len(priceIds) {
+		return false
+	}
+
+	askPriceAlertThreshold(priceIds[itemIndex], data)
+	data.SubstitudeMessage(getPriceAlertThresholdRequestText(priceIds[itemIndex], data))
+	return true
+}
+
+func sendPriceAlertCoin(data *processing.ProcessData) bool {
+	data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+		ProcessorId: "newPriceAlertCoin",
+	})
+	data.SubstitudeMessage(data.Trans("send_price_alert_coin"))
+	return true
+}
+
+func backToPriceAlerts(data *processing.ProcessData) bool {
+	data.SubstitudeDialog(data.Static.MakeDialogFn("pa", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+// remembers the coin and waits for the threshold from the user
+func askPriceAlertThreshold(priceId string, data *processing.ProcessData) {
+	data.Static.SetUserStateValue(data.UserId, "alertPriceId", priceId)
+	data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+		ProcessorId: "newPriceAlertThreshold",
+	})
+}
+
+func getPriceAlertThresholdRequestText(priceId string, data *processing.ProcessData) string {
+	fiat := staticFunctions.GetDb(data.Static).GetUserFiatCurrency(data.UserId)
+
+	text := data.Trans("send_price_alert_threshold", map[string]interface{}{
+		"Coin": priceId,
+	})
+
+	serverData := serverData.GetServerData(data.Static)
+	if serverData == nil {
+		return text
+	}
+
+	rate := serverData.GetRate(priceId, fiat)
+	if rate == nil {
+		return text
+	}
+
+	return text + "\n\n" + data.Trans("price_alert_current_rate", map[string]interface{}{
+		"Rate": cryptoFunctions.FormatRate(rate),
+		"Fiat": currencies.GetFiatCurrencyCode(fiat),
+	})
+}
+
+func (factory *priceAlertCoinDialogFactory) createVariants(priceIds []string, trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	itemsInRow := 2
+	itemRowsCount := (len(priceIds) + itemsInRow - 1) / itemsInRow
+
+	for _, variant := range factory.variants {
+		if variant.isListItem {
+			for i, priceId := range priceIds {
+				variants = append(variants, dialog.Variant{
+					Id:   variant.id + strconv.Itoa(i),
+					Text: priceId,
+					RowId: i / itemsInRow + 1,
+				})
+			}
+		} else {
+			variants = append(variants, dialog.Variant{
+				Id:   variant.id,
+				Text: trans(variant.textId),
+				RowId: itemRowsCount + 1,
+			})
+		}
+	}
+	return
+}
+
+func (factory *priceAlertCoinDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	priceIds := getUserPriceIds(userId, staticData)
+
+	// remember the order of the buttons to know which coin is chosen
+	staticData.SetUserStateValue(userId, "alertPriceIds", priceIds)
+
+	return &dialog.Dialog{
+		Text:     trans("choose_price_alert_coin"),
+		Variants: factory.createVariants(priceIds, trans),
+	}
+}
+
+func (factory *priceAlertCoinDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	for _, variant := range factory.variants {
+		if variant.isListItem {
+			if len(variantId) > 2 && variant.id == variantId[0:2] {
+				itemIndex, err := strconv.Atoi(variantId[2:])
+				if err != nil {
+					return false
+				}
+				return variant.processItem(itemIndex, data)
+			}
+		} else if variant.id == variantId {
+			return variant.process(data)
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package dialogFactories
+
+import (
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"math/big"
+	"time"
+)
+
+type priceAlertRepeatVariantPrototype struct {
+	id string
+	textId string
+	// zero for one-shot alerts
+	cooldown time.Duration
+	rowId int
+}
+
+type priceAlertRepeatDialogFactory struct {
+	variants []priceAlertRepeatVariantPrototype
+}
+
+func MakePriceAlertRepeatDialogFactory() dialogFactory.DialogFactory {
+	return &(priceAlertRepeatDialogFactory{
+		variants: []priceAlertRepeatVariantPrototype{
+			priceAlertRepeatVariantPrototype{
+				id: "once",
+				textId: "price_alert_once",
+				rowId: 1,
+			},
+			priceAlertRepeatVariantPrototype{
+				id: "hour",
+				textId: "price_alert_hourly",
+				cooldown: time.Hour,
+				rowId: 2,
+			},
+			priceAlertRepeatVariantPrototype{
+				id: "day",
+				textId: "price_alert_daily",
+				cooldown: 24 * time.Hour,
+				rowId: 2,
+			},
+		},
+	})
+}
+
+func createPriceAlert(data *processing.ProcessData, cooldown time.Duration) bool {
+	priceId, ok := data.Static.GetUserStateValue(data.UserId, "alertPriceId").(string)
+	if !ok {
+		return false
+	}
+
+	fiat, ok := data.Static.GetUserStateValue(data.UserId, "alertFiat").(string)
+	if !ok {
+		return false
+	}
+
+	direction, ok := data.Static.GetUserStateValue(data.UserId, "alertDirection").(currencies.PriceAlertDirection)
+	if !ok {
+		return false
+	}
+
+	threshold, ok := data.Static.GetUserStateValue(data.UserId, "alertThreshold").(*big.Float)
+	if !ok || threshold == nil {
+		return false
+	}
+
+	staticFunctions.GetDb(data.Static).CreatePriceAlert(currencies.PriceAlert{
+		UserId: data.UserId,
+		PriceId: priceId,
+		Fiat: fiat,
+		Direction: direction,
+		Threshold: threshold,
+		Cooldown: cooldown,
+	})
+
+	data.Static.CleanUserStateValues(data.UserId)
+	data.SubstitudeDialog(data.Static.MakeDialogFn("pa", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func (factory *priceAlertRepeatDialogFactory) createVariants(trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	for _, variant := range factory.variants {
+		variants = append(variants, dialog.Variant{
+			Id:    variant.id,
+			Text:  trans(variant.textId),
+			RowId: variant.rowId,
+		})
+	}
+	return
+}
+
+func (factory *priceAlertRepeatDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	return &dialog.Dialog{
+		Text:     trans("choose_price_alert_repeat"),
+		Variants: factory.createVariants(trans),
+	}
+}
+
+func (factory *priceAlertRepeatDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	for _, variant := range factory.variants {
+		if variant.id == variantId {
+			return createPriceAlert(data, variant.cooldown)
+		}
+	}
+	return false
+}
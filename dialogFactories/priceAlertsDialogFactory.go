@@ -0,0 +1,161 @@
+package dialogFactories
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"strconv"
+	"time"
+)
+
+type priceAlertsVariantPrototype struct {
+	isListItem bool
+	id string
+	textId string
+	process func(int64, *processing.ProcessData) bool
+	rowId int
+}
+
+type priceAlertsDialogFactory struct {
+	variants []priceAlertsVariantPrototype
+}
+
+func MakePriceAlertsDialogFactory() dialogFactory.DialogFactory {
+	return &(priceAlertsDialogFactory{
+		variants: []priceAlertsVariantPrototype{
+			priceAlertsVariantPrototype{
+				isListItem: true,
+				id: "it",
+				process: deletePriceAlert,
+			},
+			priceAlertsVariantPrototype{
+				id: "add",
+				textId: "add_price_alert_btn",
+				process: addPriceAlert,
+				rowId:1,
+			},
+			priceAlertsVariantPrototype{
+				id: "back",
+				textId: "back_to_list",
+				process: backToList, // defined in walletDialogFactory
+				rowId:2,
+			},
+		},
+	})
+}
+
+func deletePriceAlert(alertId int64, data *processing.ProcessData) bool {
+	db := staticFunctions.GetDb(data.Static)
+
+	if !db.IsPriceAlertBelongsToUser(data.UserId, alertId) {
+		return false
+	}
+
+	db.DeletePriceAlert(alertId)
+	data.SubstitudeDialog(data.Static.MakeDialogFn("pa", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func addPriceAlert(userId int64, data *processing.ProcessData) bool {
+	data.SubstitudeDialog(data.Static.MakeDialogFn("ap", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func getPriceAlertRepeatTextId(cooldown time.Duration) string {
+	if cooldown <= 0 {
+		return "price_alert_once"
+	} else if cooldown <= time.Hour {
+		return "price_alert_hourly"
+	} else {
+		return "price_alert_daily"
+	}
+}
+
+func getPriceAlertDirectionSign(direction currencies.PriceAlertDirection) string {
+	if direction == currencies.PriceAlertBelow {
+		return "≤"
+	}
+	return "≥"
+}
+
+func (factory *priceAlertsDialogFactory) createText(alerts []currencies.PriceAlert, trans i18n.TranslateFunc) string {
+	if len(alerts) == 0 {
+		return trans("price_alerts_empty")
+	}
+
+	var textBuffer bytes.Buffer
+	textBuffer.WriteString(trans("price_alerts_title"))
+
+	for i, alert := range alerts {
+		textBuffer.WriteString(fmt.Sprintf("\n%d. <b>%s</b> %s %s %s, %s",
+			i + 1,
+			alert.PriceId,
+			getPriceAlertDirectionSign(alert.Direction),
+			cryptoFunctions.FormatRate(alert.Threshold),
+			currencies.GetFiatCurrencyCode(alert.Fiat),
+			trans(getPriceAlertRepeatTextId(alert.Cooldown)),
+		))
+	}
+
+	return textBuffer.String()
+}
+
+func (factory *priceAlertsDialogFactory) createVariants(alerts []currencies.PriceAlert, trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	// delete buttons of the alerts go in rows of three above the other buttons
+	itemsInRow := 3
+	itemRowsCount := (len(alerts) + itemsInRow - 1) / itemsInRow
+
+	for _, variant := range factory.variants {
+		if variant.isListItem {
+			for i, alert := range alerts {
+				variants = append(variants, dialog.Variant{
+					Id:   variant.id + strconv.Itoa(i),
+					Text: fmt.Sprintf("❌ %d", i + 1),
+					AdditionalId: strconv.FormatInt(alert.AlertId, 10),
+					RowId: i / itemsInRow + 1,
+				})
+			}
+		} else {
+			variants = append(variants, dialog.Variant{
+				Id:   variant.id,
+				Text: trans(variant.textId),
+				RowId: itemRowsCount + variant.rowId,
+			})
+		}
+	}
+	return
+}
+
+func (factory *priceAlertsDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	alerts := staticFunctions.GetDb(staticData).GetUserPriceAlerts(userId)
+
+	return &dialog.Dialog{
+		Text:     factory.createText(alerts, trans),
+		Variants: factory.createVariants(alerts, trans),
+	}
+}
+
+func (factory *priceAlertsDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	for _, variant := range factory.variants {
+		if variant.isListItem {
+			if len(variantId) > 2 && variant.id == variantId[0:2] {
+				alertId, err := strconv.ParseInt(additionalId, 10, 64)
+				if err != nil {
+					return false
+				}
+				return variant.process(alertId, data)
+			}
+		} else if variant.id == variantId {
+			return variant.process(data.UserId, data)
+		}
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package dialogFactories
+
+import (
+	"bytes"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/serverData"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"math/big"
+)
+
+type profitLossVariantPrototype struct {
+	id string
+	textId string
+	method currencies.CostBasisMethod
+	rowId int
+}
+
+type profitLossDialogFactory struct {
+	variants []profitLossVariantPrototype
+}
+
+func MakeProfitLossDialogFactory() dialogFactory.DialogFactory {
+	return &(profitLossDialogFactory{
+		variants: []profitLossVariantPrototype{
+			profitLossVariantPrototype{
+				id: "fifo",
+				textId: "cost_basis_fifo",
+				method: currencies.CostBasisFifo,
+				rowId: 1,
+			},
+			profitLossVariantPrototype{
+				id: "lifo",
+				textId: "cost_basis_lifo",
+				method: currencies.CostBasisLifo,
+				rowId: 1,
+			},
+			profitLossVariantPrototype{
+				id: "hifo",
+				textId: "cost_basis_hifo",
+				method: currencies.CostBasisHifo,
+				rowId: 1,
+			},
+			profitLossVariantPrototype{
+				id: "avg",
+				textId: "cost_basis_average",
+				method: currencies.CostBasisAverage,
+				rowId: 1,
+			},
+		},
+	})
+}
+
+type walletProfitLoss struct {
+	currencies.ProfitLoss
+	lotsCount int
+	// the lots in other fiat currencies can't be summed with the rest
+	// so the wallet is not calculated while there are any
+	otherFiatLots []currencies.TradeLot
+}
+
+func (profitLoss *walletProfitLoss) isCalculated() bool {
+	return profitLoss.lotsCount > 0 && len(profitLoss.otherFiatLots) == 0
+}
+
+func getCostBasisMethodTextId(method currencies.CostBasisMethod) string {
+	switch method {
+	case currencies.CostBasisLifo:
+		return "cost_basis_lifo"
+	case currencies.CostBasisHifo:
+		return "cost_basis_hifo"
+	case currencies.CostBasisAverage:
+		return "cost_basis_average"
+	default:
+		return "cost_basis_fifo"
+	}
+}
+
+// the wallet is not calculated if it has lots in other fiat currencies
+func calculateWalletProfitLoss(walletId int64, fiat string, method currencies.CostBasisMethod, staticData *processing.StaticProccessStructs) (result walletProfitLoss) {
+	db := staticFunctions.GetDb(staticData)
+
+	lots := make([]currencies.TradeLot, 0)
+	for _, lot := range db.GetWalletTradeLots(walletId) {
+		if lot.Fiat == fiat {
+			lots = append(lots, lot)
+		} else {
+			result.otherFiatLots = append(result.otherFiatLots, lot)
+		}
+	}
+	result.lotsCount = len(lots)
+
+	if len(result.otherFiatLots) > 0 {
+		return
+	}
+
+	var rate *big.Float
+	serverData := serverData.GetServerData(staticData)
+	if serverData != nil {
+		rate = serverData.GetRate(db.GetWalletAddress(walletId).PriceId, fiat)
+	}
+
+	result.ProfitLoss = currencies.CalculateProfitLoss(lots, method, rate)
+	return
+}
+
+func formatFiatValue(value *big.Float, fiat string) string {
+	return value.Text('f', 2) + " " + currencies.GetFiatCurrencyCode(fiat)
+}
+
+// nil values are shown as unknown
+func formatSignedFiatValue(value *big.Float, fiat string, trans i18n.TranslateFunc) string {
+	if value == nil {
+		return trans("pl_unknown")
+	}
+
+	if value.Sign() > 0 {
+		return "+" + formatFiatValue(value, fiat)
+	}
+	return formatFiatValue(value, fiat)
+}
+
+func formatProfitLoss(profitLoss *currencies.ProfitLoss, fiat string, trans i18n.TranslateFunc) string {
+	return trans("pl_summary", map[string]interface{}{
+		"CostBasis":  formatFiatValue(profitLoss.CostBasis, fiat),
+		"Unrealized": formatSignedFiatValue(profitLoss.Unrealized, fiat, trans),
+		"Realized":   formatSignedFiatValue(profitLoss.Realized, fiat, trans),
+	})
+}
+
+// notes about the lots that are not counted
+func formatProfitLossWarnings(profitLoss *walletProfitLoss, fiat string, currencySymbol string, currencyDecimals int, timezone string, trans i18n.TranslateFunc) string {
+	var textBuffer bytes.Buffer
+
+	if profitLoss.UncoveredAmount != nil && profitLoss.UncoveredAmount.Sign() > 0 {
+		textBuffer.WriteString(trans("pl_uncovered", map[string]interface{}{
+			"Amount": cryptoFunctions.FormatFloatCurrencyAmount(profitLoss.UncoveredAmount, currencyDecimals),
+			"Symbol": currencySymbol,
+		}))
+	}
+
+	if len(profitLoss.otherFiatLots) > 0 {
+		textBuffer.WriteString(formatOtherFiatLotsWarning(profitLoss, fiat, trans))
+		for _, lot := range profitLoss.otherFiatLots {
+			textBuffer.WriteString("\n" + formatTradeLot(&lot, currencySymbol, currencyDecimals, timezone, trans))
+		}
+	}
+
+	return textBuffer.String()
+}
+
+func formatOtherFiatLotsWarning(profitLoss *walletProfitLoss, fiat string, trans i18n.TranslateFunc) string {
+	return trans("pl_other_fiat_lots", map[string]interface{}{
+		"Count": len(profitLoss.otherFiatLots),
+		"Fiat":  currencies.GetFiatCurrencyCode(fiat),
+	})
+}
+
+func (factory *profitLossDialogFactory) createText(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) string {
+	db := staticFunctions.GetDb(staticData)
+
+	serverData := serverData.GetServerData(staticData)
+	if serverData == nil {
+		return "Error"
+	}
+
+	fiat := db.GetUserFiatCurrency(userId)
+	method := db.GetUserCostBasisMethod(userId)
+	timezone := db.GetUserTimezone(userId)
+
+	var textBuffer bytes.Buffer
+	textBuffer.WriteString(trans("pl_title", map[string]interface{}{
+		"Method": trans(getCostBasisMethodTextId(method)),
+	}))
+
+	total := currencies.ProfitLoss{
+		CostBasis: new(big.Float),
+		Realized: new(big.Float),
+		Unrealized: new(big.Float),
+	}
+	walletsCount := 0
+	isTotalIncomplete := false
+
+	walletIds, walletNames := db.GetUserWallets(userId)
+	for i, walletId := range walletIds {
+		profitLoss := calculateWalletProfitLoss(walletId, fiat, method, staticData)
+		if profitLoss.lotsCount == 0 && len(profitLoss.otherFiatLots) == 0 {
+			continue
+		}
+		walletsCount++
+
+		walletAddress := db.GetWalletAddress(walletId)
+		currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+
+		textBuffer.WriteString("\n\n<b>" + walletNames[i] + "</b>")
+		if profitLoss.isCalculated() {
+			textBuffer.WriteString("\n" + formatProfitLoss(&profitLoss.ProfitLoss, fiat, trans))
+		}
+		textBuffer.WriteString(formatProfitLossWarnings(&profitLoss, fiat, currencySymbol, currencyDecimals, timezone, trans))
+
+		if !profitLoss.isCalculated() {
+			isTotalIncomplete = true
+			continue
+		}
+
+		total.CostBasis.Add(total.CostBasis, profitLoss.CostBasis)
+		total.Realized.Add(total.Realized, profitLoss.Realized)
+		// unknown value of one wallet makes the total unknown
+		if total.Unrealized != nil && profitLoss.Unrealized != nil {
+			total.Unrealized.Add(total.Unrealized, profitLoss.Unrealized)
+		} else {
+			total.Unrealized = nil
+		}
+	}
+
+	if walletsCount == 0 {
+		return trans("pl_no_lots")
+	}
+
+	if walletsCount > 1 {
+		textBuffer.WriteString("\n\n" + trans("pl_total") + "\n")
+		textBuffer.WriteString(formatProfitLoss(&total, fiat, trans))
+		if isTotalIncomplete {
+			textBuffer.WriteString(trans("pl_total_incomplete"))
+		}
+	}
+
+	return textBuffer.String()
+}
+
+func (factory *profitLossDialogFactory) createVariants(trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	for _, variant := range factory.variants {
+		variants = append(variants, dialog.Variant{
+			Id:   variant.id,
+			Text: trans(variant.textId),
+			RowId: variant.rowId,
+		})
+	}
+	return
+}
+
+func (factory *profitLossDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	return &dialog.Dialog{
+		Text:     factory.createText(userId, trans, staticData),
+		Variants: factory.createVariants(trans),
+	}
+}
+
+func (factory *profitLossDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	for _, variant := range factory.variants {
+		if variant.id == variantId {
+			staticFunctions.GetDb(data.Static).SetUserCostBasisMethod(data.UserId, variant.method)
+			data.SubstitudeDialog(data.Static.MakeDialogFn("pl", data.UserId, data.Trans, data.Static))
+			return true
+		}
+	}
+	return false
+}
@@ -5,7 +5,11 @@ import (
 	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
 	"github.com/gameraccoon/telegram-bot-skeleton/processing"
 	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/database"
 	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/wallettypes"
 	"strconv"
 )
 
@@ -34,11 +38,37 @@ func MakeReceiveDialogFactory() dialogFactory.DialogFactory {
 }
 
 func (factory *receiveDialogFactory) createText(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) string {
-	walletAddress := staticFunctions.GetDb(staticData).GetWalletAddress(walletId)
+	db := staticFunctions.GetDb(staticData)
+	walletAddress := db.GetWalletAddress(walletId)
+
+	if db.GetWalletType(walletId) == wallettypes.HdWatchOnly {
+		return trans("receive_title") + "\n<code>" + getHdWalletReceiveAddress(walletId, walletAddress, db) + "</code>"
+	}
 
 	return trans("receive_title") + "\n<code>" + walletAddress.Address + "</code>"
 }
 
+// returns the first unused receiving address of the HD wallet
+func getHdWalletReceiveAddress(walletId int64, walletAddress currencies.AddressData, db *database.AccountDb) string {
+	nextIndex := uint32(0)
+	for _, address := range db.GetHdWalletAddresses(walletId) {
+		if address.Chain != 0 {
+			continue
+		}
+		if !address.IsUsed {
+			return address.Address
+		}
+		nextIndex = address.Index + 1
+	}
+
+	// the wallet is not scanned yet
+	addresses := cryptoFunctions.DeriveHdWalletAddresses(walletAddress.Currency, walletAddress.Address, 0, nextIndex, 1)
+	if len(addresses) > 0 {
+		return addresses[0]
+	}
+	return ""
+}
+
 func (factory *receiveDialogFactory) createVariants(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) (variants []dialog.Variant) {
 	variants = make([]dialog.Variant, 0)
 
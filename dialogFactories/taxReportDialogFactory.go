@@ -0,0 +1,248 @@
+package dialogFactories
+
+import (
+	"fmt"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/reports"
+	"github.com/gameraccoon/telegram-accountant-bot/serverData"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how many last years with disposals can be chosen
+const maxTaxReportYears int = 8
+
+type taxReportVariantPrototype struct {
+	isListItem bool
+	id string
+	textId string
+	method currencies.CostBasisMethod
+	rowId int
+}
+
+type taxReportDialogFactory struct {
+	variants []taxReportVariantPrototype
+}
+
+func MakeTaxReportDialogFactory() dialogFactory.DialogFactory {
+	return &(taxReportDialogFactory{
+		variants: []taxReportVariantPrototype{
+			taxReportVariantPrototype{
+				id: "fifo",
+				textId: "cost_basis_fifo",
+				method: currencies.CostBasisFifo,
+				rowId: 1,
+			},
+			taxReportVariantPrototype{
+				id: "lifo",
+				textId: "cost_basis_lifo",
+				method: currencies.CostBasisLifo,
+				rowId: 1,
+			},
+			taxReportVariantPrototype{
+				id: "hifo",
+				textId: "cost_basis_hifo",
+				method: currencies.CostBasisHifo,
+				rowId: 1,
+			},
+			taxReportVariantPrototype{
+				isListItem: true,
+				id: "yr",
+				rowId: 2,
+			},
+		},
+	})
+}
+
+func getUserLocation(userId int64, staticData *processing.StaticProccessStructs) *time.Location {
+	location, err := time.LoadLocation(staticFunctions.GetDb(staticData).GetUserTimezone(userId))
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// disposals of all the user's wallets in the user's fiat currency sorted by time
+// and the names of the wallets that are skipped because of lots in other fiat currencies
+func getUserCapitalGains(userId int64, method currencies.CostBasisMethod, staticData *processing.StaticProccessStructs) (rows []reports.CapitalGainsRow, skippedWallets []string) {
+	db := staticFunctions.GetDb(staticData)
+
+	serverData := serverData.GetServerData(staticData)
+	if serverData == nil {
+		return
+	}
+
+	fiat := db.GetUserFiatCurrency(userId)
+
+	walletIds, walletNames := db.GetUserWallets(userId)
+	for i, walletId := range walletIds {
+		profitLoss := calculateWalletProfitLoss(walletId, fiat, method, staticData)
+		if len(profitLoss.otherFiatLots) > 0 {
+			skippedWallets = append(skippedWallets, walletNames[i])
+			continue
+		}
+
+		walletAddress := db.GetWalletAddress(walletId)
+		currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+
+		for _, disposal := range profitLoss.Disposals {
+			rows = append(rows, reports.CapitalGainsRow{
+				Disposal: disposal,
+				WalletName: walletNames[i],
+				Symbol: currencySymbol,
+				Decimals: currencyDecimals,
+				Fiat: fiat,
+			})
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].DisposalTime.Before(rows[j].DisposalTime)
+	})
+	return
+}
+
+// years with disposals from new to old
+func getCapitalGainsYears(rows []reports.CapitalGainsRow, location *time.Location) (years []int) {
+	for i := len(rows) - 1; i >= 0 && len(years) < maxTaxReportYears; i-- {
+		year := rows[i].DisposalTime.In(location).Year()
+		if len(years) == 0 || years[len(years) - 1] != year {
+			years = append(years, year)
+		}
+	}
+	return
+}
+
+func formatSkippedWalletsWarning(skippedWallets []string, trans i18n.TranslateFunc) string {
+	if len(skippedWallets) == 0 {
+		return ""
+	}
+	return trans("tax_report_other_fiat", map[string]interface{}{
+		"Wallets": strings.Join(skippedWallets, ", "),
+	})
+}
+
+func sendTaxReport(year int, data *processing.ProcessData) bool {
+	db := staticFunctions.GetDb(data.Static)
+	location := getUserLocation(data.UserId, data.Static)
+	startTime, endTime := reports.GetYearRange(year, location)
+
+	yearRows := make([]reports.CapitalGainsRow, 0)
+	totalGain := new(big.Float)
+	uncoveredCount := 0
+
+	rows, skippedWallets := getUserCapitalGains(data.UserId, db.GetUserCostBasisMethod(data.UserId), data.Static)
+	for _, row := range rows {
+		if row.DisposalTime.Before(startTime) || !row.DisposalTime.Before(endTime) {
+			continue
+		}
+		yearRows = append(yearRows, row)
+		// uncovered disposals are in the report but not in the total
+		if row.IsUncovered() {
+			uncoveredCount++
+		} else {
+			totalGain.Add(totalGain, row.GetGain())
+		}
+	}
+
+	if len(yearRows) == 0 {
+		data.SendMessage(data.Trans("tax_report_no_disposals") + formatSkippedWalletsWarning(skippedWallets, data.Trans))
+		return true
+	}
+
+	csvData, err := reports.MakeCapitalGainsCsv(yearRows, location)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+
+	caption := data.Trans("tax_report_caption", map[string]interface{}{
+		"Year":   year,
+		"Count":  len(yearRows),
+		"Method": data.Trans(getCostBasisMethodTextId(db.GetUserCostBasisMethod(data.UserId))),
+		"Gain":   formatSignedFiatValue(totalGain, db.GetUserFiatCurrency(data.UserId), data.Trans),
+	})
+
+	if uncoveredCount > 0 {
+		caption = caption + data.Trans("tax_report_uncovered", map[string]interface{}{
+			"Count": uncoveredCount,
+		})
+	}
+	caption = caption + formatSkippedWalletsWarning(skippedWallets, data.Trans)
+
+	staticFunctions.SendDocument(data.Static, data.ChatId, fmt.Sprintf("capital_gains_%d.csv", year), csvData, caption)
+	return true
+}
+
+func (factory *taxReportDialogFactory) createVariants(years []int, trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	itemsInRow := 4
+
+	for _, variant := range factory.variants {
+		if variant.isListItem {
+			for i, year := range years {
+				variants = append(variants, dialog.Variant{
+					Id:   variant.id + strconv.Itoa(year),
+					Text: strconv.Itoa(year),
+					RowId: variant.rowId + i / itemsInRow,
+				})
+			}
+		} else {
+			variants = append(variants, dialog.Variant{
+				Id:   variant.id,
+				Text: trans(variant.textId),
+				RowId: variant.rowId,
+			})
+		}
+	}
+	return
+}
+
+func (factory *taxReportDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	method := staticFunctions.GetDb(staticData).GetUserCostBasisMethod(userId)
+	rows, skippedWallets := getUserCapitalGains(userId, method, staticData)
+	years := getCapitalGainsYears(rows, getUserLocation(userId, staticData))
+
+	text := trans("tax_report_title", map[string]interface{}{
+		"Method": trans(getCostBasisMethodTextId(method)),
+	})
+
+	if len(years) == 0 {
+		text = text + "\n" + trans("tax_report_no_disposals")
+	}
+	text = text + formatSkippedWalletsWarning(skippedWallets, trans)
+
+	return &dialog.Dialog{
+		Text:     text,
+		Variants: factory.createVariants(years, trans),
+	}
+}
+
+func (factory *taxReportDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	for _, variant := range factory.variants {
+		if variant.isListItem {
+			if len(variantId) > 2 && variant.id == variantId[0:2] {
+				year, err := strconv.Atoi(variantId[2:])
+				if err != nil {
+					return false
+				}
+				return sendTaxReport(year, data)
+			}
+		} else if variant.id == variantId {
+			staticFunctions.GetDb(data.Static).SetUserCostBasisMethod(data.UserId, variant.method)
+			data.SubstitudeDialog(data.Static.MakeDialogFn("tr", data.UserId, data.Trans, data.Static))
+			return true
+		}
+	}
+	return false
+}
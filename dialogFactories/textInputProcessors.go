@@ -7,7 +7,10 @@ import (
 	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
 	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
 	"log"
+	"math/big"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,6 +23,12 @@ func GetTextInputProcessorManager() dialogManager.TextInputProcessorManager {
 			"renamingWallet" : processRenamingWallet,
 			"setWalletPriceId" : processSetWalletPriceId,
 			"newTimezone" : processSetTimezone,
+			"newPriceAlertCoin" : processNewPriceAlertCoin,
+			"newPriceAlertThreshold" : processNewPriceAlertThreshold,
+			"setNotifyMinChange" : processSetNotifyMinChange,
+			"newQuietHours" : processNewQuietHours,
+			"newTradeLot" : processNewTradeLot,
+			"transactionLotPrice" : processTransactionLotPrice,
 		},
 	}
 }
@@ -40,7 +49,11 @@ func processNewWalletName(additionalId int64, data *processing.ProcessData) bool
 		data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
 			ProcessorId: "newWalletKey",
 		})
-		data.SendMessage(data.Trans("send_address"))
+		if cryptoFunctions.IsHdWalletSupported(walletCurrency) {
+			data.SendMessage(data.Trans("send_address_or_xpub"))
+		} else {
+			data.SendMessage(data.Trans("send_address"))
+		}
 	} else {
 		// ERC20 Token
 		data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
@@ -63,11 +76,17 @@ func processNewWalletContractAddress(additionalId int64, data *processing.Proces
 	}
 
 	if !(*erc20TokenProcessor).IsContractAddressValid(data.Message) {
-		data.SendMessage(data.Trans("wrong_contract_address"))
+		if cryptoFunctions.IsEthereumAddressChecksumWrong(data.Message) {
+			data.SendMessage(data.Trans("wrong_address_checksum"))
+		} else {
+			data.SendMessage(data.Trans("wrong_contract_address"))
+		}
 		return true
 	}
 
-	data.Static.SetUserStateValue(data.UserId, "walletContractAddress", data.Message)
+	contractAddress := currencies.NormalizeAddress(currencies.Erc20Token, data.Message)
+
+	data.Static.SetUserStateValue(data.UserId, "walletContractAddress", contractAddress)
 	data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
 		ProcessorId: "newWalletKey",
 	})
@@ -96,8 +115,14 @@ func processNewWalletKey(additionalId int64, data *processing.ProcessData) bool
 		return false
 	}
 
-	if !(*currencyProcessor).IsAddressValid(data.Message) {
-		data.SendMessage(data.Trans("wrong_wallet_address"))
+	isHdWallet := cryptoFunctions.IsExtendedPublicKeyValid(walletCurrency, data.Message)
+
+	if !isHdWallet && !(*currencyProcessor).IsAddressValid(data.Message) {
+		if (walletCurrency == currencies.Ether || walletCurrency == currencies.Erc20Token) && cryptoFunctions.IsEthereumAddressChecksumWrong(data.Message) {
+			data.SendMessage(data.Trans("wrong_address_checksum"))
+		} else {
+			data.SendMessage(data.Trans("wrong_wallet_address"))
+		}
 		return true
 	}
 
@@ -109,11 +134,16 @@ func processNewWalletKey(additionalId int64, data *processing.ProcessData) bool
 	walletAddress := currencies.AddressData{
 		Currency: walletCurrency,
 		ContractAddress: walletContractAddress,
-		Address: data.Message,
+		Address: currencies.NormalizeAddress(walletCurrency, data.Message),
 		PriceId: currencies.GetCurrencyPriceId(walletCurrency),
 	}
 
-	walletId := staticFunctions.GetDb(data.Static).CreateWatchOnlyWallet(data.UserId, walletName, walletAddress)
+	var walletId int64
+	if isHdWallet {
+		walletId = staticFunctions.GetDb(data.Static).CreateHdWatchOnlyWallet(data.UserId, walletName, walletAddress)
+	} else {
+		walletId = staticFunctions.GetDb(data.Static).CreateWatchOnlyWallet(data.UserId, walletName, walletAddress)
+	}
 	staticFunctions.GetDb(data.Static).EnableBalanceNotifies(walletId)
 	data.SendMessage(data.Trans("wallet_created"))
 	data.SendDialog(data.Static.MakeDialogFn("wa", walletId, data.Trans, data.Static))
@@ -134,27 +164,39 @@ func processRenamingWallet(walletId int64, data *processing.ProcessData) bool {
 	return true
 }
 
-func processSetWalletPriceId(walletId int64, data *processing.ProcessData) bool {
-	if walletId == 0 {
-		return false
-	}
-
-	re := regexp.MustCompile("https?:\\/\\/coinmarketcap\\.com\\/currencies\\/([\\w-_]+).*")
+// returns the price id from a link to the coin page, or empty string if the link is wrong
+func parsePriceIdLink(link string) string {
+	// the ids are the same for the pages of coins and for the API of the matching rate provider
+	re := regexp.MustCompile("https?:\\/\\/(?:www\\.)?(?:coinmarketcap\\.com\\/currencies|coingecko\\.com\\/(?:\\w+\\/)?coins)\\/([\\w-_]+).*")
 	if re == nil {
 		log.Print("Wrong regexp")
-		return false
+		return ""
 	}
 
-	matches := re.FindStringSubmatch(data.Message)
+	matches := re.FindStringSubmatch(link)
 
 	if len(matches) <= 1 {
+		return ""
+	}
+
+	return matches[1]
+}
+
+func processSetWalletPriceId(walletId int64, data *processing.ProcessData) bool {
+	if walletId == 0 {
+		return false
+	}
+
+	priceId := parsePriceIdLink(data.Message)
+
+	if priceId == "" {
 		staticFunctions.GetDb(data.Static).SetWalletPriceId(walletId, "")
 		data.SendMessage(data.Trans("wrong_coinmarketcap_link"))
 		data.SendDialog(data.Static.MakeDialogFn("wa", walletId, data.Trans, data.Static))
 		return true
 	}
 
-	staticFunctions.GetDb(data.Static).SetWalletPriceId(walletId, matches[1])
+	staticFunctions.GetDb(data.Static).SetWalletPriceId(walletId, priceId)
 	data.SendDialog(data.Static.MakeDialogFn("wa", walletId, data.Trans, data.Static))
 	return true
 }
@@ -174,3 +216,265 @@ func processSetTimezone(additionalId int64, data *processing.ProcessData) bool {
 		return true
 	}
 }
+
+func processNewPriceAlertCoin(additionalId int64, data *processing.ProcessData) bool {
+	priceId := parsePriceIdLink(data.Message)
+
+	// the id itself is also fine
+	if priceId == "" && regexp.MustCompile("^[a-z0-9-]+$").MatchString(data.Message) {
+		priceId = data.Message
+	}
+
+	if priceId == "" {
+		data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+			ProcessorId: "newPriceAlertCoin",
+		})
+		data.SendMessage(data.Trans("wrong_price_alert_coin") + "\n" + data.Trans("send_price_alert_coin"))
+		return true
+	}
+
+	askPriceAlertThreshold(priceId, data)
+	data.SendMessage(getPriceAlertThresholdRequestText(priceId, data))
+	return true
+}
+
+// parses texts like ">7000", "< 0.25 usd" or "<0,25EUR"
+func parsePriceAlertThreshold(text string) (direction currencies.PriceAlertDirection, threshold *big.Float, fiat string, ok bool) {
+	matches := regexp.MustCompile("^\\s*([<>])\\s*([0-9]+(?:[.,][0-9]+)?)\\s*([a-zA-Z]{3})?\\s*$").FindStringSubmatch(text)
+	if len(matches) < 4 {
+		return
+	}
+
+	if matches[1] == "<" {
+		direction = currencies.PriceAlertBelow
+	} else {
+		direction = currencies.PriceAlertAbove
+	}
+
+	threshold, _, err := new(big.Float).Parse(strings.Replace(matches[2], ",", ".", 1), 10)
+	if err != nil {
+		return
+	}
+
+	fiat = strings.ToLower(matches[3])
+	ok = true
+	return
+}
+
+func processNewPriceAlertThreshold(additionalId int64, data *processing.ProcessData) bool {
+	priceId, ok := data.Static.GetUserStateValue(data.UserId, "alertPriceId").(string)
+	if !ok {
+		return false
+	}
+
+	direction, threshold, fiat, ok := parsePriceAlertThreshold(data.Message)
+
+	if ok && fiat == "" {
+		fiat = staticFunctions.GetDb(data.Static).GetUserFiatCurrency(data.UserId)
+	}
+
+	if !ok || !currencies.IsFiatCurrencySupported(fiat) {
+		data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+			ProcessorId: "newPriceAlertThreshold",
+		})
+		data.SendMessage(data.Trans("wrong_price_alert_threshold") + "\n" + getPriceAlertThresholdRequestText(priceId, data))
+		return true
+	}
+
+	data.Static.SetUserStateValue(data.UserId, "alertDirection", direction)
+	data.Static.SetUserStateValue(data.UserId, "alertThreshold", threshold)
+	data.Static.SetUserStateValue(data.UserId, "alertFiat", fiat)
+	data.SendDialog(data.Static.MakeDialogFn("ar", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+// parses texts like "0.01", "0.01 btc", "5 usd" or "5,5EUR", returns the unit in lowercase
+func parseNotifyMinChange(text string) (minChange *big.Float, unit string, ok bool) {
+	matches := regexp.MustCompile("^\\s*([0-9]+(?:[.,][0-9]+)?)\\s*([a-zA-Z]+)?\\s*$").FindStringSubmatch(text)
+	if len(matches) < 3 {
+		return
+	}
+
+	minChange, _, err := new(big.Float).Parse(strings.Replace(matches[1], ",", ".", 1), 10)
+	if err != nil {
+		return
+	}
+
+	unit = strings.ToLower(matches[2])
+	ok = true
+	return
+}
+
+func processSetNotifyMinChange(walletId int64, data *processing.ProcessData) bool {
+	if walletId == 0 {
+		return false
+	}
+
+	minChange, fiat, ok := parseNotifyMinChange(data.Message)
+
+	// the amount in coins can be sent with the symbol of the coin
+	if ok && fiat == strings.ToLower(getWalletCurrencySymbol(walletId, data.Static)) {
+		fiat = ""
+	}
+
+	if !ok || (fiat != "" && !currencies.IsFiatCurrencySupported(fiat)) {
+		data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+			ProcessorId: "setNotifyMinChange",
+			AdditionalId: walletId,
+		})
+		data.SendMessage(data.Trans("wrong_notify_min_change") + "\n" + getNotifyMinChangeRequestText(walletId, data))
+		return true
+	}
+
+	// zero means that any change should be reported
+	if minChange.Sign() == 0 {
+		minChange = nil
+	}
+
+	staticFunctions.GetDb(data.Static).SetBalanceNotifyMinChange(walletId, minChange, fiat)
+	data.SendDialog(data.Static.MakeDialogFn("ws", walletId, data.Trans, data.Static))
+	return true
+}
+
+// parses texts like "23-8" or "23:00 - 08:00"
+func parseQuietHours(text string) (quietHours currencies.QuietHours, ok bool) {
+	matches := regexp.MustCompile("^\\s*([0-9]{1,2})(?::00)?\\s*[-–]\\s*([0-9]{1,2})(?::00)?\\s*$").FindStringSubmatch(text)
+	if len(matches) < 3 {
+		return
+	}
+
+	startHour, err := strconv.Atoi(matches[1])
+	if err != nil || startHour > 23 {
+		return
+	}
+
+	endHour, err := strconv.Atoi(matches[2])
+	if err != nil || endHour > 23 {
+		return
+	}
+
+	quietHours = currencies.QuietHours{
+		StartHour: startHour,
+		EndHour: endHour,
+	}
+	ok = quietHours.IsEnabled()
+	return
+}
+
+func processNewQuietHours(additionalId int64, data *processing.ProcessData) bool {
+	quietHours, ok := parseQuietHours(data.Message)
+
+	if !ok {
+		data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+			ProcessorId: "newQuietHours",
+		})
+		data.SendMessage(data.Trans("wrong_quiet_hours") + "\n" + data.Trans("send_quiet_hours"))
+		return true
+	}
+
+	staticFunctions.GetDb(data.Static).SetUserQuietHours(data.UserId, quietHours)
+	data.SendDialog(data.Static.MakeDialogFn("us", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+// parses texts like "0.5 6500", "0,5 6500.25 eur" or "0.5 6500 usd 14.09.2018"
+func parseTradeLot(text string, location *time.Location) (amount *big.Float, price *big.Float, fiat string, lotTime time.Time, ok bool) {
+	matches := regexp.MustCompile("^\\s*([0-9]+(?:[.,][0-9]+)?)\\s+([0-9]+(?:[.,][0-9]+)?)\\s*([a-zA-Z]{3})?(?:\\s+([0-9]{1,2}\\.[0-9]{1,2}\\.[0-9]{4}))?\\s*$").FindStringSubmatch(text)
+	if len(matches) < 5 {
+		return
+	}
+
+	amount, _, err := new(big.Float).Parse(strings.Replace(matches[1], ",", ".", 1), 10)
+	if err != nil || amount.Sign() <= 0 {
+		return
+	}
+
+	price, _, err = new(big.Float).Parse(strings.Replace(matches[2], ",", ".", 1), 10)
+	if err != nil {
+		return
+	}
+
+	if matches[4] != "" {
+		lotTime, err = time.ParseInLocation("2.1.2006", matches[4], location)
+		if err != nil {
+			return
+		}
+	} else {
+		lotTime = time.Now()
+	}
+
+	fiat = strings.ToLower(matches[3])
+	ok = true
+	return
+}
+
+func processNewTradeLot(walletId int64, data *processing.ProcessData) bool {
+	if walletId == 0 {
+		return false
+	}
+
+	isSell, ok := data.Static.GetUserStateValue(data.UserId, "tradeLotIsSell").(bool)
+	if !ok {
+		return false
+	}
+
+	db := staticFunctions.GetDb(data.Static)
+
+	amount, price, fiat, lotTime, ok := parseTradeLot(data.Message, getUserLocation(data.UserId, data.Static))
+
+	if ok && fiat == "" {
+		fiat = db.GetUserFiatCurrency(data.UserId)
+	}
+
+	if !ok || !currencies.IsFiatCurrencySupported(fiat) {
+		data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+			ProcessorId: "newTradeLot",
+			AdditionalId: walletId,
+		})
+		data.SendMessage(data.Trans("wrong_trade_lot") + "\n" + getTradeLotRequestText(walletId, data))
+		return true
+	}
+
+	db.AddTradeLot(currencies.TradeLot{
+		WalletId: walletId,
+		IsSell: isSell,
+		Amount: amount,
+		Price: price,
+		Fiat: fiat,
+		Time: lotTime,
+	})
+	data.SendDialog(data.Static.MakeDialogFn("tl", walletId, data.Trans, data.Static))
+	return true
+}
+
+func processTransactionLotPrice(walletId int64, data *processing.ProcessData) bool {
+	if walletId == 0 {
+		return false
+	}
+
+	item, ok := getHistoryTransaction(data.UserId, walletId, data.Static)
+	if !ok {
+		return false
+	}
+
+	db := staticFunctions.GetDb(data.Static)
+
+	price, fiat, ok := parseNotifyMinChange(data.Message)
+
+	if ok && fiat == "" {
+		fiat = db.GetUserFiatCurrency(data.UserId)
+	}
+
+	if !ok || !currencies.IsFiatCurrencySupported(fiat) {
+		data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+			ProcessorId: "transactionLotPrice",
+			AdditionalId: walletId,
+		})
+		data.SendMessage(data.Trans("wrong_transaction_price") + "\n" + getTransactionPriceRequestText(walletId, data))
+		return true
+	}
+
+	attachTransactionLot(walletId, &item, price, fiat, data.Static)
+	data.SendDialog(data.Static.MakeDialogFn("tx", walletId, data.Trans, data.Static))
+	return true
+}
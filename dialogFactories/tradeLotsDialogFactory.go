@@ -0,0 +1,236 @@
+package dialogFactories
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/serverData"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"strconv"
+)
+
+// older lots are not shown to keep the message short
+const maxTradeLotsShown int = 15
+
+type tradeLotsVariantPrototype struct {
+	isListItem bool
+	id string
+	textId string
+	process func(int64, *processing.ProcessData) bool
+	rowId int
+}
+
+type tradeLotsDialogFactory struct {
+	variants []tradeLotsVariantPrototype
+}
+
+func MakeTradeLotsDialogFactory() dialogFactory.DialogFactory {
+	return &(tradeLotsDialogFactory{
+		variants: []tradeLotsVariantPrototype{
+			tradeLotsVariantPrototype{
+				isListItem: true,
+				id: "it",
+			},
+			tradeLotsVariantPrototype{
+				id: "buy",
+				textId: "add_buy_lot_btn",
+				process: addBuyLot,
+				rowId:1,
+			},
+			tradeLotsVariantPrototype{
+				id: "sell",
+				textId: "add_sell_lot_btn",
+				process: addSellLot,
+				rowId:1,
+			},
+			tradeLotsVariantPrototype{
+				id: "back",
+				textId: "back_to_wallet",
+				process: backToWallet,
+				rowId:2,
+			},
+		},
+	})
+}
+
+func askTradeLot(walletId int64, isSell bool, data *processing.ProcessData) bool {
+	data.Static.SetUserStateValue(data.UserId, "tradeLotIsSell", isSell)
+	data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+		ProcessorId: "newTradeLot",
+		AdditionalId: walletId,
+	})
+	data.SendMessage(getTradeLotRequestText(walletId, data))
+	return true
+}
+
+func addBuyLot(walletId int64, data *processing.ProcessData) bool {
+	return askTradeLot(walletId, false, data)
+}
+
+func addSellLot(walletId int64, data *processing.ProcessData) bool {
+	return askTradeLot(walletId, true, data)
+}
+
+func getTradeLotRequestText(walletId int64, data *processing.ProcessData) string {
+	return data.Trans("send_trade_lot", map[string]interface{}{
+		"Symbol": getWalletCurrencySymbol(walletId, data.Static),
+		"Fiat":   currencies.GetFiatCurrencyCode(staticFunctions.GetDb(data.Static).GetUserFiatCurrency(data.UserId)),
+	})
+}
+
+func deleteTradeLot(walletId int64, lotId int64, data *processing.ProcessData) bool {
+	db := staticFunctions.GetDb(data.Static)
+
+	if !db.IsTradeLotBelongsToUser(data.UserId, lotId) {
+		return false
+	}
+
+	db.DeleteTradeLot(lotId)
+	data.SubstitudeDialog(data.Static.MakeDialogFn("tl", walletId, data.Trans, data.Static))
+	return true
+}
+
+func getTradeLotTypeTextId(lot *currencies.TradeLot) string {
+	if lot.IsSell {
+		return "trade_lot_sell"
+	}
+	return "trade_lot_buy"
+}
+
+func formatTradeLot(lot *currencies.TradeLot, currencySymbol string, currencyDecimals int, timezone string, trans i18n.TranslateFunc) string {
+	return fmt.Sprintf("%s %s %s × %s, %s",
+		trans(getTradeLotTypeTextId(lot)),
+		cryptoFunctions.FormatFloatCurrencyAmount(lot.Amount, currencyDecimals),
+		currencySymbol,
+		formatFiatValue(lot.Price, lot.Fiat),
+		staticFunctions.FormatDate(lot.Time, timezone),
+	)
+}
+
+func getShownTradeLots(lots []currencies.TradeLot) []currencies.TradeLot {
+	if len(lots) > maxTradeLotsShown {
+		return lots[len(lots) - maxTradeLotsShown:]
+	}
+	return lots
+}
+
+func (factory *tradeLotsDialogFactory) createText(walletId int64, lots []currencies.TradeLot, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) string {
+	db := staticFunctions.GetDb(staticData)
+
+	serverData := serverData.GetServerData(staticData)
+	if serverData == nil {
+		return "Error"
+	}
+
+	walletAddress := db.GetWalletAddress(walletId)
+	currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+	userId := db.GetWalletOwner(walletId)
+	timezone := db.GetUserTimezone(userId)
+
+	var textBuffer bytes.Buffer
+	textBuffer.WriteString(trans("trade_lots_title", map[string]interface{}{
+		"Name": db.GetWalletName(walletId),
+	}))
+
+	if len(lots) == 0 {
+		textBuffer.WriteString(trans("trade_lots_empty"))
+		return textBuffer.String()
+	}
+
+	if len(lots) > maxTradeLotsShown {
+		textBuffer.WriteString(trans("trade_lots_hidden", map[string]interface{}{
+			"Count": len(lots) - maxTradeLotsShown,
+		}))
+	}
+
+	for i, lot := range getShownTradeLots(lots) {
+		textBuffer.WriteString(fmt.Sprintf("\n%d. %s", i + 1, formatTradeLot(&lot, currencySymbol, currencyDecimals, timezone, trans)))
+
+		if lot.TransactionHash != "" {
+			textBuffer.WriteString(" " + trans("trade_lot_from_tx"))
+		}
+	}
+
+	fiat := db.GetUserFiatCurrency(userId)
+	profitLoss := calculateWalletProfitLoss(walletId, fiat, db.GetUserCostBasisMethod(userId), staticData)
+	if profitLoss.isCalculated() {
+		textBuffer.WriteString("\n\n")
+		textBuffer.WriteString(formatProfitLoss(&profitLoss.ProfitLoss, fiat, trans))
+	} else {
+		textBuffer.WriteString("\n")
+	}
+	textBuffer.WriteString(formatProfitLossWarnings(&profitLoss, fiat, currencySymbol, currencyDecimals, timezone, trans))
+
+	return textBuffer.String()
+}
+
+func (factory *tradeLotsDialogFactory) createVariants(walletId int64, lots []currencies.TradeLot, trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	shownLots := getShownTradeLots(lots)
+
+	// delete buttons of the lots go in rows of five above the other buttons
+	itemsInRow := 5
+	itemRowsCount := (len(shownLots) + itemsInRow - 1) / itemsInRow
+
+	for _, variant := range factory.variants {
+		if variant.isListItem {
+			for i, lot := range shownLots {
+				variants = append(variants, dialog.Variant{
+					Id:   variant.id + strconv.FormatInt(lot.LotId, 10),
+					Text: fmt.Sprintf("❌ %d", i + 1),
+					AdditionalId: strconv.FormatInt(walletId, 10),
+					RowId: i / itemsInRow + 1,
+				})
+			}
+		} else {
+			variants = append(variants, dialog.Variant{
+				Id:   variant.id,
+				Text: trans(variant.textId),
+				AdditionalId: strconv.FormatInt(walletId, 10),
+				RowId: itemRowsCount + variant.rowId,
+			})
+		}
+	}
+	return
+}
+
+func (factory *tradeLotsDialogFactory) MakeDialog(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	lots := staticFunctions.GetDb(staticData).GetWalletTradeLots(walletId)
+
+	return &dialog.Dialog{
+		Text:     factory.createText(walletId, lots, trans, staticData),
+		Variants: factory.createVariants(walletId, lots, trans),
+	}
+}
+
+func (factory *tradeLotsDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	walletId, err := strconv.ParseInt(additionalId, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	if !staticFunctions.GetDb(data.Static).IsWalletBelongsToUser(data.UserId, walletId) {
+		return false
+	}
+
+	for _, variant := range factory.variants {
+		if variant.isListItem {
+			if len(variantId) > 2 && variant.id == variantId[0:2] {
+				lotId, err := strconv.ParseInt(variantId[2:], 10, 64)
+				if err != nil {
+					return false
+				}
+				return deleteTradeLot(walletId, lotId, data)
+			}
+		} else if variant.id == variantId {
+			return variant.process(walletId, data)
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package dialogFactories
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
+	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/serverData"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"math/big"
+	"strconv"
+)
+
+type transactionVariantPrototype struct {
+	id string
+	textId string
+	process func(int64, *processing.ProcessData) bool
+	rowId int
+}
+
+type transactionDialogFactory struct {
+	variants []transactionVariantPrototype
+}
+
+func MakeTransactionDialogFactory() dialogFactory.DialogFactory {
+	return &(transactionDialogFactory{
+		variants: []transactionVariantPrototype{
+			transactionVariantPrototype{
+				id: "price",
+				textId: "set_transaction_price",
+				process: askTransactionPrice,
+				rowId:1,
+			},
+			transactionVariantPrototype{
+				id: "back",
+				textId: "back_to_history",
+				process: backToHistory,
+				rowId:2,
+			},
+		},
+	})
+}
+
+func backToHistory(walletId int64, data *processing.ProcessData) bool {
+	// the page is kept in the user state, so we return to the same page
+	data.SubstitudeDialog(data.Static.MakeDialogFn("hi", walletId, data.Trans, data.Static))
+	return true
+}
+
+func askTransactionPrice(walletId int64, data *processing.ProcessData) bool {
+	data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+		ProcessorId: "transactionLotPrice",
+		AdditionalId: walletId,
+	})
+	data.SendMessage(getTransactionPriceRequestText(walletId, data))
+	return true
+}
+
+func getTransactionPriceRequestText(walletId int64, data *processing.ProcessData) string {
+	return data.Trans("send_transaction_price", map[string]interface{}{
+		"Symbol": getWalletCurrencySymbol(walletId, data.Static),
+		"Fiat":   currencies.GetFiatCurrencyCode(staticFunctions.GetDb(data.Static).GetUserFiatCurrency(data.UserId)),
+	})
+}
+
+// incoming transactions become purchases and outgoing become sales of the transferred amount
+func attachTransactionLot(walletId int64, item *currencies.TransactionsHistoryItem, price *big.Float, fiat string, staticData *processing.StaticProccessStructs) {
+	serverData := serverData.GetServerData(staticData)
+	if serverData == nil {
+		return
+	}
+
+	db := staticFunctions.GetDb(staticData)
+	walletAddress := db.GetWalletAddress(walletId)
+	_, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+
+	db.AddTradeLot(currencies.TradeLot{
+		WalletId: walletId,
+		IsSell: isHistoryItemSent(item, &walletAddress),
+		Amount: cryptoFunctions.GetFloatBalance(item.Amount, currencyDecimals),
+		Price: price,
+		Fiat: fiat,
+		Time: item.Time,
+		TransactionHash: item.Hash,
+	})
+}
+
+func getTransactionStatusTextId(status currencies.TransactionStatus) string {
+	switch status {
+	case currencies.TransactionPending:
+		return "tx_status_pending"
+	case currencies.TransactionFailed:
+		return "tx_status_failed"
+	default:
+		return "tx_status_confirmed"
+	}
+}
+
+func (factory *transactionDialogFactory) createText(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) string {
+	serverData := serverData.GetServerData(staticData)
+
+	if serverData == nil {
+		return "Error"
+	}
+
+	db := staticFunctions.GetDb(staticData)
+	userId := db.GetWalletOwner(walletId)
+
+	item, ok := getHistoryTransaction(userId, walletId, staticData)
+	if !ok {
+		return trans("tx_not_found")
+	}
+
+	walletAddress := db.GetWalletAddress(walletId)
+	userTimezone := db.GetUserTimezone(userId)
+
+	currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+
+	var textBuffer bytes.Buffer
+
+	textBuffer.WriteString(trans("tx_title"))
+
+	if item.Hash != "" {
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_hash"), item.Hash))
+	}
+
+	textBuffer.WriteString(fmt.Sprintf(trans("tx_time"), staticFunctions.FormatTimestamp(item.Time, userTimezone)))
+
+	amountText := cryptoFunctions.FormatCurrencyAmount(item.Amount, currencyDecimals)
+	if isHistoryItemSent(&item, &walletAddress) {
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_sent"), amountText, currencySymbol))
+	} else {
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_recieved"), amountText, currencySymbol))
+	}
+
+	if item.From != "" {
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_from"), item.From))
+	}
+
+	if item.To != "" {
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_to"), item.To))
+	}
+
+	if item.Fee != nil {
+		feeCurrency := currencies.GetFeeCurrency(walletAddress.Currency)
+		feeSymbol, feeDecimals := currencySymbol, currencyDecimals
+		if feeCurrency != walletAddress.Currency {
+			feeSymbol = currencies.GetCurrencySymbol(feeCurrency)
+			feeDecimals = currencies.GetCurrencyDecimals(feeCurrency)
+		}
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_fee"), cryptoFunctions.FormatCurrencyAmount(item.Fee, feeDecimals), feeSymbol))
+	}
+
+	if item.BlockHeight > 0 {
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_block"), item.BlockHeight))
+	}
+
+	if item.Confirmations > 0 {
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_confirmations"), item.Confirmations))
+	}
+
+	textBuffer.WriteString(trans(getTransactionStatusTextId(item.Status)))
+
+	if lot, ok := db.GetTransactionTradeLot(walletId, item.Hash); ok {
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_lot_price"), formatFiatValue(lot.Price, lot.Fiat), currencySymbol))
+	}
+
+	if item.Hash != "" {
+		textBuffer.WriteString(fmt.Sprintf(trans("tx_explorer_link"), currencies.GetTransactionExplorerUrl(walletAddress.Currency, item.Hash)))
+	}
+
+	return textBuffer.String()
+}
+
+func (factory *transactionDialogFactory) createVariants(walletId int64, trans i18n.TranslateFunc) (variants []dialog.Variant) {
+	variants = make([]dialog.Variant, 0)
+
+	for _, variant := range factory.variants {
+		variants = append(variants, dialog.Variant{
+			Id:   variant.id,
+			Text: trans(variant.textId),
+			AdditionalId: strconv.FormatInt(walletId, 10),
+			RowId: variant.rowId,
+		})
+	}
+	return
+}
+
+func (factory *transactionDialogFactory) MakeDialog(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	return &dialog.Dialog{
+		Text:     factory.createText(walletId, trans, staticData),
+		Variants: factory.createVariants(walletId, trans),
+	}
+}
+
+func (factory *transactionDialogFactory) ProcessVariant(variantId string, additionalId string, data *processing.ProcessData) bool {
+	walletId, err := strconv.ParseInt(additionalId, 10, 64)
+
+	if err != nil {
+		return false
+	}
+
+	if !staticFunctions.GetDb(data.Static).IsWalletBelongsToUser(data.UserId, walletId) {
+		return false
+	}
+
+	for _, variant := range factory.variants {
+		if variant.id == variantId {
+			return variant.process(walletId, data)
+		}
+	}
+	return false
+}
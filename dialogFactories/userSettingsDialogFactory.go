@@ -1,10 +1,12 @@
 package dialogFactories
 
 import (
+	"fmt"
 	"github.com/gameraccoon/telegram-bot-skeleton/dialog"
 	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
 	"github.com/gameraccoon/telegram-bot-skeleton/processing"
 	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
 	static "github.com/gameraccoon/telegram-accountant-bot/staticData"
 )
@@ -12,6 +14,7 @@ import (
 type userSettingsData struct {
 	userId int64
 	staticData *processing.StaticProccessStructs
+	quietHours currencies.QuietHours
 }
 
 type userSettingsVariantPrototype struct {
@@ -41,11 +44,36 @@ func MakeUserSettingsDialogFactory() dialogFactory.DialogFactory {
 				process: changeTimezone,
 				rowId:2,
 			},
+			userSettingsVariantPrototype{
+				id: "fiat",
+				textId: "change_fiat_currency",
+				process: changeFiatCurrency,
+				rowId:3,
+			},
+			userSettingsVariantPrototype{
+				id: "digest",
+				textId: "change_digest",
+				process: changeDigest,
+				rowId:4,
+			},
+			userSettingsVariantPrototype{
+				id: "quiet",
+				textId: "set_quiet_hours",
+				process: setQuietHours,
+				rowId:5,
+			},
+			userSettingsVariantPrototype{
+				id: "noquiet",
+				textId: "disable_quiet_hours",
+				process: disableQuietHours,
+				rowId:5,
+				isActiveFn: isQuietHoursEnabled,
+			},
 			userSettingsVariantPrototype{
 				id: "back",
 				textId: "back_to_list",
 				process: backToList, // defined in walletDialogFactory
-				rowId:3,
+				rowId:6,
 			},
 		},
 	})
@@ -64,6 +92,41 @@ func changeTimezone(userId int64, data *processing.ProcessData) bool {
 	return true
 }
 
+func changeFiatCurrency(userId int64, data *processing.ProcessData) bool {
+	data.SubstitudeDialog(data.Static.MakeDialogFn("fc", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func changeDigest(userId int64, data *processing.ProcessData) bool {
+	data.SubstitudeDialog(data.Static.MakeDialogFn("ds", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func isQuietHoursEnabled(settingsData *userSettingsData) bool {
+	return settingsData.quietHours.IsEnabled()
+}
+
+func setQuietHours(userId int64, data *processing.ProcessData) bool {
+	data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+		ProcessorId: "newQuietHours",
+	})
+	data.SubstitudeMessage(data.Trans("send_quiet_hours"))
+	return true
+}
+
+func disableQuietHours(userId int64, data *processing.ProcessData) bool {
+	staticFunctions.GetDb(data.Static).SetUserQuietHours(data.UserId, currencies.QuietHours{})
+	data.SubstitudeDialog(data.Static.MakeDialogFn("us", data.UserId, data.Trans, data.Static))
+	return true
+}
+
+func getQuietHoursText(quietHours currencies.QuietHours, trans i18n.TranslateFunc) string {
+	if !quietHours.IsEnabled() {
+		return trans("quiet_hours_off")
+	}
+	return fmt.Sprintf("%02d:00–%02d:00", quietHours.StartHour, quietHours.EndHour)
+}
+
 func (factory *userSettingsDialogFactory) createVariants(settingsData *userSettingsData, trans i18n.TranslateFunc) (variants []dialog.Variant) {
 	variants = make([]dialog.Variant, 0)
 
@@ -80,15 +143,18 @@ func (factory *userSettingsDialogFactory) createVariants(settingsData *userSetti
 }
 
 func (factory *userSettingsDialogFactory) MakeDialog(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
+	db := staticFunctions.GetDb(staticData)
+
 	settingsData := userSettingsData {
 		userId: userId,
 		staticData: staticData,
+		quietHours: db.GetUserQuietHours(userId),
 	}
 
-	db := staticFunctions.GetDb(staticData)
-
 	language := db.GetUserLanguage(userId)
 	timezone := db.GetUserTimezone(userId)
+	fiat := db.GetUserFiatCurrency(userId)
+	digest := db.GetUserDigest(userId)
 
 	config, configCastSuccess := staticData.Config.(static.StaticConfiguration)
 
@@ -108,6 +174,9 @@ func (factory *userSettingsDialogFactory) MakeDialog(userId int64, trans i18n.Tr
 	translationMap := map[string]interface{} {
 		"Lang":     langName,
 		"Timezone": timezone,
+		"Fiat":     currencies.GetFiatCurrencyCode(fiat),
+		"Digest":   getDigestDescription(digest, trans),
+		"Quiet":    getQuietHoursText(settingsData.quietHours, trans),
 	}
 
 	return &dialog.Dialog{
@@ -9,6 +9,7 @@ import (
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"github.com/gameraccoon/telegram-accountant-bot/serverData"
 	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/wallettypes"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -49,6 +50,18 @@ func MakeWalletDialogFactory() dialogFactory.DialogFactory {
 				isActiveFn: isHistoryEnabled,
 				rowId:1,
 			},
+			walletVariantPrototype{
+				id: "chart",
+				textId: "chart",
+				process: showWalletChart,
+				rowId:2,
+			},
+			walletVariantPrototype{
+				id: "lots",
+				textId: "trade_lots",
+				process: showTradeLots,
+				rowId:2,
+			},
 			walletVariantPrototype{
 				id: "set",
 				textId: "settings",
@@ -66,7 +79,12 @@ func MakeWalletDialogFactory() dialogFactory.DialogFactory {
 }
 
 func isHistoryEnabled(walletId int64, staticData *processing.StaticProccessStructs) bool {
-	walletAddress := staticFunctions.GetDb(staticData).GetWalletAddress(walletId)
+	db := staticFunctions.GetDb(staticData)
+	// history of HD wallets is spread over many addresses
+	if db.GetWalletType(walletId) == wallettypes.HdWatchOnly {
+		return false
+	}
+	walletAddress := db.GetWalletAddress(walletId)
 	return currencies.IsHistoryEnabled(walletAddress.Currency)
 }
 
@@ -80,10 +98,21 @@ func receiveToWallet(walletId int64, data *processing.ProcessData) bool {
 }
 
 func showHistory(walletId int64, data *processing.ProcessData) bool {
+	setHistoryPage(data.UserId, walletId, 0, data.Static) // declared in historyDialogFactory.go
 	data.SubstitudeDialog(data.Static.MakeDialogFn("hi", walletId, data.Trans, data.Static))
 	return true
 }
 
+func showWalletChart(walletId int64, data *processing.ProcessData) bool {
+	data.SubstitudeDialog(data.Static.MakeDialogFn("cp", walletId, data.Trans, data.Static))
+	return true
+}
+
+func showTradeLots(walletId int64, data *processing.ProcessData) bool {
+	data.SubstitudeDialog(data.Static.MakeDialogFn("tl", walletId, data.Trans, data.Static))
+	return true
+}
+
 func walletSettings(walletId int64, data *processing.ProcessData) bool {
 	data.SubstitudeDialog(data.Static.MakeDialogFn("ws", walletId, data.Trans, data.Static))
 	return true
@@ -95,7 +124,9 @@ func backToList(walletId int64, data *processing.ProcessData) bool {
 }
 
 func (factory *walletDialogFactory) getDialogText(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) (result string) {
-	walletAddress := staticFunctions.GetDb(staticData).GetWalletAddress(walletId)
+	db := staticFunctions.GetDb(staticData)
+
+	walletAddress := db.GetWalletAddress(walletId)
 
 	serverData := serverData.GetServerData(staticData)
 
@@ -106,7 +137,7 @@ func (factory *walletDialogFactory) getDialogText(walletId int64, trans i18n.Tra
 	balance := serverData.GetBalance(walletAddress)
 
 	if balance == nil {
-		return trans("no_data")
+		return fmt.Sprintf("<b>%s</b>\n%s", db.GetWalletName(walletId), trans("balance_unknown"))
 	}
 
 	currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
@@ -125,22 +156,29 @@ func (factory *walletDialogFactory) getDialogText(walletId int64, trans i18n.Tra
 		currencySymbol,
 	)
 
-	toUsdRate := serverData.GetRateToUsd(walletAddress.PriceId)
+	fiat := db.GetUserFiatCurrency(db.GetWalletOwner(walletId))
 
-	if toUsdRate == nil {
-		return
-	}
+	rate := serverData.GetRate(walletAddress.PriceId, fiat)
 
-	usdCost := new(big.Float).Mul(floatBalance, toUsdRate)
+	if rate != nil {
+		fiatCost := new(big.Float).Mul(floatBalance, rate)
 
-	if usdCost == nil {
-		return
+		result = result + fmt.Sprintf("\n%s %s",
+			fiatCost.Text('f', 2),
+			currencies.GetFiatCurrencyCode(fiat),
+		)
 	}
 
-	result = result + fmt.Sprintf("\n%s %s",
-		usdCost.Text('f', 2),
-		trans("usd"),
-	)
+	if staleText := staticFunctions.GetStaleBalancesText(db.GetUserTimezone(db.GetWalletOwner(walletId)), trans, serverData.GetBalanceStatus(walletAddress)); staleText != "" {
+		result = result + "\n" + staleText
+	}
+
+	profitLoss := calculateWalletProfitLoss(walletId, fiat, db.GetUserCostBasisMethod(db.GetWalletOwner(walletId)), staticData)
+	if profitLoss.isCalculated() {
+		result = result + "\n\n" + formatProfitLoss(&profitLoss.ProfitLoss, fiat, trans)
+	} else if len(profitLoss.otherFiatLots) > 0 {
+		result = result + "\n" + formatOtherFiatLotsWarning(&profitLoss, fiat, trans)
+	}
 
 	return
 }
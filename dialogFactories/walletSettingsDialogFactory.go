@@ -5,8 +5,10 @@ import (
 	"github.com/gameraccoon/telegram-bot-skeleton/dialogFactory"
 	"github.com/gameraccoon/telegram-bot-skeleton/processing"
 	"github.com/nicksnyder/go-i18n/i18n"
-	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/serverData"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
 	"strconv"
 )
 
@@ -14,6 +16,7 @@ type walletSettingsData struct {
 	walletId int64
 	staticData *processing.StaticProccessStructs
 	isNotificationsEnabled bool
+	notifySettings currencies.BalanceNotifySettings
 }
 
 type walletSettingsVariantPrototype struct {
@@ -64,11 +67,25 @@ func MakeWalletSettingsDialogFactory() dialogFactory.DialogFactory {
 				rowId:3,
 				isActiveFn: isNotificationsEnabled,
 			},
+			walletSettingsVariantPrototype{
+				id: "ntfdir",
+				textId: "change_notify_direction",
+				process: changeNotifyDirection,
+				rowId:4,
+				isActiveFn: isNotificationsEnabled,
+			},
+			walletSettingsVariantPrototype{
+				id: "ntfmin",
+				textId: "set_notify_min_change",
+				process: setNotifyMinChange,
+				rowId:4,
+				isActiveFn: isNotificationsEnabled,
+			},
 			walletSettingsVariantPrototype{
 				id: "back",
 				textId: "back_to_wallet",
 				process: backToWallet,
-				rowId:4,
+				rowId:5,
 			},
 		},
 	})
@@ -115,6 +132,76 @@ func disableBalanceNotifications(walletId int64, data *processing.ProcessData) b
 	return true
 }
 
+// switches between both directions, only incoming and only outgoing changes
+func changeNotifyDirection(walletId int64, data *processing.ProcessData) bool {
+	db := staticFunctions.GetDb(data.Static)
+
+	var newDirection currencies.BalanceNotifyDirection
+	switch db.GetBalanceNotifySettings(walletId).Direction {
+	case currencies.BalanceNotifyBoth:
+		newDirection = currencies.BalanceNotifyIncoming
+	case currencies.BalanceNotifyIncoming:
+		newDirection = currencies.BalanceNotifyOutgoing
+	default:
+		newDirection = currencies.BalanceNotifyBoth
+	}
+
+	db.SetBalanceNotifyDirection(walletId, newDirection)
+
+	data.SubstitudeDialog(data.Static.MakeDialogFn("ws", walletId, data.Trans, data.Static))
+	return true
+}
+
+func setNotifyMinChange(walletId int64, data *processing.ProcessData) bool {
+	data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
+		ProcessorId: "setNotifyMinChange",
+		AdditionalId: walletId,
+	})
+	data.SubstitudeMessage(getNotifyMinChangeRequestText(walletId, data))
+	return true
+}
+
+func getWalletCurrencySymbol(walletId int64, staticData *processing.StaticProccessStructs) string {
+	serverData := serverData.GetServerData(staticData)
+	if serverData == nil {
+		return ""
+	}
+
+	walletAddress := staticFunctions.GetDb(staticData).GetWalletAddress(walletId)
+	currencySymbol, _ := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+	return currencySymbol
+}
+
+func getNotifyMinChangeRequestText(walletId int64, data *processing.ProcessData) string {
+	return data.Trans("send_notify_min_change", map[string]interface{}{
+		"Sign": getWalletCurrencySymbol(walletId, data.Static),
+	})
+}
+
+func getNotifyDirectionTextId(direction currencies.BalanceNotifyDirection) string {
+	switch direction {
+	case currencies.BalanceNotifyIncoming:
+		return "notify_direction_incoming"
+	case currencies.BalanceNotifyOutgoing:
+		return "notify_direction_outgoing"
+	default:
+		return "notify_direction_both"
+	}
+}
+
+func getNotifyMinChangeText(settingsData *walletSettingsData, trans i18n.TranslateFunc) string {
+	minChange := settingsData.notifySettings.MinChange
+	if minChange == nil {
+		return trans("notify_min_change_any")
+	}
+
+	if settingsData.notifySettings.MinChangeFiat != "" {
+		return cryptoFunctions.FormatRate(minChange) + " " + currencies.GetFiatCurrencyCode(settingsData.notifySettings.MinChangeFiat)
+	}
+
+	return minChange.Text('f', -1) + " " + getWalletCurrencySymbol(settingsData.walletId, settingsData.staticData)
+}
+
 func changePriceId(walletId int64, data *processing.ProcessData) bool {
 	data.Static.SetUserStateTextProcessor(data.UserId, &processing.AwaitingTextProcessorData{
 		ProcessorId: "setWalletPriceId",
@@ -146,7 +233,8 @@ func (factory *walletSettingsDialogFactory) createVariants(settingsData *walletS
 }
 
 func (factory *walletSettingsDialogFactory) MakeDialog(walletId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) *dialog.Dialog {
-	isNotificationsEnabled := staticFunctions.GetDb(staticData).IsBalanceNotifiesEnabled(walletId)
+	db := staticFunctions.GetDb(staticData)
+	isNotificationsEnabled := db.IsBalanceNotifiesEnabled(walletId)
 
 	settingsData := walletSettingsData {
 		walletId: walletId,
@@ -156,7 +244,11 @@ func (factory *walletSettingsDialogFactory) MakeDialog(walletId int64, trans i18
 
 	var notificationsText string
 	if isNotificationsEnabled {
-		notificationsText = trans("balance_notify_enabled")
+		settingsData.notifySettings = db.GetBalanceNotifySettings(walletId)
+		notificationsText = trans("balance_notify_enabled") + "\n" + trans("balance_notify_filters", map[string]interface{}{
+			"Direction": trans(getNotifyDirectionTextId(settingsData.notifySettings.Direction)),
+			"MinChange": getNotifyMinChangeText(&settingsData, trans),
+		})
 	} else {
 		notificationsText = trans("balance_notify_disabled")
 	}
@@ -55,6 +55,12 @@ func MakeWalletsListDialogFactory() dialogFactory.DialogFactory {
 				isActiveFn: isTheFirstPage,
 				process: addWallet,
 			},
+			walletsListDialogVariantPrototype{
+				id: "chart",
+				textId: "chart",
+				isActiveFn: isTheFirstPage,
+				process: showPortfolioChart,
+			},
 			walletsListDialogVariantPrototype{
 				isListItem: true,
 				id: "it",
@@ -105,6 +111,12 @@ func addWallet(additionalId string, data *processing.ProcessData) bool {
 	return true
 }
 
+func showPortfolioChart(additionalId string, data *processing.ProcessData) bool {
+	// zero instead of a wallet id means the whole portfolio
+	data.SubstitudeDialog(data.Static.MakeDialogFn("cp", 0, data.Trans, data.Static))
+	return true
+}
+
 func moveForward(additionalId string, data *processing.ProcessData) bool {
 	ids, _ := staticFunctions.GetDb(data.Static).GetUserWallets(data.UserId)
 	itemsCount := len(ids)
@@ -235,12 +247,16 @@ type balanceLineKey struct {
 }
 
 func (factory *walletsListDialogFactory) GetDialogCaption(userId int64, trans i18n.TranslateFunc, staticData *processing.StaticProccessStructs) string {
-	walletAddresses := staticFunctions.GetDb(staticData).GetUserWalletAddresses(userId)
+	db := staticFunctions.GetDb(staticData)
+
+	walletAddresses := db.GetUserWalletAddresses(userId)
 
 	if len(walletAddresses) == 0 {
 		return ""
 	}
 
+	balanceStatuses := []serverData.BalanceStatus{}
+
 	serverData := serverData.GetServerData(staticData)
 
 	if serverData == nil {
@@ -267,20 +283,35 @@ func (factory *walletsListDialogFactory) GetDialogCaption(userId int64, trans i1
 	var textBuffer bytes.Buffer
 	textBuffer.WriteString(trans("balance_header"))
 
-	usdSum := new(big.Float)
+	fiat := db.GetUserFiatCurrency(userId)
+	fiatSum := new(big.Float)
+	// some balances are unknown, so they are not counted
+	isIncomplete := false
 
 	for key, addresses := range groupedWallets {
 		sumBalance := big.NewInt(0)
+		unknownCount := 0
 
 		for _, address := range addresses {
 			balance := serverData.GetBalance(address)
 			if balance != nil {
 				sumBalance.Add(sumBalance, balance)
+			} else {
+				unknownCount++
 			}
+			balanceStatuses = append(balanceStatuses, serverData.GetBalanceStatus(address))
 		}
 
 		currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, key.currency, key.contractAddress)
 
+		if unknownCount == len(addresses) {
+			isIncomplete = true
+			textBuffer.WriteString(trans("balance_line_unknown", map[string]interface{}{
+				"Symbol": currencySymbol,
+			}) + "\n")
+			continue
+		}
+
 		floatBalance := cryptoFunctions.GetFloatBalance(sumBalance, currencyDecimals)
 
 		if floatBalance == nil {
@@ -288,17 +319,30 @@ func (factory *walletsListDialogFactory) GetDialogCaption(userId int64, trans i1
 			continue
 		}
 
-		toUsdRate := serverData.GetRateToUsd(key.priceId)
+		rate := serverData.GetRate(key.priceId, fiat)
 
-		if toUsdRate != nil {
-			usdSum.Add(usdSum, new(big.Float).Mul(floatBalance, toUsdRate))
+		if rate != nil {
+			fiatSum.Add(fiatSum, new(big.Float).Mul(floatBalance, rate))
 		}
 
-		textBuffer.WriteString(cryptoFunctions.FormatFloatCurrencyAmount(floatBalance, currencyDecimals) + " " + currencySymbol + "\n")
+		textBuffer.WriteString(cryptoFunctions.FormatFloatCurrencyAmount(floatBalance, currencyDecimals) + " " + currencySymbol)
+		if unknownCount > 0 {
+			isIncomplete = true
+			textBuffer.WriteString(" " + trans("balance_incomplete"))
+		}
+		textBuffer.WriteString("\n")
+	}
+
+	if fiatSum != nil {
+		textBuffer.WriteString(fmt.Sprintf("%s %s %s", trans("sum"), fiatSum.Text('f', 2), currencies.GetFiatCurrencyCode(fiat)))
+		if isIncomplete {
+			textBuffer.WriteString(" " + trans("balance_incomplete"))
+		}
+		textBuffer.WriteString("\n")
 	}
 
-	if usdSum != nil {
-		textBuffer.WriteString(fmt.Sprintf("%s %s %s\n", trans("sum"), usdSum.Text('f', 2), trans("usd")))
+	if staleText := staticFunctions.GetStaleBalancesText(db.GetUserTimezone(userId), trans, balanceStatuses...); staleText != "" {
+		textBuffer.WriteString(staleText + "\n")
 	}
 
 	return textBuffer.String()
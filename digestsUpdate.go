@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/database"
+	"github.com/gameraccoon/telegram-accountant-bot/serverData"
+	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
+	"log"
+	"math/big"
+	"time"
+)
+
+// how many coins are shown in the biggest movers section
+const digestMoversCount int = 3
+
+type digestCoinKey struct {
+	currency currencies.Currency
+	contractAddress string
+}
+
+func updateDigests(staticData *processing.StaticProccessStructs) {
+	db := staticFunctions.GetDb(staticData)
+
+	serverData := serverData.GetServerData(staticData)
+
+	if serverData == nil {
+		log.Print("ServerData is nil")
+		return
+	}
+
+	now := time.Now()
+
+	for _, digest := range db.GetAllDigests() {
+		if !digest.IsDue(now, getUserLocation(db, digest.UserId)) {
+			continue
+		}
+
+		SendDigest(staticData, db, serverData, digest.UserId, now)
+	}
+}
+
+// balances of the user's wallets summed by coins, only the cached balances are used
+// coins with unknown balances are marked, the statuses are returned to warn about stale balances
+func makeDigestCoins(db *database.AccountDb, serverData serverData.ServerDataInterface, userId int64, fiat string) (coins []currencies.DigestCoin, statuses []serverData.BalanceStatus) {
+	coinIndexes := make(map[digestCoinKey]int)
+	sumBalances := make([]*big.Int, 0)
+	coinDecimals := make([]int, 0)
+	coinPriceIds := make([]string, 0)
+	knownCounts := make([]int, 0)
+
+	for _, walletAddress := range db.GetUserWalletAddresses(userId) {
+		key := digestCoinKey{
+			currency: walletAddress.Currency,
+			contractAddress: walletAddress.ContractAddress,
+		}
+
+		index, isFound := coinIndexes[key]
+		if !isFound {
+			currencySymbol, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, walletAddress.Currency, walletAddress.ContractAddress)
+
+			index = len(coins)
+			coinIndexes[key] = index
+			coins = append(coins, currencies.DigestCoin{
+				CoinKey: fmt.Sprintf("%d:%s", walletAddress.Currency, walletAddress.ContractAddress),
+				Symbol: currencySymbol,
+			})
+			sumBalances = append(sumBalances, big.NewInt(0))
+			coinDecimals = append(coinDecimals, currencyDecimals)
+			coinPriceIds = append(coinPriceIds, walletAddress.PriceId)
+			knownCounts = append(knownCounts, 0)
+		}
+
+		statuses = append(statuses, serverData.GetBalanceStatus(walletAddress))
+
+		balance := serverData.GetCachedBalance(walletAddress)
+		if balance == nil {
+			coins[index].IsIncomplete = true
+			continue
+		}
+
+		knownCounts[index]++
+		sumBalances[index].Add(sumBalances[index], balance)
+		if coinPriceIds[index] == "" {
+			coinPriceIds[index] = walletAddress.PriceId
+		}
+	}
+
+	for index := range coins {
+		if knownCounts[index] == 0 {
+			continue
+		}
+
+		coins[index].Balance = cryptoFunctions.GetFloatBalance(sumBalances[index], coinDecimals[index])
+
+		rate := serverData.GetRate(coinPriceIds[index], fiat)
+		if rate != nil {
+			coins[index].Value = new(big.Float).Mul(coins[index].Balance, rate)
+		}
+	}
+
+	return
+}
+
+func formatSignedAmount(amount *big.Float, format func(*big.Float) string) string {
+	if amount.Sign() > 0 {
+		return "+" + format(amount)
+	} else if amount.Sign() < 0 {
+		return "-" + format(new(big.Float).Neg(amount))
+	}
+	return format(amount)
+}
+
+func formatFiatAmount(amount *big.Float) string {
+	return amount.Text('f', 2)
+}
+
+func formatCoinAmount(amount *big.Float) string {
+	return cryptoFunctions.FormatFloatCurrencyAmount(amount, 8)
+}
+
+func makeDigestText(changes []currencies.DigestCoinChange, fiat string, trans i18n.TranslateFunc) string {
+	fiatCode := currencies.GetFiatCurrencyCode(fiat)
+
+	var textBuffer bytes.Buffer
+	textBuffer.WriteString(trans("digest_title"))
+
+	totalValue := new(big.Float)
+	totalValueDiff := new(big.Float)
+	isTotalDiffFound := false
+	isTotalDiffKnown := true
+	isTotalIncomplete := false
+
+	for _, change := range changes {
+		if change.IsIncomplete {
+			isTotalIncomplete = true
+			isTotalDiffKnown = false
+		}
+
+		if change.Value != nil {
+			totalValue.Add(totalValue, change.Value)
+		}
+
+		if change.ValueDiff != nil {
+			totalValueDiff.Add(totalValueDiff, change.ValueDiff)
+			isTotalDiffFound = true
+		} else if change.Value != nil {
+			isTotalDiffKnown = false
+		}
+	}
+
+	textBuffer.WriteString("\n" + trans("digest_total", map[string]interface{}{
+		"Value": formatFiatAmount(totalValue),
+		"Fiat":  fiatCode,
+	}))
+
+	if isTotalDiffFound && isTotalDiffKnown {
+		textBuffer.WriteString(fmt.Sprintf(" (%s %s)", formatSignedAmount(totalValueDiff, formatFiatAmount), fiatCode))
+	}
+
+	if isTotalIncomplete {
+		textBuffer.WriteString(" " + trans("balance_incomplete"))
+	}
+
+	textBuffer.WriteString("\n\n" + trans("digest_balances"))
+
+	for _, change := range changes {
+		if change.Balance == nil {
+			textBuffer.WriteString("\n" + trans("balance_line_unknown", map[string]interface{}{
+				"Symbol": change.Symbol,
+			}))
+			continue
+		}
+
+		textBuffer.WriteString(fmt.Sprintf("\n%s %s", formatCoinAmount(change.Balance), change.Symbol))
+
+		if change.Value != nil {
+			textBuffer.WriteString(fmt.Sprintf(" ≈ %s %s", formatFiatAmount(change.Value), fiatCode))
+		}
+
+		if change.BalanceDiff != nil && change.BalanceDiff.Sign() != 0 {
+			textBuffer.WriteString(fmt.Sprintf(", %s %s", formatSignedAmount(change.BalanceDiff, formatCoinAmount), change.Symbol))
+		}
+
+		if change.ValueDiff != nil && change.ValueDiff.Sign() != 0 {
+			textBuffer.WriteString(fmt.Sprintf(", %s %s", formatSignedAmount(change.ValueDiff, formatFiatAmount), fiatCode))
+		}
+
+		if change.IsIncomplete {
+			textBuffer.WriteString(" " + trans("balance_incomplete"))
+		}
+	}
+
+	movers := currencies.GetBiggestMovers(changes, digestMoversCount)
+	if len(movers) > 0 {
+		textBuffer.WriteString("\n\n" + trans("digest_movers"))
+
+		for _, mover := range movers {
+			textBuffer.WriteString(fmt.Sprintf("\n<b>%s</b> %s %s", mover.Symbol, formatSignedAmount(mover.ValueDiff, formatFiatAmount), fiatCode))
+		}
+	}
+
+	return textBuffer.String()
+}
+
+func SendDigest(staticData *processing.StaticProccessStructs, db *database.AccountDb, serverData serverData.ServerDataInterface, userId int64, now time.Time) {
+	fiat := db.GetUserFiatCurrency(userId)
+
+	coins, statuses := makeDigestCoins(db, serverData, userId, fiat)
+
+	previousCoins, previousFiat := db.GetLastDigestCoins(userId)
+
+	// the costs in different currencies can't be compared
+	if previousFiat != fiat {
+		for index := range previousCoins {
+			previousCoins[index].Value = nil
+		}
+	}
+
+	changes := currencies.MakeDigestChanges(coins, previousCoins)
+
+	// remember that the digest is sent before sending to not to send it twice
+	db.SetDigestSent(userId, now, fiat, currencies.ReplaceIncompleteDigestCoins(coins, previousCoins))
+
+	if len(changes) == 0 {
+		return
+	}
+
+	translateFn := staticFunctions.FindTransFunction(userId, staticData)
+
+	text := makeDigestText(changes, fiat, translateFn)
+	if staleText := staticFunctions.GetStaleBalancesText(db.GetUserTimezone(userId), translateFn, statuses...); staleText != "" {
+		text += "\n\n" + staleText
+	}
+
+	staticData.Chat.SendMessage(db.GetUserChatId(userId), text, 0)
+}
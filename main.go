@@ -7,6 +7,8 @@ import (
 	"github.com/gameraccoon/telegram-bot-skeleton/processing"
 	"github.com/gameraccoon/telegram-bot-skeleton/telegramChat"
 	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"github.com/gameraccoon/telegram-accountant-bot/database"
 	"github.com/gameraccoon/telegram-accountant-bot/dialogFactories"
 	"github.com/gameraccoon/telegram-accountant-bot/serverData"
@@ -14,6 +16,7 @@ import (
 	"io/ioutil"
 	"log"
 	"strings"
+	"time"
 )
 
 func init() {
@@ -41,6 +44,44 @@ func loadConfig(path string) (config static.StaticConfiguration, err error) {
 	return
 }
 
+func getConfigCurrency(key string) (currencies.Currency, bool) {
+	if strings.EqualFold(key, "ERC20") {
+		return currencies.Erc20Token, true
+	}
+	return currencies.GetCurrencyBySymbol(key)
+}
+
+func setBalanceProviders(config *static.StaticConfiguration) {
+	for key, providersData := range config.BalanceProviders {
+		currency, ok := getConfigCurrency(key)
+		if !ok {
+			log.Fatalf("Unknown currency in balanceProviders: %s", key)
+		}
+
+		providers := []cryptoFunctions.BalanceProvider{}
+		for _, providerData := range providersData {
+			provider := cryptoFunctions.MakeBalanceProvider(currency, providerData.Name, providerData.Url, providerData.ApiKey)
+			if provider == nil {
+				log.Fatalf("Balance provider %s doesn't support %s", providerData.Name, key)
+			}
+			providers = append(providers, provider)
+		}
+
+		cryptoFunctions.SetBalanceProviders(currency, providers)
+	}
+
+	cryptoFunctions.SetBalanceCrossCheckEnabled(config.CrossCheckBalances)
+}
+
+func setHistoryApiUrls(config *static.StaticConfiguration) {
+	for key, url := range config.HistoryApiUrls {
+		currency, ok := getConfigCurrency(key)
+		if !ok || !cryptoFunctions.SetProcessorApiUrl(currency, url) {
+			log.Fatalf("Can't set history API URL for %s", key)
+		}
+	}
+}
+
 func main() {
 	apiToken, err := getApiToken()
 	if err != nil {
@@ -93,13 +134,25 @@ func main() {
 	dialogManager := &(dialogManager.DialogManager{})
 	dialogManager.RegisterDialogFactory("us", dialogFactories.MakeUserSettingsDialogFactory())
 	dialogManager.RegisterDialogFactory("lc", dialogFactories.MakeLanguageSelectDialogFactory())
+	dialogManager.RegisterDialogFactory("fc", dialogFactories.MakeFiatCurrencySelectDialogFactory())
+	dialogManager.RegisterDialogFactory("ds", dialogFactories.MakeDigestSettingsDialogFactory())
 	dialogManager.RegisterDialogFactory("wl", dialogFactories.MakeWalletsListDialogFactory())
 	dialogManager.RegisterDialogFactory("wa", dialogFactories.MakeWalletDialogFactory())
 	dialogManager.RegisterDialogFactory("ws", dialogFactories.MakeWalletSettingsDialogFactory())
 	dialogManager.RegisterDialogFactory("rc", dialogFactories.MakeReceiveDialogFactory())
 	dialogManager.RegisterDialogFactory("de", dialogFactories.MakeDeleteConfirmationDialogFactory())
 	dialogManager.RegisterDialogFactory("hi", dialogFactories.MakeHistoryDialogFactory())
+	dialogManager.RegisterDialogFactory("tx", dialogFactories.MakeTransactionDialogFactory())
+	dialogManager.RegisterDialogFactory("cp", dialogFactories.MakeChartDialogFactory())
+	dialogManager.RegisterDialogFactory("tl", dialogFactories.MakeTradeLotsDialogFactory())
+	dialogManager.RegisterDialogFactory("pl", dialogFactories.MakeProfitLossDialogFactory())
+	dialogManager.RegisterDialogFactory("tr", dialogFactories.MakeTaxReportDialogFactory())
+	dialogManager.RegisterDialogFactory("ex", dialogFactories.MakeExportDialogFactory())
+	dialogManager.RegisterDialogFactory("im", dialogFactories.MakeImportDialogFactory())
 	dialogManager.RegisterDialogFactory("cc", dialogFactories.MakeChooseCurrencyDialogFactory())
+	dialogManager.RegisterDialogFactory("pa", dialogFactories.MakePriceAlertsDialogFactory())
+	dialogManager.RegisterDialogFactory("ap", dialogFactories.MakePriceAlertCoinDialogFactory())
+	dialogManager.RegisterDialogFactory("ar", dialogFactories.MakePriceAlertRepeatDialogFactory())
 	dialogManager.RegisterTextInputProcessorManager(dialogFactories.GetTextInputProcessorManager())
 
 	staticData := &processing.StaticProccessStructs{
@@ -114,7 +167,19 @@ func main() {
 
 	staticData.Init()
 
+	cryptoFunctions.SetProviderConcurrencyLimits(config.ProviderConcurrencyLimits)
+	cryptoFunctions.SetHostRateLimits(config.HostRateLimits)
+	setBalanceProviders(&config)
+	setHistoryApiUrls(&config)
+
+	rateProvider := cryptoFunctions.MakeRateProvider(config.RateProvider, config.RateProviderApiKey, config.RateProviderUrl)
+	if rateProvider == nil {
+		log.Fatal("Can't create rate provider")
+	}
+
 	serverDataManager := serverData.ServerDataManager{}
+	serverDataManager.SetRateProvider(rateProvider)
+	serverDataManager.SetStaleBalanceAge(time.Duration(config.StaleBalanceAgeSec) * time.Second)
 	serverDataManager.RegisterServerDataInterface(staticData)
 	tickUpdateData := serverDataManager.InitialUpdate(db)
 	tickAfterupdate(staticData, tickUpdateData)
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/gameraccoon/telegram-bot-skeleton/processing"
 	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
@@ -9,8 +11,19 @@ import (
 	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
 	"log"
 	"math/big"
+	"time"
+	"unicode/utf8"
 )
 
+// Telegram doesn't accept longer messages
+const maxMessageLength int = 4096
+
+// notifications that are sent together in one message
+type deferredNotificationsMessage struct {
+	text string
+	ids []int64
+}
+
 func updateBalanceNotifies(staticData *processing.StaticProccessStructs, balanceNotifies []currencies.BalanceNotify) {
 	db := staticFunctions.GetDb(staticData)
 
@@ -26,10 +39,93 @@ func updateBalanceNotifies(staticData *processing.StaticProccessStructs, balance
 			continue
 		}
 
+		if !isBalanceChangeReported(serverData, &balanceNotify) {
+			continue
+		}
+
 		SendBalanceChangeNotification(staticData, db, serverData, &balanceNotify)
 	}
 }
 
+// checks the direction and the minimal change that the user set for the wallet
+func isBalanceChangeReported(serverData serverData.ServerDataInterface, balanceNotify *currencies.BalanceNotify) bool {
+	if balanceNotify.OldBalance == nil || balanceNotify.NewBalance == nil {
+		return false
+	}
+
+	_, currencyDecimals := staticFunctions.GetCurrencySymbolAndDecimals(serverData, balanceNotify.WalletAddress.Currency, balanceNotify.WalletAddress.ContractAddress)
+
+	isIncoming := balanceNotify.NewBalance.Cmp(balanceNotify.OldBalance) > 0
+	balanceDiff := new(big.Int).Sub(balanceNotify.NewBalance, balanceNotify.OldBalance)
+	floatBalanceDiff := cryptoFunctions.GetFloatBalance(balanceDiff.Abs(balanceDiff), currencyDecimals)
+
+	var rate *big.Float
+	if balanceNotify.MinChangeFiat != "" {
+		rate = serverData.GetRate(balanceNotify.WalletAddress.PriceId, balanceNotify.MinChangeFiat)
+	}
+
+	return balanceNotify.IsChangeReported(isIncoming, floatBalanceDiff, rate)
+}
+
+func updatePriceAlerts(staticData *processing.StaticProccessStructs) {
+	db := staticFunctions.GetDb(staticData)
+
+	serverData := serverData.GetServerData(staticData)
+
+	if serverData == nil {
+		log.Print("ServerData is nil")
+		return
+	}
+
+	now := time.Now()
+
+	for _, alert := range db.GetAllPriceAlerts() {
+		rate := serverData.GetRate(alert.PriceId, alert.Fiat)
+
+		if !alert.IsTriggered(rate, now) {
+			continue
+		}
+
+		alert.Rate = rate
+		SendPriceAlertNotification(staticData, db, &alert)
+
+		if alert.IsOneShot() {
+			db.DeletePriceAlert(alert.AlertId)
+		} else {
+			db.SetPriceAlertTriggered(alert.AlertId, now)
+		}
+	}
+}
+
+func SendPriceAlertNotification(staticData *processing.StaticProccessStructs, db *database.AccountDb, alert *currencies.PriceAlert) {
+	if alert.Rate == nil {
+		return
+	}
+
+	userChatId := db.GetUserChatId(alert.UserId)
+
+	var alertTemplate string
+	if alert.Direction == currencies.PriceAlertBelow {
+		alertTemplate = "price_alert_below_notify"
+	} else {
+		alertTemplate = "price_alert_above_notify"
+	}
+
+	translateMap := map[string]interface{}{
+		"Coin":      alert.PriceId,
+		"Rate":      cryptoFunctions.FormatRate(alert.Rate),
+		"Threshold": cryptoFunctions.FormatRate(alert.Threshold),
+		"Fiat":      currencies.GetFiatCurrencyCode(alert.Fiat),
+	}
+
+	translateFn := staticFunctions.FindTransFunction(alert.UserId, staticData)
+
+	staticData.Chat.SendMessage(userChatId,
+		translateFn(alertTemplate, translateMap),
+		0,
+	)
+}
+
 func SendBalanceChangeNotification(staticData *processing.StaticProccessStructs, db *database.AccountDb, serverData serverData.ServerDataInterface, balanceNotify *currencies.BalanceNotify) {
 	if balanceNotify.OldBalance != nil && balanceNotify.NewBalance != nil {
 		userChatId := db.GetUserChatId(balanceNotify.UserId)
@@ -60,9 +156,124 @@ func SendBalanceChangeNotification(staticData *processing.StaticProccessStructs,
 
 		translateFn := staticFunctions.FindTransFunction(balanceNotify.UserId, staticData)
 
-		staticData.Chat.SendMessage(userChatId,
-			translateFn(balanceNotifyTemplate, translateMap),
-			0,
-		)
+		notifyText := translateFn(balanceNotifyTemplate, translateMap)
+
+		// add the cost of the change if we know the rate
+		fiat := db.GetUserFiatCurrency(balanceNotify.UserId)
+		rate := serverData.GetRate(balanceNotify.WalletAddress.PriceId, fiat)
+		floatBalanceDiff := cryptoFunctions.GetFloatBalance(balanceDiff, currencyDecimals)
+
+		if rate != nil && floatBalanceDiff != nil {
+			notifyText = notifyText + "\n" + translateFn("balance_notify_fiat_template", map[string]interface{}{
+				"Diff": new(big.Float).Mul(floatBalanceDiff, rate).Text('f', 2),
+				"Fiat": currencies.GetFiatCurrencyCode(fiat),
+			})
+		}
+
+		sendOrDeferNotification(staticData, db, balanceNotify.UserId, userChatId, notifyText, time.Now())
+	}
+}
+
+func getUserLocation(db *database.AccountDb, userId int64) *time.Location {
+	location, err := time.LoadLocation(db.GetUserTimezone(userId))
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// during the quiet hours of the user the notification is queued to be sent later
+func sendOrDeferNotification(staticData *processing.StaticProccessStructs, db *database.AccountDb, userId int64, userChatId int64, text string, now time.Time) {
+	quietHours := db.GetUserQuietHours(userId)
+
+	if quietHours.IsQuietTime(now, getUserLocation(db, userId)) {
+		db.AddDeferredNotification(userId, text, now)
+		return
+	}
+
+	staticData.Chat.SendMessage(userChatId,
+		text,
+		0,
+	)
+}
+
+// sends notifications queued during quiet hours as one message per user when the quiet hours end
+func sendDeferredNotifications(staticData *processing.StaticProccessStructs) {
+	db := staticFunctions.GetDb(staticData)
+
+	now := time.Now()
+
+	for _, userId := range db.GetUsersWithDeferredNotifications() {
+		quietHours := db.GetUserQuietHours(userId)
+
+		if quietHours.IsQuietTime(now, getUserLocation(db, userId)) {
+			continue
+		}
+
+		ids, texts, times := db.GetDeferredNotifications(userId)
+		if len(ids) == 0 {
+			continue
+		}
+
+		timezone := db.GetUserTimezone(userId)
+
+		entries := make([]string, len(texts))
+		for i, text := range texts {
+			entries[i] = fmt.Sprintf("\n\n<i>%s</i>\n%s", staticFunctions.FormatTimestamp(times[i], timezone), text)
+		}
+
+		translateFn := staticFunctions.FindTransFunction(userId, staticData)
+		messages := makeDeferredNotificationsMessages(translateFn("deferred_notifications_title"), ids, entries, maxMessageLength)
+
+		chatId := db.GetUserChatId(userId)
+		for _, message := range messages {
+			// the rest is kept to be sent with the next tick in the same order
+			if staticData.Chat.SendMessage(chatId, message.text, 0) == 0 {
+				log.Printf("Can't send deferred notifications to user %d", userId)
+				break
+			}
+
+			db.DeleteDeferredNotifications(message.ids)
+		}
 	}
 }
+
+// packs the notifications into as few messages as possible, every message starts with the title
+// a notification that doesn't fit in a message on its own is cut
+func makeDeferredNotificationsMessages(title string, ids []int64, entries []string, maxLength int) (messages []deferredNotificationsMessage) {
+	var textBuffer bytes.Buffer
+	var messageIds []int64
+	messageLength := 0
+
+	titleLength := utf8.RuneCountInString(title)
+
+	for i, entry := range entries {
+		entryLength := utf8.RuneCountInString(entry)
+
+		if len(messageIds) > 0 && messageLength + entryLength > maxLength {
+			messages = append(messages, deferredNotificationsMessage{text: textBuffer.String(), ids: messageIds})
+			messageIds = nil
+		}
+
+		if len(messageIds) == 0 {
+			textBuffer.Reset()
+			textBuffer.WriteString(title)
+			messageLength = titleLength
+		}
+
+		if messageLength + entryLength > maxLength {
+			entry = string([]rune(entry)[:maxLength - messageLength])
+			entryLength = maxLength - messageLength
+		}
+
+		textBuffer.WriteString(entry)
+		messageLength += entryLength
+		messageIds = append(messageIds, ids[i])
+	}
+
+	if len(messageIds) > 0 {
+		messages = append(messages, deferredNotificationsMessage{text: textBuffer.String(), ids: messageIds})
+	}
+
+	return
+}
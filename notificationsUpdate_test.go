@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDeferredNotificationsMessages(t *testing.T) {
+	assert := require.New(t)
+
+	entries := []string{
+		"\n" + strings.Repeat("a", 10),
+		"\n" + strings.Repeat("б", 10),
+		"\n" + strings.Repeat("c", 10),
+	}
+
+	// everything fits in one message
+	messages := makeDeferredNotificationsMessages("title", []int64{1, 2, 3}, entries, 100)
+	assert.Equal(1, len(messages))
+	assert.Equal("title" + strings.Join(entries, ""), messages[0].text)
+	assert.Equal([]int64{1, 2, 3}, messages[0].ids)
+
+	// the length is counted in characters, not bytes
+	messages = makeDeferredNotificationsMessages("title", []int64{1, 2, 3}, entries, 27)
+	assert.Equal(2, len(messages))
+	assert.Equal("title" + entries[0] + entries[1], messages[0].text)
+	assert.Equal([]int64{1, 2}, messages[0].ids)
+	assert.Equal("title" + entries[2], messages[1].text)
+	assert.Equal([]int64{3}, messages[1].ids)
+
+	// too long notifications are cut
+	messages = makeDeferredNotificationsMessages("title", []int64{1, 2}, []string{strings.Repeat("д", 50), "\nshort"}, 20)
+	assert.Equal(2, len(messages))
+	assert.Equal(20, utf8.RuneCountInString(messages[0].text))
+	assert.Equal([]int64{1}, messages[0].ids)
+	assert.Equal("title\nshort", messages[1].text)
+
+	assert.Nil(makeDeferredNotificationsMessages("title", nil, nil, 100))
+}
@@ -3,10 +3,14 @@ package main
 import (
 	"github.com/gameraccoon/telegram-bot-skeleton/dialogManager"
 	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/gameraccoon/telegram-accountant-bot/dialogFactories"
 	"github.com/gameraccoon/telegram-accountant-bot/staticFunctions"
 	"strings"
 )
 
+// bigger files are not downloaded
+const maxUploadedFileSize int64 = 1024 * 1024
+
 type ProcessorFunc func(*processing.ProcessData)
 
 type ProcessorFuncMap map[string]ProcessorFunc
@@ -29,6 +33,40 @@ func settingsCommand(data *processing.ProcessData) {
 	data.SendDialog(data.Static.MakeDialogFn("us", data.UserId, data.Trans, data.Static))
 }
 
+func alertsCommand(data *processing.ProcessData) {
+	data.SendDialog(data.Static.MakeDialogFn("pa", data.UserId, data.Trans, data.Static))
+}
+
+func profitLossCommand(data *processing.ProcessData) {
+	data.SendDialog(data.Static.MakeDialogFn("pl", data.UserId, data.Trans, data.Static))
+}
+
+func taxReportCommand(data *processing.ProcessData) {
+	data.SendDialog(data.Static.MakeDialogFn("tr", data.UserId, data.Trans, data.Static))
+}
+
+func exportCommand(data *processing.ProcessData) {
+	data.SendDialog(data.Static.MakeDialogFn("ex", data.UserId, data.Trans, data.Static))
+}
+
+func importCommand(data *processing.ProcessData) {
+	if len(data.Message) > 0 {
+		dialogFactories.StartWalletsImport([]byte(data.Message), data)
+	} else {
+		data.SendMessage(data.Trans("import_info"))
+	}
+}
+
+func uploadCommand(data *processing.ProcessData) {
+	fileData, ok := staticFunctions.DownloadFile(data.Static, data.Message, maxUploadedFileSize)
+	if !ok {
+		data.SendMessage(data.Trans("import_wrong_file") + "\n" + data.Trans("import_info"))
+		return
+	}
+
+	dialogFactories.StartWalletsImport(fileData, data)
+}
+
 func helpCommand(data *processing.ProcessData) {
 	data.SendMessage(data.Trans("help_info"))
 }
@@ -44,6 +82,12 @@ func makeUserCommandProcessors() ProcessorFuncMap {
 		"wallets":    walletsCommand,
 		"add_wallet": createWalletCommand,
 		"settings":   settingsCommand,
+		"alerts":     alertsCommand,
+		"pnl":        profitLossCommand,
+		"report":     taxReportCommand,
+		"export":     exportCommand,
+		"import":     importCommand,
+		"upload":     uploadCommand,
 		"help":       helpCommand,
 		"cancel":     cancelCommand,
 	}
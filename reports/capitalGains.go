@@ -0,0 +1,93 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const reportDateFormat string = "2006-01-02"
+
+// a disposal of coins of one wallet
+type CapitalGainsRow struct {
+	currencies.Disposal
+	WalletName string
+	Symbol string
+	// digits of the coin amount to keep
+	Decimals int
+	Fiat string
+}
+
+func formatAmount(value *big.Float, digits int) string {
+	text := value.Text('f', digits)
+
+	if strings.ContainsAny(text, ".") {
+		return strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+	return text
+}
+
+// the acquisition date is "various" if the cost was averaged over many purchases
+// and "unknown" if the coins were sold over the recorded purchases
+func formatAcquisitionDate(disposal *currencies.Disposal, location *time.Location) string {
+	if disposal.IsUncovered() {
+		return "unknown"
+	}
+	if disposal.AcquisitionTime.IsZero() {
+		return "various"
+	}
+	return disposal.AcquisitionTime.In(location).Format(reportDateFormat)
+}
+
+// empty for unknown values
+func formatFiatAmount(value *big.Float) string {
+	if value == nil {
+		return ""
+	}
+	return value.Text('f', 2)
+}
+
+// the dates are written in the given location
+func MakeCapitalGainsCsv(rows []CapitalGainsRow, location *time.Location) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	err := writer.Write([]string{"Wallet", "Coin", "Amount", "Acquired", "Disposed", "Proceeds", "Cost basis", "Gain", "Currency"})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		err = writer.Write([]string{
+			row.WalletName,
+			row.Symbol,
+			formatAmount(row.Amount, row.Decimals),
+			formatAcquisitionDate(&row.Disposal, location),
+			row.DisposalTime.In(location).Format(reportDateFormat),
+			formatFiatAmount(row.Proceeds),
+			formatFiatAmount(row.CostBasis),
+			formatFiatAmount(row.GetGain()),
+			strings.ToUpper(row.Fiat),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// the bounds of the calendar year in the given location
+func GetYearRange(year int, location *time.Location) (startTime time.Time, endTime time.Time) {
+	startTime = time.Date(year, time.January, 1, 0, 0, 0, 0, location)
+	endTime = startTime.AddDate(1, 0, 0)
+	return
+}
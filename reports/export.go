@@ -0,0 +1,118 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"time"
+)
+
+const exportTimeFormat string = "2006-01-02 15:04:05"
+
+// the amounts are formatted strings to keep the precision
+type ExportTransaction struct {
+	Hash string `json:"hash"`
+	Time time.Time `json:"time"`
+	IsSent bool `json:"is_sent"`
+	From string `json:"from"`
+	To string `json:"to"`
+	Amount string `json:"amount"`
+	// nil if unknown
+	Fee *string `json:"fee"`
+	Status string `json:"status"`
+}
+
+type ExportWallet struct {
+	Name string `json:"name"`
+	Symbol string `json:"symbol"`
+	Address string `json:"address"`
+	ContractAddress string `json:"contract_address,omitempty"`
+	PriceId string `json:"price_id,omitempty"`
+	// nil if unknown
+	Balance *string `json:"balance"`
+	// nil if unknown
+	Value *string `json:"value"`
+	Transactions []ExportTransaction `json:"transactions"`
+}
+
+type Export struct {
+	Time time.Time `json:"time"`
+	Fiat string `json:"fiat"`
+	Wallets []ExportWallet `json:"wallets"`
+}
+
+func MakeExportJson(export *Export) ([]byte, error) {
+	return json.MarshalIndent(export, "", "\t")
+}
+
+func getOptionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func writeCsv(records [][]string) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	err := writer.WriteAll(records)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// unknown balances and values are left empty
+func MakeWalletsCsv(export *Export) ([]byte, error) {
+	records := [][]string{
+		[]string{"Wallet", "Coin", "Address", "Contract address", "Price id", "Balance", "Value", "Currency"},
+	}
+
+	for _, wallet := range export.Wallets {
+		records = append(records, []string{
+			wallet.Name,
+			wallet.Symbol,
+			wallet.Address,
+			wallet.ContractAddress,
+			wallet.PriceId,
+			getOptionalString(wallet.Balance),
+			getOptionalString(wallet.Value),
+			export.Fiat,
+		})
+	}
+
+	return writeCsv(records)
+}
+
+// the times are written in the given location
+func MakeTransactionsCsv(export *Export, location *time.Location) ([]byte, error) {
+	records := [][]string{
+		[]string{"Wallet", "Coin", "Time", "Direction", "Amount", "Fee", "From", "To", "Hash", "Status"},
+	}
+
+	for _, wallet := range export.Wallets {
+		for _, transaction := range wallet.Transactions {
+			direction := "in"
+			if transaction.IsSent {
+				direction = "out"
+			}
+
+			records = append(records, []string{
+				wallet.Name,
+				wallet.Symbol,
+				transaction.Time.In(location).Format(exportTimeFormat),
+				direction,
+				transaction.Amount,
+				getOptionalString(transaction.Fee),
+				transaction.From,
+				transaction.To,
+				transaction.Hash,
+				transaction.Status,
+			})
+		}
+	}
+
+	return writeCsv(records)
+}
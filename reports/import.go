@@ -0,0 +1,119 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// a wallet described in an uploaded file, the values are not validated
+type ImportRow struct {
+	// number of the row in a CSV file or of the wallet in a JSON file, starting from 1
+	Line int
+	Name string
+	Symbol string
+	Address string
+	ContractAddress string
+	PriceId string
+}
+
+type importJsonWallet struct {
+	Name string `json:"name"`
+	Symbol string `json:"symbol"`
+	Currency string `json:"currency"`
+	Address string `json:"address"`
+	ContractAddress string `json:"contract_address"`
+	PriceId string `json:"price_id"`
+}
+
+// accepts CSV with columns: name, currency symbol, address, contract address, price id (the last two are optional)
+// or JSON with a list of wallets (the same format as the JSON export)
+func ParseWalletsImport(data []byte) ([]ImportRow, error) {
+	trimmedData := bytes.TrimSpace(data)
+	if len(trimmedData) > 0 && (trimmedData[0] == '[' || trimmedData[0] == '{') {
+		return parseWalletsJson(trimmedData)
+	}
+	return parseWalletsCsv(trimmedData)
+}
+
+func parseWalletsJson(data []byte) (rows []ImportRow, err error) {
+	var wallets []importJsonWallet
+
+	if data[0] == '[' {
+		err = json.Unmarshal(data, &wallets)
+	} else {
+		export := struct {
+			Wallets []importJsonWallet `json:"wallets"`
+		}{}
+		err = json.Unmarshal(data, &export)
+		wallets = export.Wallets
+	}
+
+	if err != nil {
+		return
+	}
+
+	for i, wallet := range wallets {
+		symbol := wallet.Symbol
+		if symbol == "" {
+			symbol = wallet.Currency
+		}
+
+		rows = append(rows, ImportRow{
+			Line: i + 1,
+			Name: strings.TrimSpace(wallet.Name),
+			Symbol: strings.TrimSpace(symbol),
+			Address: strings.TrimSpace(wallet.Address),
+			ContractAddress: strings.TrimSpace(wallet.ContractAddress),
+			PriceId: strings.TrimSpace(wallet.PriceId),
+		})
+	}
+	return
+}
+
+func isCsvHeader(record []string) bool {
+	firstColumn := strings.ToLower(strings.TrimSpace(record[0]))
+	return firstColumn == "name" || firstColumn == "wallet"
+}
+
+func getCsvColumn(record []string, index int) string {
+	if index < len(record) {
+		return strings.TrimSpace(record[index])
+	}
+	return ""
+}
+
+func parseWalletsCsv(data []byte) (rows []ImportRow, err error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	// empty lines are skipped by the reader, so this is the number of the record including the header
+	line := 0
+	for {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		line++
+		if line == 1 && isCsvHeader(record) {
+			continue
+		}
+
+		rows = append(rows, ImportRow{
+			Line: line,
+			Name: getCsvColumn(record, 0),
+			Symbol: getCsvColumn(record, 1),
+			Address: getCsvColumn(record, 2),
+			ContractAddress: getCsvColumn(record, 3),
+			PriceId: getCsvColumn(record, 4),
+		})
+	}
+	return
+}
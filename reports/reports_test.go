@@ -0,0 +1,198 @@
+package reports
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCapitalGainsCsv(t *testing.T) {
+	assert := require.New(t)
+
+	location := time.FixedZone("UTC+3", 3 * 60 * 60)
+
+	rows := []CapitalGainsRow{
+		CapitalGainsRow{
+			Disposal: currencies.Disposal{
+				AcquisitionTime: time.Date(2017, time.March, 10, 12, 0, 0, 0, time.UTC),
+				// already the next day in the location
+				DisposalTime: time.Date(2018, time.May, 1, 22, 30, 0, 0, time.UTC),
+				Amount: big.NewFloat(0.5),
+				Proceeds: big.NewFloat(4000),
+				CostBasis: big.NewFloat(600.5),
+			},
+			WalletName: "My, \"main\" wallet",
+			Symbol: "BTC",
+			Decimals: 8,
+			Fiat: "usd",
+		},
+		CapitalGainsRow{
+			Disposal: currencies.Disposal{
+				DisposalTime: time.Date(2018, time.June, 1, 12, 0, 0, 0, time.UTC),
+				Amount: big.NewFloat(2),
+				Proceeds: big.NewFloat(800),
+				CostBasis: big.NewFloat(1000),
+			},
+			WalletName: "eth",
+			Symbol: "ETH",
+			Decimals: 18,
+			Fiat: "usd",
+		},
+		// sold over the recorded purchases
+		CapitalGainsRow{
+			Disposal: currencies.Disposal{
+				DisposalTime: time.Date(2018, time.July, 1, 12, 0, 0, 0, time.UTC),
+				Amount: big.NewFloat(1),
+				Proceeds: big.NewFloat(450),
+			},
+			WalletName: "eth",
+			Symbol: "ETH",
+			Decimals: 18,
+			Fiat: "usd",
+		},
+	}
+
+	csvData, err := MakeCapitalGainsCsv(rows, location)
+	assert.Nil(err)
+	assert.Equal("Wallet,Coin,Amount,Acquired,Disposed,Proceeds,Cost basis,Gain,Currency\n" +
+		"\"My, \"\"main\"\" wallet\",BTC,0.5,2017-03-10,2018-05-02,4000.00,600.50,3399.50,USD\n" +
+		"eth,ETH,2,various,2018-06-01,800.00,1000.00,-200.00,USD\n" +
+		"eth,ETH,1,unknown,2018-07-01,450.00,,,USD\n", string(csvData))
+}
+
+func TestYearRange(t *testing.T) {
+	assert := require.New(t)
+
+	location := time.FixedZone("UTC+3", 3 * 60 * 60)
+
+	startTime, endTime := GetYearRange(2018, location)
+	assert.Equal(time.Date(2017, time.December, 31, 21, 0, 0, 0, time.UTC), startTime.UTC())
+	assert.Equal(time.Date(2018, time.December, 31, 21, 0, 0, 0, time.UTC), endTime.UTC())
+}
+
+func makeTestExport() *Export {
+	balance := "1.5"
+	value := "9750.00"
+	fee := "0.0001"
+
+	return &Export{
+		Time: time.Date(2018, time.September, 14, 12, 0, 0, 0, time.UTC),
+		Fiat: "USD",
+		Wallets: []ExportWallet{
+			ExportWallet{
+				Name: "main",
+				Symbol: "BTC",
+				Address: "addr1",
+				PriceId: "bitcoin",
+				Balance: &balance,
+				Value: &value,
+				Transactions: []ExportTransaction{
+					ExportTransaction{
+						Hash: "hash1",
+						Time: time.Date(2018, time.September, 13, 22, 0, 0, 0, time.UTC),
+						IsSent: true,
+						From: "addr1",
+						To: "addr2",
+						Amount: "0.5",
+						Fee: &fee,
+						Status: "confirmed",
+					},
+				},
+			},
+			ExportWallet{
+				Name: "token",
+				Symbol: "TKN",
+				Address: "addr3",
+				ContractAddress: "contract",
+				Transactions: []ExportTransaction{},
+			},
+		},
+	}
+}
+
+func TestExportCsv(t *testing.T) {
+	assert := require.New(t)
+
+	export := makeTestExport()
+
+	walletsCsv, err := MakeWalletsCsv(export)
+	assert.Nil(err)
+	assert.Equal("Wallet,Coin,Address,Contract address,Price id,Balance,Value,Currency\n" +
+		"main,BTC,addr1,,bitcoin,1.5,9750.00,USD\n" +
+		"token,TKN,addr3,contract,,,,USD\n", string(walletsCsv))
+
+	transactionsCsv, err := MakeTransactionsCsv(export, time.FixedZone("UTC+3", 3 * 60 * 60))
+	assert.Nil(err)
+	assert.Equal("Wallet,Coin,Time,Direction,Amount,Fee,From,To,Hash,Status\n" +
+		"main,BTC,2018-09-14 01:00:00,out,0.5,0.0001,addr1,addr2,hash1,confirmed\n", string(transactionsCsv))
+}
+
+func TestExportJson(t *testing.T) {
+	assert := require.New(t)
+
+	jsonData, err := MakeExportJson(makeTestExport())
+	assert.Nil(err)
+
+	var parsed map[string]interface{}
+	assert.Nil(json.Unmarshal(jsonData, &parsed))
+	assert.Equal("USD", parsed["fiat"])
+
+	wallets := parsed["wallets"].([]interface{})
+	assert.Equal(2, len(wallets))
+	assert.Equal("1.5", wallets[0].(map[string]interface{})["balance"])
+	// unknown values are null, not zero
+	assert.Nil(wallets[1].(map[string]interface{})["balance"])
+	assert.Nil(wallets[1].(map[string]interface{})["value"])
+
+	transaction := wallets[0].(map[string]interface{})["transactions"].([]interface{})[0].(map[string]interface{})
+	assert.Equal("2018-09-13T22:00:00Z", transaction["time"])
+	assert.Equal(true, transaction["is_sent"])
+}
+
+func TestParseWalletsCsv(t *testing.T) {
+	assert := require.New(t)
+
+	rows, err := ParseWalletsImport([]byte("Name,Coin,Address,Contract address,Price id\n" +
+		"main, BTC ,addr1\n" +
+		"\"my, token\",TKN,addr2,contract,token-id\n" +
+		"broken\n"))
+	assert.Nil(err)
+	assert.Equal(3, len(rows))
+	assert.Equal(ImportRow{Line: 2, Name: "main", Symbol: "BTC", Address: "addr1"}, rows[0])
+	assert.Equal(ImportRow{Line: 3, Name: "my, token", Symbol: "TKN", Address: "addr2", ContractAddress: "contract", PriceId: "token-id"}, rows[1])
+	assert.Equal(ImportRow{Line: 4, Name: "broken"}, rows[2])
+
+	// the header is optional
+	rows, err = ParseWalletsImport([]byte("main,LTC,addr1"))
+	assert.Nil(err)
+	assert.Equal(1, len(rows))
+	assert.Equal(1, rows[0].Line)
+	assert.Equal("LTC", rows[0].Symbol)
+
+	_, err = ParseWalletsImport([]byte("main,\"BTC,addr1"))
+	assert.NotNil(err)
+}
+
+func TestParseWalletsJson(t *testing.T) {
+	assert := require.New(t)
+
+	rows, err := ParseWalletsImport([]byte(`[{"name": "main", "currency": "BTC", "address": "addr1"}, {"name": "eth", "symbol": "ETH", "address": "addr2", "price_id": "ethereum"}]`))
+	assert.Nil(err)
+	assert.Equal(2, len(rows))
+	assert.Equal(ImportRow{Line: 1, Name: "main", Symbol: "BTC", Address: "addr1"}, rows[0])
+	assert.Equal(ImportRow{Line: 2, Name: "eth", Symbol: "ETH", Address: "addr2", PriceId: "ethereum"}, rows[1])
+
+	// the exported file can be imported back
+	jsonData, err := MakeExportJson(makeTestExport())
+	assert.Nil(err)
+	rows, err = ParseWalletsImport(jsonData)
+	assert.Nil(err)
+	assert.Equal(2, len(rows))
+	assert.Equal(ImportRow{Line: 2, Name: "token", Symbol: "TKN", Address: "addr3", ContractAddress: "contract"}, rows[1])
+
+	_, err = ParseWalletsImport([]byte(`[{"name": 1}]`))
+	assert.NotNil(err)
+}
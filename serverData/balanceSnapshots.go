@@ -0,0 +1,53 @@
+package serverData
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/database"
+	"time"
+)
+
+// how often we record balances of the wallets
+const balanceSnapshotsInterval time.Duration = time.Hour
+// older snapshots are thinned out to one per day
+const detailedSnapshotsPeriod time.Duration = 7 * 24 * time.Hour
+const downsampledSnapshotsInterval time.Duration = 24 * time.Hour
+// older snapshots are removed
+const balanceSnapshotsRetention time.Duration = 400 * 24 * time.Hour
+
+func (serverDataManager *ServerDataManager) updateBalanceSnapshots(db *database.AccountDb, walletAddresses []database.WalletAddressDbWrapper, now time.Time) {
+	// the last time is taken from the DB to keep the interval between restarts
+	if now.Sub(db.GetLastBalanceSnapshotTime()) < balanceSnapshotsInterval {
+		return
+	}
+
+	fiats := db.GetWalletsFiatCurrencies()
+
+	snapshots := make([]currencies.BalanceSnapshot, 0, len(walletAddresses))
+	for _, walletAddress := range walletAddresses {
+		// unknown balances are skipped, a snapshot shouldn't cause requests or HD wallet scans
+		balance := serverDataManager.GetCachedBalance(walletAddress.Data)
+		if balance == nil {
+			continue
+		}
+
+		// an old balance is not the balance at this time, so the chart gets a gap instead of a flat line
+		if serverDataManager.getBalanceStatus(walletAddress.Data, now).IsStale {
+			continue
+		}
+
+		fiat := fiats[walletAddress.WalletId]
+
+		snapshots = append(snapshots, currencies.BalanceSnapshot{
+			WalletId: walletAddress.WalletId,
+			Time: now,
+			Balance: balance,
+			Rate: serverDataManager.GetRate(walletAddress.Data.PriceId, fiat),
+			Fiat: fiat,
+		})
+	}
+
+	db.AddBalanceSnapshots(snapshots)
+
+	db.DownsampleBalanceSnapshots(now.Add(-detailedSnapshotsPeriod), downsampledSnapshotsInterval)
+	db.DeleteBalanceSnapshotsBefore(now.Add(-balanceSnapshotsRetention))
+}
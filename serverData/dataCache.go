@@ -1,33 +1,44 @@
 package serverData
 
 import (
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"math/big"
 	"sync"
+	"time"
 )
 
-type ratesStruct struct {
-	toUsd map[string]*big.Float
+type balanceStatus struct {
+	updateTime time.Time
+	provider string
+	lastError error
 }
 
 type dataCache struct {
-	rates ratesStruct
+	// fiat -> price id -> rate
+	rates map[string]map[string]*big.Float
 	ratesMutex sync.Mutex
 	balances map[currencies.AddressData]*big.Int
+	// guarded by balancesMutex as well
+	balanceStatuses map[currencies.AddressData]balanceStatus
 	balancesMutex sync.Mutex
 	erc20Tokens map[string]currencies.Erc20TokenData
 	erc20TokensMutex sync.Mutex
 }
 
 func (cache *dataCache) Init() {
-	if cache.rates.toUsd == nil {
-		cache.rates.toUsd = make(map[string]*big.Float)
+	if cache.rates == nil {
+		cache.rates = make(map[string]map[string]*big.Float)
 	}
 
 	if cache.balances == nil {
 		cache.balances = make(map[currencies.AddressData]*big.Int)
 	}
 
+	if cache.balanceStatuses == nil {
+		cache.balanceStatuses = make(map[currencies.AddressData]balanceStatus)
+	}
+
 	if cache.erc20Tokens == nil {
 		cache.erc20Tokens = make(map[string]currencies.Erc20TokenData)
 	}
@@ -45,14 +56,33 @@ func (cache *dataCache) getBalance(address currencies.AddressData) *big.Int {
 	}
 }
 
-func (cache *dataCache) getRateToUsd(priceId string) *big.Float {
+func (cache *dataCache) getBalanceStatus(address currencies.AddressData) balanceStatus {
+	cache.balancesMutex.Lock()
+	defer cache.balancesMutex.Unlock()
+
+	return cache.balanceStatuses[address]
+}
+
+// remembers the result of a balance request, balancesMutex should be locked
+// the update time is kept from the last successful request
+func (cache *dataCache) updateBalanceStatus(address currencies.AddressData, result cryptoFunctions.BalanceResult, now time.Time) {
+	status := cache.balanceStatuses[address]
+	status.provider = result.Provider
+	status.lastError = result.Error
+	if result.Balance != nil {
+		status.updateTime = now
+	}
+	cache.balanceStatuses[address] = status
+}
+
+func (cache *dataCache) getRate(priceId string, fiat string) *big.Float {
 	cache.ratesMutex.Lock()
 	defer cache.ratesMutex.Unlock()
 
-	rateToUsd, ok := cache.rates.toUsd[priceId]
+	rate, ok := cache.rates[fiat][priceId]
 
 	if ok {
-		return rateToUsd
+		return rate
 	} else {
 		return nil
 	}
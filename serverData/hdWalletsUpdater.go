@@ -0,0 +1,234 @@
+package serverData
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/database"
+	"github.com/gameraccoon/telegram-accountant-bot/wallettypes"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+)
+
+// how many unused addresses in a row we check before we stop scanning a chain (BIP-44)
+const hdWalletGapLimit int = 20
+
+// external (receiving) and internal (change) chains
+var hdWalletChains []uint32 = []uint32{0, 1}
+
+// balances received during one update, the addresses are not requested twice
+type fetchedBalances map[currencies.AddressData]cryptoFunctions.BalanceResult
+
+func getHdAddressData(walletAddress currencies.AddressData, address string) currencies.AddressData {
+	return currencies.AddressData{
+		Currency: walletAddress.Currency,
+		Address: address,
+	}
+}
+
+// requests the balances of the addresses that are not fetched yet and adds them to fetched
+func fetchHdAddressBalances(processor *cryptoFunctions.CurrencyProcessor, walletAddress currencies.AddressData, addresses []database.HdWalletAddressDbWrapper, fetched fetchedBalances) error {
+	requestData := []currencies.AddressData{}
+	for _, address := range addresses {
+		addressData := getHdAddressData(walletAddress, address.Address)
+		if _, ok := fetched[addressData]; !ok {
+			requestData = append(requestData, addressData)
+		}
+	}
+
+	if len(requestData) == 0 {
+		return nil
+	}
+
+	results := (*processor).GetBalanceResults(requestData)
+	if len(results) != len(requestData) {
+		return fmt.Errorf("return count doesn't match input count: %d != %d", len(requestData), len(results))
+	}
+
+	for i, result := range results {
+		fetched[requestData[i]] = result
+	}
+	return nil
+}
+
+// derives addresses of the wallet until each chain ends with hdWalletGapLimit unused addresses
+// an address is used if it has any transactions, even if its balance is zero now
+// returns the full set of known addresses and the sum of their balances (unknown if any balance is unknown)
+// fetched can be nil, otherwise its balances are reused and the new ones are added to it
+func scanHdWallet(walletAddress currencies.AddressData, knownAddresses []database.HdWalletAddressDbWrapper, fetched fetchedBalances) (addresses []database.HdWalletAddressDbWrapper, result cryptoFunctions.BalanceResult) {
+	processor := cryptoFunctions.GetProcessor(walletAddress.Currency)
+	if processor == nil {
+		log.Print("No processor found")
+		result.Error = fmt.Errorf("no processor for %s", currencies.GetCurrencySymbol(walletAddress.Currency))
+		return knownAddresses, result
+	}
+
+	if fetched == nil {
+		fetched = make(fetchedBalances)
+	}
+
+	addresses = knownAddresses
+
+	for _, chain := range hdWalletChains {
+		// index of the first address that is not derived yet and the index after the last used one
+		derivedCount := 0
+		usedCount := 0
+		for _, address := range knownAddresses {
+			if address.Chain == chain {
+				if int(address.Index) + 1 > derivedCount {
+					derivedCount = int(address.Index) + 1
+				}
+				if address.IsUsed && int(address.Index) + 1 > usedCount {
+					usedCount = int(address.Index) + 1
+				}
+			}
+		}
+
+		for derivedCount < usedCount + hdWalletGapLimit {
+			newAddresses := cryptoFunctions.DeriveHdWalletAddresses(walletAddress.Currency, walletAddress.Address, chain, uint32(derivedCount), usedCount + hdWalletGapLimit - derivedCount)
+			if newAddresses == nil {
+				result.Error = fmt.Errorf("can't derive addresses of the HD wallet")
+				return addresses, result
+			}
+
+			for i, address := range newAddresses {
+				addresses = append(addresses, database.HdWalletAddressDbWrapper{
+					Chain: chain,
+					Index: uint32(derivedCount + i),
+					Address: address,
+				})
+			}
+			derivedCount += len(newAddresses)
+
+			// new addresses are checked here, the rest are checked below with the known ones
+			usedCount = markUsedHdAddresses(processor, walletAddress, addresses, chain, usedCount, fetched)
+		}
+	}
+
+	// the addresses that were not checked during the scan are requested in one bunch
+	if err := fetchHdAddressBalances(processor, walletAddress, addresses, fetched); err != nil {
+		result.Error = err
+		log.Print(result.Error)
+		return addresses, result
+	}
+
+	balance := big.NewInt(0)
+	for i, address := range addresses {
+		addressResult := fetched[getHdAddressData(walletAddress, address.Address)]
+		if addressResult.IsUsed {
+			addresses[i].IsUsed = true
+		}
+		if addressResult.Balance == nil {
+			// the first unknown balance is the reason
+			if balance != nil {
+				result.Provider = addressResult.Provider
+				result.Error = addressResult.Error
+			}
+			balance = nil
+			continue
+		}
+		if balance != nil {
+			balance.Add(balance, addressResult.Balance)
+			result.Provider = addressResult.Provider
+		}
+	}
+
+	result.Balance = balance
+	return
+}
+
+// checks the addresses of the chain starting from usedCount and returns the new usedCount
+func markUsedHdAddresses(processor *cryptoFunctions.CurrencyProcessor, walletAddress currencies.AddressData, addresses []database.HdWalletAddressDbWrapper, chain uint32, usedCount int, fetched fetchedBalances) int {
+	indexes := []int{}
+	checkedAddresses := []database.HdWalletAddressDbWrapper{}
+	for i, address := range addresses {
+		if address.Chain == chain && int(address.Index) >= usedCount && !address.IsUsed {
+			indexes = append(indexes, i)
+			checkedAddresses = append(checkedAddresses, address)
+		}
+	}
+
+	if len(checkedAddresses) == 0 {
+		return usedCount
+	}
+
+	if err := fetchHdAddressBalances(processor, walletAddress, checkedAddresses, fetched); err != nil {
+		log.Print(err)
+		return usedCount
+	}
+
+	for _, index := range indexes {
+		address := &addresses[index]
+		if fetched[getHdAddressData(walletAddress, address.Address)].IsUsed {
+			address.IsUsed = true
+			if int(address.Index) + 1 > usedCount {
+				usedCount = int(address.Index) + 1
+			}
+		}
+	}
+
+	return usedCount
+}
+
+// returns addresses that were added or changed during the scan
+func getChangedHdAddresses(oldAddresses []database.HdWalletAddressDbWrapper, newAddresses []database.HdWalletAddressDbWrapper) (changedAddresses []database.HdWalletAddressDbWrapper) {
+	type addressKey struct {
+		chain uint32
+		index uint32
+	}
+
+	oldAddressesMap := make(map[addressKey]database.HdWalletAddressDbWrapper)
+	for _, address := range oldAddresses {
+		oldAddressesMap[addressKey{chain: address.Chain, index: address.Index}] = address
+	}
+
+	for _, address := range newAddresses {
+		oldAddress, ok := oldAddressesMap[addressKey{chain: address.Chain, index: address.Index}]
+		if !ok || oldAddress != address {
+			changedAddresses = append(changedAddresses, address)
+		}
+	}
+	return
+}
+
+// fetched are the balances already received during this update, they are not requested again
+func (dataUpdater *serverDataUpdater) updateHdWalletsBalance(db *database.AccountDb, hdWallets []database.WalletAddressDbWrapper, fetched fetchedBalances) (balanceChanges balanceChangesData) {
+	balanceChanges = make(balanceChangesData)
+
+	if fetched == nil {
+		fetched = make(fetchedBalances)
+	}
+
+	for _, wallet := range hdWallets {
+		knownAddresses := db.GetHdWalletAddresses(wallet.WalletId)
+
+		addresses, result := scanHdWallet(wallet.Data, knownAddresses, fetched)
+
+		db.UpdateHdWalletAddresses(wallet.WalletId, getChangedHdAddresses(knownAddresses, addresses))
+
+		dataUpdater.cache.balancesMutex.Lock()
+		dataUpdater.cache.updateBalanceStatus(wallet.Data, result, time.Now())
+		if balance := result.Balance; balance != nil {
+			oldBalance := dataUpdater.cache.balances[wallet.Data]
+			if oldBalance == nil || balance.Cmp(oldBalance) != 0 {
+				balanceChanges[wallet.WalletId] = new(big.Int).Set(balance)
+				dataUpdater.cache.balances[wallet.Data] = balance
+			}
+		}
+		dataUpdater.cache.balancesMutex.Unlock()
+	}
+
+	return
+}
+
+func splitHdWallets(walletAddresses []database.WalletAddressDbWrapper) (simpleWallets []database.WalletAddressDbWrapper, hdWallets []database.WalletAddressDbWrapper) {
+	for _, walletAddress := range walletAddresses {
+		if walletAddress.Type == wallettypes.HdWatchOnly {
+			hdWallets = append(hdWallets, walletAddress)
+		} else {
+			simpleWallets = append(simpleWallets, walletAddress)
+		}
+	}
+	return
+}
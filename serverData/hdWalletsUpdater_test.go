@@ -0,0 +1,46 @@
+package serverData
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"math/big"
+	"testing"
+)
+
+func TestScanHdWalletReusesFetchedBalances(t *testing.T) {
+	assert := require.New(t)
+
+	walletAddress := currencies.AddressData{
+		Currency: currencies.Bitcoin,
+		Address: "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs",
+	}
+
+	// the third receiving and the first change addresses are used, the gaps are counted after them
+	receiving := cryptoFunctions.DeriveHdWalletAddresses(currencies.Bitcoin, walletAddress.Address, 0, 0, 3 + hdWalletGapLimit)
+	change := cryptoFunctions.DeriveHdWalletAddresses(currencies.Bitcoin, walletAddress.Address, 1, 0, 1 + hdWalletGapLimit)
+
+	// all the balances are already known, nothing is requested from the providers
+	fetched := make(fetchedBalances)
+	for _, address := range append(receiving, change...) {
+		fetched[getHdAddressData(walletAddress, address)] = cryptoFunctions.BalanceResult{Balance: big.NewInt(0), Provider: "test"}
+	}
+	// spent to zero, but it had transactions
+	fetched[getHdAddressData(walletAddress, receiving[2])] = cryptoFunctions.BalanceResult{Balance: big.NewInt(0), Provider: "test", IsUsed: true}
+	fetched[getHdAddressData(walletAddress, change[0])] = cryptoFunctions.BalanceResult{Balance: big.NewInt(15), Provider: "test", IsUsed: true}
+
+	addresses, result := scanHdWallet(walletAddress, nil, fetched)
+
+	assert.Nil(result.Error)
+	assert.Equal(int64(15), result.Balance.Int64())
+	assert.Equal(len(receiving) + len(change), len(addresses))
+	assert.Equal(len(receiving) + len(change), len(fetched))
+
+	usedAddresses := []string{}
+	for _, address := range addresses {
+		if address.IsUsed {
+			usedAddresses = append(usedAddresses, address.Address)
+		}
+	}
+	assert.Equal([]string{receiving[2], change[0]}, usedAddresses)
+}
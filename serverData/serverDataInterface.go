@@ -3,10 +3,28 @@ package serverData
 import (
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"math/big"
+	"time"
 )
 
+// what is known about the last updates of a balance
+type BalanceStatus struct {
+	// when the balance was received the last time, zero if it is unknown
+	UpdateTime time.Time
+	// the provider that returned the balance or the last one that failed
+	Provider string
+	// why the last update failed, nil if it succeeded
+	LastError error
+	// the balance was not updated for longer than the configured age
+	IsStale bool
+}
+
 type ServerDataInterface interface {
+	// returns nil if the balance is unknown
 	GetBalance(address currencies.AddressData) *big.Int
-	GetRateToUsd(priceId string) *big.Float
+	// same as GetBalance but never requests the balance, nil if it is not in the cache
+	GetCachedBalance(address currencies.AddressData) *big.Int
+	GetBalanceStatus(address currencies.AddressData) BalanceStatus
+	// returns the price of one coin in the fiat currency, nil if unknown
+	GetRate(priceId string, fiat string) *big.Float
 	GetErc20TokenData(contractAddress string) *currencies.Erc20TokenData
 }
@@ -4,16 +4,22 @@ import (
 	"github.com/gameraccoon/telegram-bot-skeleton/processing"
 	"github.com/gameraccoon/telegram-accountant-bot/database"
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
 	"log"
 	"math/big"
+	"time"
 )
 
 type TickUpdateData struct {
 	BalanceNotifies []currencies.BalanceNotify
 }
 
+// balances that were not updated for longer are marked as stale if not configured
+const defaultStaleBalanceAge time.Duration = time.Hour
+
 type ServerDataManager struct {
 	dataUpdater serverDataUpdater
+	staleBalanceAge time.Duration
 }
 
 func GetServerData(staticData *processing.StaticProccessStructs) ServerDataInterface {
@@ -31,6 +37,15 @@ func GetServerData(staticData *processing.StaticProccessStructs) ServerDataInter
 	}
 }
 
+func (serverDataManager *ServerDataManager) SetRateProvider(rateProvider cryptoFunctions.RateProvider) {
+	serverDataManager.dataUpdater.rateProvider = rateProvider
+}
+
+// balances that were not updated for longer than the age are marked as stale, zero sets the default age
+func (serverDataManager *ServerDataManager) SetStaleBalanceAge(age time.Duration) {
+	serverDataManager.staleBalanceAge = age
+}
+
 func (serverDataManager *ServerDataManager) RegisterServerDataInterface(staticData *processing.StaticProccessStructs) {
 	if staticData == nil {
 		log.Fatal("staticData is nil")
@@ -88,13 +103,50 @@ func (serverDataManager *ServerDataManager) updateBalanceNotifications(db *datab
 	return notifiesToProcess
 }
 
+func appendMissingStrings(values []string, newValues []string) []string {
+	for _, newValue := range newValues {
+		isFound := false
+		for _, value := range values {
+			if value == newValue {
+				isFound = true
+				break
+			}
+		}
+
+		if !isFound {
+			values = append(values, newValue)
+		}
+	}
+	return values
+}
+
 func (serverDataManager *ServerDataManager) updateAll(db *database.AccountDb) []currencies.BalanceNotify {
 	walletAddresses := db.GetAllWalletAddresses()
 	priceIds := db.GetAllPriceIds()
+	fiats := db.GetAllFiatCurrencies()
+
+	// price alerts can watch coins and fiats that are not used for the wallets
+	alertPriceIds, alertFiats := db.GetPriceAlertsRateIds()
+	priceIds = appendMissingStrings(priceIds, alertPriceIds)
+	fiats = appendMissingStrings(fiats, alertFiats)
+	// minimal changes of balances can be set in other fiats
+	fiats = appendMissingStrings(fiats, db.GetBalanceNotifiesFiats())
+
+	simpleWallets, hdWallets := splitHdWallets(walletAddresses)
+
+	changedWalletIds, fetched := serverDataManager.dataUpdater.updateBalance(simpleWallets)
+	// addresses of HD wallets can be added as simple wallets as well, their balances are not requested again
+	hdChangedWalletIds := serverDataManager.dataUpdater.updateHdWalletsBalance(db, hdWallets, fetched)
 
-	changedWalletIds := serverDataManager.dataUpdater.updateBalance(walletAddresses)
+	if changedWalletIds == nil {
+		changedWalletIds = hdChangedWalletIds
+	} else {
+		for walletId, balance := range hdChangedWalletIds {
+			changedWalletIds[walletId] = balance
+		}
+	}
 
-	serverDataManager.dataUpdater.updateRates(priceIds)
+	serverDataManager.dataUpdater.updateRates(priceIds, fiats)
 
 	return serverDataManager.updateBalanceNotifications(db, changedWalletIds)
 }
@@ -123,6 +175,10 @@ func (serverDataManager *ServerDataManager) TimerTick(db *database.AccountDb) Ti
 
 	balanceNotifies := serverDataManager.updateAll(db)
 
+	walletAddresses := db.GetAllWalletAddresses()
+	serverDataManager.updateBalanceSnapshots(db, walletAddresses, time.Now())
+	updateTransactions(db, walletAddresses)
+
 	return TickUpdateData {
 		BalanceNotifies: balanceNotifies,
 	}
@@ -138,8 +194,32 @@ func (serverDataManager *ServerDataManager) GetBalance(address currencies.Addres
 	}
 }
 
-func (serverDataManager *ServerDataManager) GetRateToUsd(priceId string) *big.Float {
-	return serverDataManager.dataUpdater.cache.getRateToUsd(priceId)
+func (serverDataManager *ServerDataManager) GetCachedBalance(address currencies.AddressData) *big.Int {
+	return serverDataManager.dataUpdater.cache.getBalance(address)
+}
+
+func (serverDataManager *ServerDataManager) GetBalanceStatus(address currencies.AddressData) BalanceStatus {
+	return serverDataManager.getBalanceStatus(address, time.Now())
+}
+
+func (serverDataManager *ServerDataManager) getBalanceStatus(address currencies.AddressData, now time.Time) BalanceStatus {
+	status := serverDataManager.dataUpdater.cache.getBalanceStatus(address)
+
+	staleBalanceAge := serverDataManager.staleBalanceAge
+	if staleBalanceAge <= 0 {
+		staleBalanceAge = defaultStaleBalanceAge
+	}
+
+	return BalanceStatus{
+		UpdateTime: status.updateTime,
+		Provider: status.provider,
+		LastError: status.lastError,
+		IsStale: !status.updateTime.IsZero() && now.Sub(status.updateTime) > staleBalanceAge,
+	}
+}
+
+func (serverDataManager *ServerDataManager) GetRate(priceId string, fiat string) *big.Float {
+	return serverDataManager.dataUpdater.cache.getRate(priceId, fiat)
 }
 
 func (serverDataManager *ServerDataManager) GetErc20TokenData(contractAddress string) *currencies.Erc20TokenData {
@@ -4,41 +4,110 @@ import (
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
 	"github.com/gameraccoon/telegram-accountant-bot/database"
+	"fmt"
 	"math/big"
 	"log"
+	"sync"
+	"time"
 )
 
 type serverDataUpdater struct {
 	cache dataCache
+	rateProvider cryptoFunctions.RateProvider
+	// nil means cryptoFunctions.GetProcessor, replaced in tests
+	getProcessorFn func(currencies.Currency) *cryptoFunctions.CurrencyProcessor
 }
 
 type balanceChangesData map[int64]*big.Int
 
+func (dataUpdater *serverDataUpdater) getCurrencyProcessor(currency currencies.Currency) *cryptoFunctions.CurrencyProcessor {
+	if dataUpdater.getProcessorFn != nil {
+		return dataUpdater.getProcessorFn(currency)
+	}
+	return cryptoFunctions.GetProcessor(currency)
+}
+
+func makeFailedBalanceResults(count int, err error) []cryptoFunctions.BalanceResult {
+	results := make([]cryptoFunctions.BalanceResult, count)
+	for i := range results {
+		results[i].Error = err
+	}
+	return results
+}
+
 func (dataUpdater *serverDataUpdater) updateBalanceOneWallet(walletAddress currencies.AddressData) *big.Int {
-	processor := cryptoFunctions.GetProcessor(walletAddress.Currency)
+	processor := dataUpdater.getCurrencyProcessor(walletAddress.Currency)
 
 	if processor == nil {
 		log.Print("No processor found")
 		return nil
 	}
 
-	balance := (*processor).GetBalance(walletAddress)
+	var result cryptoFunctions.BalanceResult
+	if cryptoFunctions.IsExtendedPublicKeyValid(walletAddress.Currency, walletAddress.Address) {
+		// a new HD wallet, the full scan will be done and stored with the next update
+		_, result = scanHdWallet(walletAddress, nil, nil)
+	} else {
+		results := (*processor).GetBalanceResults([]currencies.AddressData{walletAddress})
+		if len(results) != 1 {
+			log.Printf("return count doesn't match input count: 1 != %d", len(results))
+			return nil
+		}
+		result = results[0]
+	}
 
-	if balance != nil {
-		dataUpdater.cache.balancesMutex.Lock()
-		dataUpdater.cache.balances[walletAddress] = balance
-		dataUpdater.cache.balancesMutex.Unlock()
+	dataUpdater.cache.balancesMutex.Lock()
+	if result.Balance != nil {
+		dataUpdater.cache.balances[walletAddress] = result.Balance
 	}
+	dataUpdater.cache.updateBalanceStatus(walletAddress, result, time.Now())
+	dataUpdater.cache.balancesMutex.Unlock()
+
+	return result.Balance
+}
 
-	return balance
+// balances of wallets of one currency, in the same order
+type currencyBalances struct {
+	currency currencies.Currency
+	addressWrappers []database.WalletAddressDbWrapper
+	results []cryptoFunctions.BalanceResult
 }
 
-func (dataUpdater *serverDataUpdater) updateBalance(walletAddresses []database.WalletAddressDbWrapper) (balanceChanges balanceChangesData) {
+func (dataUpdater *serverDataUpdater) requestCurrencyBalances(currencyData *currencyBalances) {
+	processor := dataUpdater.getCurrencyProcessor(currencyData.currency)
+
+	if processor == nil {
+		log.Print("No processor found")
+		currencyData.results = makeFailedBalanceResults(len(currencyData.addressWrappers), fmt.Errorf("no processor for %s", currencies.GetCurrencySymbol(currencyData.currency)))
+		return
+	}
+
+	addresses := []currencies.AddressData{}
+	for _, addressWrapper := range currencyData.addressWrappers {
+		addresses = append(addresses, addressWrapper.Data)
+	}
+
+	// request and get balances
+	results := (*processor).GetBalanceResults(addresses)
+
+	if len(currencyData.addressWrappers) != len(results) {
+		err := fmt.Errorf("return count doesn't match input count: %d != %d", len(currencyData.addressWrappers), len(results))
+		log.Print(err)
+		currencyData.results = makeFailedBalanceResults(len(currencyData.addressWrappers), err)
+		return
+	}
+
+	currencyData.results = results
+}
+
+// returns the changed balances and all the received results to reuse them during the update
+func (dataUpdater *serverDataUpdater) updateBalance(walletAddresses []database.WalletAddressDbWrapper) (balanceChanges balanceChangesData, fetched fetchedBalances) {
 	if len(walletAddresses) == 0 {
 		return
 	}
 
 	balanceChanges = make(balanceChangesData)
+	fetched = make(fetchedBalances)
 
 	// group wallets to process in groups
 	groupedWallets := make(map[currencies.Currency] []database.WalletAddressDbWrapper)
@@ -52,31 +121,37 @@ func (dataUpdater *serverDataUpdater) updateBalance(walletAddresses []database.W
 		}
 	}
 
+	currenciesBalances := make([]currencyBalances, 0, len(groupedWallets))
 	for currency, addressWrappers := range groupedWallets {
-		processor := cryptoFunctions.GetProcessor(currency)
-
-		if processor == nil {
-			log.Print("No processor found")
-			continue
-		}
+		currenciesBalances = append(currenciesBalances, currencyBalances{
+			currency: currency,
+			addressWrappers: addressWrappers,
+		})
+	}
 
-		addresses := []currencies.AddressData{}
-		for _, addressWrapper := range addressWrappers {
-			addresses = append(addresses, addressWrapper.Data)
-		}
+	// currencies are requested in parallel, the processors limit requests to their providers
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(len(currenciesBalances))
+	for i := range currenciesBalances {
+		go func(currencyData *currencyBalances) {
+			defer waitGroup.Done()
+			dataUpdater.requestCurrencyBalances(currencyData)
+		}(&currenciesBalances[i])
+	}
+	waitGroup.Wait()
 
-		// request and get balances
-		balances := (*processor).GetBalanceBunch(addresses)
+	now := time.Now()
 
-		if len(addressWrappers) != len(balances) {
-			log.Printf("return count doesn't match input count: %d != %d", len(addressWrappers), len(balances))
-			continue
-		}
+	dataUpdater.cache.balancesMutex.Lock()
+	defer dataUpdater.cache.balancesMutex.Unlock()
 
-		dataUpdater.cache.balancesMutex.Lock()
+	for _, currencyData := range currenciesBalances {
+		for i, addressWrapper := range currencyData.addressWrappers {
+			fetched[addressWrapper.Data] = currencyData.results[i]
+			dataUpdater.cache.updateBalanceStatus(addressWrapper.Data, currencyData.results[i], now)
 
-		for i, addressWrapper := range addressWrappers {
-			balance := balances[i]
+			// the old balance is kept if the new one is unknown
+			balance := currencyData.results[i].Balance
 			if balance != nil {
 				oldBalance := dataUpdater.cache.balances[addressWrapper.Data]
 				if oldBalance == nil || balance.Cmp(oldBalance) != 0 {
@@ -88,33 +163,44 @@ func (dataUpdater *serverDataUpdater) updateBalance(walletAddresses []database.W
 				}
 			}
 		}
-
-		dataUpdater.cache.balancesMutex.Unlock()
 	}
 	return
 }
 
-func (dataUpdater *serverDataUpdater) updateRates(priceIds []string) {
-
-	toUsdRates := map[string]*big.Float{}
+func (dataUpdater *serverDataUpdater) updateRates(priceIds []string, fiats []string) {
+	if dataUpdater.rateProvider == nil {
+		log.Print("Rate provider is not set")
+		return
+	}
 
-	for _, priceId := range priceIds {
-		toUsdRate := cryptoFunctions.GetCurrencyToUsdRate(priceId)
+	if len(priceIds) == 0 || len(fiats) == 0 {
+		return
+	}
 
-		if toUsdRate != nil {
-			toUsdRates[priceId] = toUsdRate
-		}
+	// all the rates are requested at once
+	rates, err := dataUpdater.rateProvider.GetRates(priceIds, fiats)
+	if err != nil {
+		// the rates that we got are still used
+		log.Print(err)
 	}
 
-	dataUpdater.cache.balancesMutex.Lock()
+	dataUpdater.cache.ratesMutex.Lock()
 
-	for priceId, rate := range toUsdRates {
-		if rate != nil {
-			dataUpdater.cache.rates.toUsd[priceId] = rate
+	for fiat, fiatRates := range rates {
+		cachedRates, ok := dataUpdater.cache.rates[fiat]
+		if !ok {
+			cachedRates = make(map[string]*big.Float)
+			dataUpdater.cache.rates[fiat] = cachedRates
+		}
+
+		for priceId, rate := range fiatRates {
+			if rate != nil {
+				cachedRates[priceId] = rate
+			}
 		}
 	}
 
-	dataUpdater.cache.balancesMutex.Unlock()
+	dataUpdater.cache.ratesMutex.Unlock()
 }
 
 func (dataUpdater *serverDataUpdater) updateErc20TokensData(contractAddresses []string) {
@@ -135,14 +221,14 @@ func (dataUpdater *serverDataUpdater) updateErc20TokensData(contractAddresses []
 		tokenDatas[contractAddress] = processor.GetTokenData(contractAddress)
 	}
 
-	dataUpdater.cache.balancesMutex.Lock()
+	dataUpdater.cache.erc20TokensMutex.Lock()
 	for contractAddress, contractData := range tokenDatas {
 		if contractData != nil {
 			dataUpdater.cache.erc20Tokens[contractAddress] = *contractData
 		}
 	}
 
-	dataUpdater.cache.balancesMutex.Unlock()
+	dataUpdater.cache.erc20TokensMutex.Unlock()
 }
 
 func (dataUpdater *serverDataUpdater) updateOneErc20TokensData(contractAddress string) *currencies.Erc20TokenData {
@@ -156,9 +242,9 @@ func (dataUpdater *serverDataUpdater) updateOneErc20TokensData(contractAddress s
 	tokenData := processor.GetTokenData(contractAddress)
 
 	if tokenData != nil {
-		dataUpdater.cache.balancesMutex.Lock()
+		dataUpdater.cache.erc20TokensMutex.Lock()
 		dataUpdater.cache.erc20Tokens[contractAddress] = *tokenData
-		dataUpdater.cache.balancesMutex.Unlock()
+		dataUpdater.cache.erc20TokensMutex.Unlock()
 	}
 
 	return tokenData
@@ -0,0 +1,260 @@
+package serverData
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/database"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testBalanceProcessor struct {
+	mutex sync.Mutex
+	balances map[string]*big.Int
+	// shared between the processors of all currencies
+	running *int
+	maxRunning *int
+	runningMutex *sync.Mutex
+}
+
+func (processor *testBalanceProcessor) setBalance(address string, balance int64) {
+	processor.mutex.Lock()
+	processor.balances[address] = big.NewInt(balance)
+	processor.mutex.Unlock()
+}
+
+func (processor *testBalanceProcessor) GetBalance(address currencies.AddressData) *big.Int {
+	processor.mutex.Lock()
+	defer processor.mutex.Unlock()
+
+	balance, ok := processor.balances[address.Address]
+	if !ok {
+		return nil
+	}
+	return new(big.Int).Set(balance)
+}
+
+func (processor *testBalanceProcessor) GetBalanceBunch(addresses []currencies.AddressData) []*big.Int {
+	balances := []*big.Int{}
+	for _, result := range processor.GetBalanceResults(addresses) {
+		balances = append(balances, result.Balance)
+	}
+	return balances
+}
+
+func (processor *testBalanceProcessor) GetBalanceResults(addresses []currencies.AddressData) []cryptoFunctions.BalanceResult {
+	processor.runningMutex.Lock()
+	*processor.running++
+	if *processor.running > *processor.maxRunning {
+		*processor.maxRunning = *processor.running
+	}
+	processor.runningMutex.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	results := make([]cryptoFunctions.BalanceResult, len(addresses))
+	for i, address := range addresses {
+		results[i].Provider = "test"
+		results[i].Balance = processor.GetBalance(address)
+		if results[i].Balance == nil {
+			results[i].Error = fmt.Errorf("unknown address")
+		}
+	}
+
+	processor.runningMutex.Lock()
+	*processor.running--
+	processor.runningMutex.Unlock()
+
+	return results
+}
+
+func (processor *testBalanceProcessor) GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error) {
+	return
+}
+
+func (processor *testBalanceProcessor) IsAddressValid(address string) bool {
+	return true
+}
+
+func makeTestWallet(walletId int64, currency currencies.Currency, address string) database.WalletAddressDbWrapper {
+	return database.WalletAddressDbWrapper{
+		WalletId: walletId,
+		Data: currencies.AddressData{
+			Currency: currency,
+			Address: address,
+		},
+	}
+}
+
+func TestUpdateBalanceConcurrently(t *testing.T) {
+	assert := require.New(t)
+
+	running := 0
+	maxRunning := 0
+	runningMutex := sync.Mutex{}
+
+	processors := make(map[currencies.Currency]cryptoFunctions.CurrencyProcessor)
+	testProcessors := make(map[currencies.Currency]*testBalanceProcessor)
+	for _, currency := range []currencies.Currency{currencies.Bitcoin, currencies.Ether, currencies.Litecoin} {
+		processor := &testBalanceProcessor{
+			balances: make(map[string]*big.Int),
+			running: &running,
+			maxRunning: &maxRunning,
+			runningMutex: &runningMutex,
+		}
+		processors[currency] = processor
+		testProcessors[currency] = processor
+	}
+
+	dataUpdater := serverDataUpdater{
+		getProcessorFn: func(currency currencies.Currency) *cryptoFunctions.CurrencyProcessor {
+			processor, ok := processors[currency]
+			if !ok {
+				return nil
+			}
+			return &processor
+		},
+	}
+	dataUpdater.cache.Init()
+
+	wallets := []database.WalletAddressDbWrapper{
+		makeTestWallet(1, currencies.Bitcoin, "btc1"),
+		makeTestWallet(2, currencies.Bitcoin, "btc2"),
+		makeTestWallet(3, currencies.Ether, "eth1"),
+		makeTestWallet(4, currencies.Litecoin, "ltc1"),
+		// no processor for this currency
+		makeTestWallet(5, currencies.RippleXrp, "xrp1"),
+	}
+
+	testProcessors[currencies.Bitcoin].setBalance("btc1", 10)
+	testProcessors[currencies.Bitcoin].setBalance("btc2", 20)
+	testProcessors[currencies.Ether].setBalance("eth1", 30)
+	// the balance of ltc1 is unknown
+
+	// the cache is read by users while it is being updated
+	stopReading := make(chan struct{})
+	var readersWaitGroup sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readersWaitGroup.Add(1)
+		go func() {
+			defer readersWaitGroup.Done()
+			for {
+				select {
+				case <-stopReading:
+					return
+				default:
+					for _, wallet := range wallets {
+						dataUpdater.cache.getBalance(wallet.Data)
+					}
+				}
+			}
+		}()
+	}
+
+	balanceChanges, fetched := dataUpdater.updateBalance(wallets)
+
+	assert.Equal(3, len(balanceChanges))
+	assert.Equal(int64(10), balanceChanges[1].Int64())
+	assert.Equal(int64(20), balanceChanges[2].Int64())
+	assert.Equal(int64(30), balanceChanges[3].Int64())
+	assert.Equal(int64(20), dataUpdater.cache.getBalance(wallets[1].Data).Int64())
+	assert.Nil(dataUpdater.cache.getBalance(wallets[3].Data))
+	// currencies were requested at the same time
+	assert.True(maxRunning > 1)
+	// the results are kept to be reused by HD wallets
+	assert.Equal(5, len(fetched))
+	assert.Equal(int64(10), fetched[wallets[0].Data].Balance.Int64())
+	assert.NotNil(fetched[wallets[4].Data].Error)
+
+	// only changed balances are reported
+	testProcessors[currencies.Bitcoin].setBalance("btc2", 25)
+	testProcessors[currencies.Litecoin].setBalance("ltc1", 0)
+
+	balanceChanges, _ = dataUpdater.updateBalance(wallets)
+
+	assert.Equal(2, len(balanceChanges))
+	assert.Equal(int64(25), balanceChanges[2].Int64())
+	assert.Equal(int64(0), balanceChanges[4].Int64())
+	assert.Equal(int64(10), dataUpdater.cache.getBalance(wallets[0].Data).Int64())
+
+	close(stopReading)
+	readersWaitGroup.Wait()
+}
+
+func TestBalanceStatuses(t *testing.T) {
+	assert := require.New(t)
+
+	running := 0
+	maxRunning := 0
+	processor := &testBalanceProcessor{
+		balances: make(map[string]*big.Int),
+		running: &running,
+		maxRunning: &maxRunning,
+		runningMutex: &sync.Mutex{},
+	}
+
+	manager := ServerDataManager{}
+	manager.dataUpdater.getProcessorFn = func(currency currencies.Currency) *cryptoFunctions.CurrencyProcessor {
+		if currency != currencies.Bitcoin {
+			return nil
+		}
+		var currencyProcessor cryptoFunctions.CurrencyProcessor = processor
+		return &currencyProcessor
+	}
+	manager.dataUpdater.cache.Init()
+	manager.SetStaleBalanceAge(time.Hour)
+
+	wallets := []database.WalletAddressDbWrapper{
+		makeTestWallet(1, currencies.Bitcoin, "btc1"),
+		makeTestWallet(2, currencies.Bitcoin, "btc2"),
+		makeTestWallet(3, currencies.Ether, "eth1"),
+	}
+
+	processor.setBalance("btc1", 0)
+	processor.setBalance("btc2", 20)
+
+	startTime := time.Now()
+	manager.dataUpdater.updateBalance(wallets)
+
+	// zero is a known balance
+	assert.Equal(int64(0), manager.GetBalance(wallets[0].Data).Int64())
+	status := manager.GetBalanceStatus(wallets[0].Data)
+	assert.False(status.UpdateTime.Before(startTime))
+	assert.Equal("test", status.Provider)
+	assert.Nil(status.LastError)
+	assert.False(status.IsStale)
+
+	// no processor, the balance is unknown and was never received
+	assert.Nil(manager.GetCachedBalance(wallets[2].Data))
+	status = manager.GetBalanceStatus(wallets[2].Data)
+	assert.True(status.UpdateTime.IsZero())
+	assert.NotNil(status.LastError)
+	assert.False(status.IsStale)
+
+	// the failed update keeps the old balance and its time
+	firstUpdateTime := manager.GetBalanceStatus(wallets[1].Data).UpdateTime
+	processor.mutex.Lock()
+	delete(processor.balances, "btc2")
+	processor.mutex.Unlock()
+	manager.dataUpdater.updateBalance(wallets)
+
+	assert.Equal(int64(20), manager.GetBalance(wallets[1].Data).Int64())
+	status = manager.GetBalanceStatus(wallets[1].Data)
+	assert.Equal(firstUpdateTime, status.UpdateTime)
+	assert.NotNil(status.LastError)
+	assert.False(status.IsStale)
+
+	// the cached value is returned without a request
+	assert.Equal(int64(20), manager.GetCachedBalance(wallets[1].Data).Int64())
+	notRequestedWallet := makeTestWallet(4, currencies.Bitcoin, "btc3")
+	processor.setBalance("btc3", 30)
+	assert.Nil(manager.GetCachedBalance(notRequestedWallet.Data))
+	assert.Nil(manager.dataUpdater.cache.getBalance(notRequestedWallet.Data))
+
+	assert.True(manager.getBalanceStatus(wallets[1].Data, firstUpdateTime.Add(2 * time.Hour)).IsStale)
+	assert.False(manager.getBalanceStatus(wallets[1].Data, firstUpdateTime.Add(30 * time.Minute)).IsStale)
+}
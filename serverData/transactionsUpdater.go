@@ -0,0 +1,114 @@
+package serverData
+
+import (
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/database"
+	"github.com/gameraccoon/telegram-accountant-bot/wallettypes"
+	"log"
+)
+
+// how many transactions we request from a provider at once
+const transactionsSyncPageSize int = 50
+// limit of requests for one wallet during one update, the rest will be downloaded with the next updates
+const maxTransactionsSyncPages int = 4
+
+// downloads new transactions of the wallets and continues downloading of the old ones
+func updateTransactions(db *database.AccountDb, walletAddresses []database.WalletAddressDbWrapper) {
+	for _, walletAddress := range walletAddresses {
+		// history of HD wallets is spread over many addresses
+		if walletAddress.Type == wallettypes.HdWatchOnly {
+			continue
+		}
+
+		if !currencies.IsHistoryEnabled(walletAddress.Data.Currency) {
+			continue
+		}
+
+		processor := cryptoFunctions.GetProcessor(walletAddress.Data.Currency)
+		if processor == nil {
+			log.Print("No processor found")
+			continue
+		}
+
+		updateWalletTransactions(db, processor, walletAddress)
+	}
+}
+
+func updateWalletTransactions(db *database.AccountDb, processor *cryptoFunctions.CurrencyProcessor, walletAddress database.WalletAddressDbWrapper) {
+	walletId := walletAddress.WalletId
+	isSynced := db.IsWalletHistorySynced(walletId)
+	// the offsets of the providers count records from the newest one, so they include the records we can't store
+	syncOffset := db.GetHistorySyncOffset(walletId)
+
+	// new transactions are on the first pages, we stop as soon as we meet the ones we already have
+	// (known ones are still updated to get new confirmations)
+	pagesLeft := maxTransactionsSyncPages
+	isKnownReached := false
+	offset := 0
+	for pagesLeft > 0 {
+		pagesLeft--
+
+		history, err := (*processor).GetTransactionsHistory(walletAddress.Data, offset, transactionsSyncPageSize)
+		if err != nil {
+			// the provider is not available, try next time
+			log.Print(err)
+			return
+		}
+
+		_, firstKnownIndex := db.UpdateTransactions(walletId, history)
+
+		if firstKnownIndex >= 0 {
+			// the old records moved by the number of the new ones
+			syncOffset += offset + firstKnownIndex
+			isKnownReached = true
+		}
+
+		offset += len(history)
+
+		if len(history) < transactionsSyncPageSize {
+			// we have the whole history now
+			db.SetHistorySyncOffset(walletId, offset)
+			db.SetWalletHistorySynced(walletId, true)
+			return
+		}
+
+		if isKnownReached {
+			break
+		}
+	}
+
+	if !isKnownReached {
+		// there can be a gap between the new transactions and the ones we have, it is downloaded as old ones
+		syncOffset = offset
+		isSynced = false
+		db.SetWalletHistorySynced(walletId, false)
+	} else if syncOffset < offset {
+		syncOffset = offset
+	}
+
+	db.SetHistorySyncOffset(walletId, syncOffset)
+
+	if isSynced {
+		return
+	}
+
+	// continue downloading old transactions from the place we stopped last time
+	for ; pagesLeft > 0; pagesLeft-- {
+		history, err := (*processor).GetTransactionsHistory(walletAddress.Data, syncOffset, transactionsSyncPageSize)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+
+		db.UpdateTransactions(walletId, history)
+
+		syncOffset += len(history)
+		db.SetHistorySyncOffset(walletId, syncOffset)
+
+		if len(history) < transactionsSyncPageSize {
+			db.SetWalletHistorySynced(walletId, true)
+			return
+		}
+	}
+}
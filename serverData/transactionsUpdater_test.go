@@ -0,0 +1,75 @@
+package serverData
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/gameraccoon/telegram-accountant-bot/cryptoFunctions"
+	"github.com/gameraccoon/telegram-accountant-bot/currencies"
+	"github.com/gameraccoon/telegram-accountant-bot/database"
+	"os"
+	"testing"
+	"time"
+)
+
+const transactionsTestDbPath string = "./transactionsTestDb.db"
+
+// the history is returned from the newest transaction like the providers do
+type testHistoryProcessor struct {
+	testBalanceProcessor
+	transactionsCount int
+}
+
+func (processor *testHistoryProcessor) addTransactions(count int) {
+	processor.transactionsCount += count
+}
+
+func (processor *testHistoryProcessor) GetTransactionsHistory(address currencies.AddressData, offset int, limit int) (history []currencies.TransactionsHistoryItem, err error) {
+	history = []currencies.TransactionsHistoryItem{}
+	for i := processor.transactionsCount - 1 - offset; i >= 0 && len(history) < limit; i-- {
+		history = append(history, currencies.TransactionsHistoryItem{
+			Hash: fmt.Sprintf("tx%d", i),
+			Time: time.Unix(int64(1000 + i), 0),
+		})
+	}
+	return
+}
+
+func TestUpdateWalletTransactions(t *testing.T) {
+	assert := require.New(t)
+
+	os.Remove(transactionsTestDbPath)
+	db, err := database.ConnectDb(transactionsTestDbPath)
+	assert.Nil(err)
+	defer os.Remove(transactionsTestDbPath)
+	defer db.Disconnect()
+
+	userId := db.GetUserId(123, "")
+	walletId := db.CreateWatchOnlyWallet(userId, "test", currencies.AddressData{Currency: currencies.Bitcoin, Address: "adr"})
+	walletAddress := makeTestWallet(walletId, currencies.Bitcoin, "adr")
+
+	historyProcessor := &testHistoryProcessor{}
+	var processor cryptoFunctions.CurrencyProcessor = historyProcessor
+
+	// the history is too long to be downloaded at once
+	historyProcessor.addTransactions(300)
+	updateWalletTransactions(db, &processor, walletAddress)
+	assert.Equal(maxTransactionsSyncPages * transactionsSyncPageSize, db.GetTransactionsCount(walletId))
+	assert.False(db.IsWalletHistorySynced(walletId))
+
+	// new transactions shift the old ones
+	historyProcessor.addTransactions(10)
+	updateWalletTransactions(db, &processor, walletAddress)
+	assert.Equal(310, db.GetTransactionsCount(walletId))
+	assert.True(db.IsWalletHistorySynced(walletId))
+
+	// more new transactions than we request at once
+	historyProcessor.addTransactions(250)
+	updateWalletTransactions(db, &processor, walletAddress)
+	assert.False(db.IsWalletHistorySynced(walletId))
+
+	for i := 0; i < 3 && !db.IsWalletHistorySynced(walletId); i++ {
+		updateWalletTransactions(db, &processor, walletAddress)
+	}
+	assert.Equal(560, db.GetTransactionsCount(walletId))
+	assert.True(db.IsWalletHistorySynced(walletId))
+}
@@ -5,9 +5,35 @@ type LanguageData struct {
 	Name string
 }
 
+type BalanceProviderData struct {
+	Name string
+	// the default URL of the provider is used if empty
+	Url string
+	// needed only for providers that require a key
+	ApiKey string
+}
+
 type StaticConfiguration struct {
 	AvailableLanguages []LanguageData
 	DefaultLanguage string
 	ExtendedLog bool
 	UpdateIntervalSec int
+	// balances that were not updated for longer are shown as stale, one hour if not set
+	StaleBalanceAgeSec int
+	// "coingecko" (default) or "coinmarketcap"
+	RateProvider string
+	// needed only for providers that require a key
+	RateProviderApiKey string
+	// the default URL of the provider is used if empty
+	RateProviderUrl string
+	// provider name -> how many requests can be sent to it at the same time
+	ProviderConcurrencyLimits map[string]int
+	// host -> how many requests per second can be sent to it
+	HostRateLimits map[string]float64
+	// currency symbol ("ERC20" for tokens) -> providers of balances in the order of priority
+	BalanceProviders map[string][]BalanceProviderData
+	// check every balance with one more provider
+	CrossCheckBalances bool
+	// currency symbol ("ERC20" for tokens) -> base URL of the API used for the transactions history
+	HistoryApiUrls map[string]string
 }
@@ -2,15 +2,25 @@ package staticFunctions
 
 import (
 	"github.com/gameraccoon/telegram-bot-skeleton/processing"
+	"github.com/gameraccoon/telegram-bot-skeleton/telegramChat"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/gameraccoon/telegram-accountant-bot/database"
 	"github.com/gameraccoon/telegram-accountant-bot/currencies"
 	"github.com/gameraccoon/telegram-accountant-bot/serverData"
 	static "github.com/gameraccoon/telegram-accountant-bot/staticData"
 	"github.com/nicksnyder/go-i18n/i18n"
+	"io"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"time"
 )
 
+// a stuck download shouldn't block the processing of the user's messages forever
+const fileDownloadTimeout time.Duration = 60 * time.Second
+
+var downloadClient = &http.Client{Timeout: fileDownloadTimeout}
+
 func GetDb(staticData *processing.StaticProccessStructs) *database.AccountDb {
 	if staticData == nil {
 		log.Fatal("staticData is nil")
@@ -98,3 +108,131 @@ func FormatTimestamp(timestamp time.Time, timezone string) string {
 		return timestamp.Format("15:04:05 _2.01.2006")
 	}
 }
+
+// only the time for today's timestamps
+func FormatRecentTimestamp(timestamp time.Time, now time.Time, timezone string) string {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.Local
+	}
+
+	timestamp = timestamp.In(loc)
+	if timestamp.Format("2006-01-02") == now.In(loc).Format("2006-01-02") {
+		return timestamp.Format("15:04")
+	} else {
+		return timestamp.Format("15:04 _2.01.2006")
+	}
+}
+
+// warns with the time of the oldest update if some of the balances are stale, empty if all of them are fresh
+func GetStaleBalancesText(timezone string, trans i18n.TranslateFunc, statuses ...serverData.BalanceStatus) string {
+	var oldestUpdateTime time.Time
+	for _, status := range statuses {
+		if status.IsStale && (oldestUpdateTime.IsZero() || status.UpdateTime.Before(oldestUpdateTime)) {
+			oldestUpdateTime = status.UpdateTime
+		}
+	}
+
+	if oldestUpdateTime.IsZero() {
+		return ""
+	}
+
+	return trans("balance_stale", map[string]interface{}{
+		"Time": FormatRecentTimestamp(oldestUpdateTime, time.Now(), timezone),
+	})
+}
+
+func FormatDate(timestamp time.Time, timezone string) string {
+	loc, err := time.LoadLocation(timezone)
+	if err == nil {
+		return timestamp.In(loc).Format("_2.01.2006")
+	} else {
+		return timestamp.Format("_2.01.2006")
+	}
+}
+
+// the chat interface of the skeleton can send only texts, so we use the bot API directly
+func SendPhoto(staticData *processing.StaticProccessStructs, chatId int64, fileName string, imageData []byte, caption string) bool {
+	chat, ok := staticData.Chat.(*telegramChat.TelegramChat)
+	if !ok || chat.GetBot() == nil {
+		log.Print("Chat doesn't support sending photos")
+		return false
+	}
+
+	photo := tgbotapi.NewPhotoUpload(chatId, tgbotapi.FileBytes{
+		Name: fileName,
+		Bytes: imageData,
+	})
+	photo.Caption = caption
+	photo.ParseMode = "HTML"
+
+	_, err := chat.GetBot().Send(photo)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return true
+}
+
+func SendDocument(staticData *processing.StaticProccessStructs, chatId int64, fileName string, fileData []byte, caption string) bool {
+	chat, ok := staticData.Chat.(*telegramChat.TelegramChat)
+	if !ok || chat.GetBot() == nil {
+		log.Print("Chat doesn't support sending documents")
+		return false
+	}
+
+	document := tgbotapi.NewDocumentUpload(chatId, tgbotapi.FileBytes{
+		Name: fileName,
+		Bytes: fileData,
+	})
+	document.Caption = caption
+	document.ParseMode = "HTML"
+
+	_, err := chat.GetBot().Send(document)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return true
+}
+
+// returns false if the file can't be downloaded or is bigger than maxSize bytes
+func DownloadFile(staticData *processing.StaticProccessStructs, fileId string, maxSize int64) (fileData []byte, ok bool) {
+	chat, isTelegramChat := staticData.Chat.(*telegramChat.TelegramChat)
+	if !isTelegramChat || chat.GetBot() == nil {
+		log.Print("Chat doesn't support downloading files")
+		return
+	}
+
+	url, err := chat.GetBot().GetFileDirectURL(fileId)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	resp, err := downloadClient.Get(url)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Can't download file, status: %s", resp.Status)
+		return
+	}
+
+	// read one byte more to know that the file is too big
+	fileData, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxSize + 1))
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	if int64(len(fileData)) > maxSize {
+		return nil, false
+	}
+
+	ok = true
+	return
+}
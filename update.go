@@ -40,7 +40,12 @@ func updateTimer(staticData *processing.StaticProccessStructs, serverDataManager
 }
 
 func tickAfterupdate(staticData *processing.StaticProccessStructs, tickUpdateData serverData.TickUpdateData) {
+	// the queued notifications go before the new ones
+	sendDeferredNotifications(staticData)
 	updateBalanceNotifies(staticData, tickUpdateData.BalanceNotifies)
+	// rates are already updated here
+	updatePriceAlerts(staticData)
+	updateDigests(staticData)
 }
 
 func updateBot(chat *telegramChat.TelegramChat, staticData *processing.StaticProccessStructs, dialogManager *dialogManager.DialogManager) {
@@ -76,7 +81,11 @@ func processMessageUpdate(userChans userChannelsData, update *tgbotapi.Update, s
 
 	message := update.Message.Text
 
-	if strings.HasPrefix(message, "/") {
+	if update.Message.Document != nil {
+		// uploaded files are processed as a command with the file id
+		data.Command = "upload"
+		data.Message = update.Message.Document.FileID
+	} else if strings.HasPrefix(message, "/") {
 		commandLen := strings.Index(message, " ")
 		if commandLen != -1 {
 			data.Command = message[1:commandLen]
@@ -5,4 +5,5 @@ type WalletType int8
 const (
 	// don't use iota to make it more explicit
 	WatchOnly WalletType = 0
+	HdWatchOnly WalletType = 1 // tracked by an extended public key
 )